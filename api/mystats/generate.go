@@ -0,0 +1,4 @@
+// Package mystats has server and client of mystats HTTP API. They are generated from openapi.yaml.
+package mystats
+
+//go:generate go tool ogen --target . --package mystats --clean openapi.yaml
@@ -0,0 +1,16 @@
+// Code generated by ogen, DO NOT EDIT.
+
+package mystats
+
+// OperationName is the ogen operation name
+type OperationName = string
+
+const (
+	GetActivityOperation    OperationName = "GetActivity"
+	GetBestOperation        OperationName = "GetBest"
+	GetHeartRateOperation   OperationName = "GetHeartRate"
+	GetStatsOperation       OperationName = "GetStats"
+	GetStepsOperation       OperationName = "GetSteps"
+	GetTopOperation         OperationName = "GetTop"
+	ListActivitiesOperation OperationName = "ListActivities"
+)
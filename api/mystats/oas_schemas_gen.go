@@ -0,0 +1,1364 @@
+// Code generated by ogen, DO NOT EDIT.
+
+package mystats
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/go-faster/errors"
+)
+
+func (s *ErrorStatusCode) Error() string {
+	return fmt.Sprintf("code %d: %+v", s.StatusCode, s.Response)
+}
+
+// Ref: #/components/schemas/Activities
+type Activities struct {
+	Offset int `json:"offset"`
+	Limit  int `json:"limit"`
+	// Offset of the next page. Missing on the last page.
+	NextOffset OptInt     `json:"next_offset"`
+	Activities []Activity `json:"activities"`
+}
+
+// GetOffset returns the value of Offset.
+func (s *Activities) GetOffset() int {
+	return s.Offset
+}
+
+// GetLimit returns the value of Limit.
+func (s *Activities) GetLimit() int {
+	return s.Limit
+}
+
+// GetNextOffset returns the value of NextOffset.
+func (s *Activities) GetNextOffset() OptInt {
+	return s.NextOffset
+}
+
+// GetActivities returns the value of Activities.
+func (s *Activities) GetActivities() []Activity {
+	return s.Activities
+}
+
+// SetOffset sets the value of Offset.
+func (s *Activities) SetOffset(val int) {
+	s.Offset = val
+}
+
+// SetLimit sets the value of Limit.
+func (s *Activities) SetLimit(val int) {
+	s.Limit = val
+}
+
+// SetNextOffset sets the value of NextOffset.
+func (s *Activities) SetNextOffset(val OptInt) {
+	s.NextOffset = val
+}
+
+// SetActivities sets the value of Activities.
+func (s *Activities) SetActivities(val []Activity) {
+	s.Activities = val
+}
+
+// Ref: #/components/schemas/Activity
+type Activity struct {
+	ID          int64     `json:"id"`
+	Date        time.Time `json:"date"`
+	Name        string    `json:"name"`
+	Type        string    `json:"type"`
+	WorkoutType string    `json:"workout_type"`
+	Distance    float64   `json:"distance"`
+	Elevation   float64   `json:"elevation"`
+	ElapsedTime float64   `json:"elapsed_time"`
+}
+
+// GetID returns the value of ID.
+func (s *Activity) GetID() int64 {
+	return s.ID
+}
+
+// GetDate returns the value of Date.
+func (s *Activity) GetDate() time.Time {
+	return s.Date
+}
+
+// GetName returns the value of Name.
+func (s *Activity) GetName() string {
+	return s.Name
+}
+
+// GetType returns the value of Type.
+func (s *Activity) GetType() string {
+	return s.Type
+}
+
+// GetWorkoutType returns the value of WorkoutType.
+func (s *Activity) GetWorkoutType() string {
+	return s.WorkoutType
+}
+
+// GetDistance returns the value of Distance.
+func (s *Activity) GetDistance() float64 {
+	return s.Distance
+}
+
+// GetElevation returns the value of Elevation.
+func (s *Activity) GetElevation() float64 {
+	return s.Elevation
+}
+
+// GetElapsedTime returns the value of ElapsedTime.
+func (s *Activity) GetElapsedTime() float64 {
+	return s.ElapsedTime
+}
+
+// SetID sets the value of ID.
+func (s *Activity) SetID(val int64) {
+	s.ID = val
+}
+
+// SetDate sets the value of Date.
+func (s *Activity) SetDate(val time.Time) {
+	s.Date = val
+}
+
+// SetName sets the value of Name.
+func (s *Activity) SetName(val string) {
+	s.Name = val
+}
+
+// SetType sets the value of Type.
+func (s *Activity) SetType(val string) {
+	s.Type = val
+}
+
+// SetWorkoutType sets the value of WorkoutType.
+func (s *Activity) SetWorkoutType(val string) {
+	s.WorkoutType = val
+}
+
+// SetDistance sets the value of Distance.
+func (s *Activity) SetDistance(val float64) {
+	s.Distance = val
+}
+
+// SetElevation sets the value of Elevation.
+func (s *Activity) SetElevation(val float64) {
+	s.Elevation = val
+}
+
+// SetElapsedTime sets the value of ElapsedTime.
+func (s *Activity) SetElapsedTime(val float64) {
+	s.ElapsedTime = val
+}
+
+// Merged schema.
+// Ref: #/components/schemas/ActivityDetails
+type ActivityDetails struct {
+	ID               int64      `json:"id"`
+	Date             time.Time  `json:"date"`
+	Name             string     `json:"name"`
+	Type             string     `json:"type"`
+	WorkoutType      string     `json:"workout_type"`
+	Distance         float64    `json:"distance"`
+	Elevation        float64    `json:"elevation"`
+	ElapsedTime      float64    `json:"elapsed_time"`
+	SportType        string     `json:"sport_type"`
+	MovingTime       float64    `json:"moving_time"`
+	AverageHeartrate OptFloat64 `json:"average_heartrate"`
+	Kilojoules       OptFloat64 `json:"kilojoules"`
+	// Splits are per kilometer or per mile depending on units of server.
+	Splits []Split `json:"splits"`
+}
+
+// GetID returns the value of ID.
+func (s *ActivityDetails) GetID() int64 {
+	return s.ID
+}
+
+// GetDate returns the value of Date.
+func (s *ActivityDetails) GetDate() time.Time {
+	return s.Date
+}
+
+// GetName returns the value of Name.
+func (s *ActivityDetails) GetName() string {
+	return s.Name
+}
+
+// GetType returns the value of Type.
+func (s *ActivityDetails) GetType() string {
+	return s.Type
+}
+
+// GetWorkoutType returns the value of WorkoutType.
+func (s *ActivityDetails) GetWorkoutType() string {
+	return s.WorkoutType
+}
+
+// GetDistance returns the value of Distance.
+func (s *ActivityDetails) GetDistance() float64 {
+	return s.Distance
+}
+
+// GetElevation returns the value of Elevation.
+func (s *ActivityDetails) GetElevation() float64 {
+	return s.Elevation
+}
+
+// GetElapsedTime returns the value of ElapsedTime.
+func (s *ActivityDetails) GetElapsedTime() float64 {
+	return s.ElapsedTime
+}
+
+// GetSportType returns the value of SportType.
+func (s *ActivityDetails) GetSportType() string {
+	return s.SportType
+}
+
+// GetMovingTime returns the value of MovingTime.
+func (s *ActivityDetails) GetMovingTime() float64 {
+	return s.MovingTime
+}
+
+// GetAverageHeartrate returns the value of AverageHeartrate.
+func (s *ActivityDetails) GetAverageHeartrate() OptFloat64 {
+	return s.AverageHeartrate
+}
+
+// GetKilojoules returns the value of Kilojoules.
+func (s *ActivityDetails) GetKilojoules() OptFloat64 {
+	return s.Kilojoules
+}
+
+// GetSplits returns the value of Splits.
+func (s *ActivityDetails) GetSplits() []Split {
+	return s.Splits
+}
+
+// SetID sets the value of ID.
+func (s *ActivityDetails) SetID(val int64) {
+	s.ID = val
+}
+
+// SetDate sets the value of Date.
+func (s *ActivityDetails) SetDate(val time.Time) {
+	s.Date = val
+}
+
+// SetName sets the value of Name.
+func (s *ActivityDetails) SetName(val string) {
+	s.Name = val
+}
+
+// SetType sets the value of Type.
+func (s *ActivityDetails) SetType(val string) {
+	s.Type = val
+}
+
+// SetWorkoutType sets the value of WorkoutType.
+func (s *ActivityDetails) SetWorkoutType(val string) {
+	s.WorkoutType = val
+}
+
+// SetDistance sets the value of Distance.
+func (s *ActivityDetails) SetDistance(val float64) {
+	s.Distance = val
+}
+
+// SetElevation sets the value of Elevation.
+func (s *ActivityDetails) SetElevation(val float64) {
+	s.Elevation = val
+}
+
+// SetElapsedTime sets the value of ElapsedTime.
+func (s *ActivityDetails) SetElapsedTime(val float64) {
+	s.ElapsedTime = val
+}
+
+// SetSportType sets the value of SportType.
+func (s *ActivityDetails) SetSportType(val string) {
+	s.SportType = val
+}
+
+// SetMovingTime sets the value of MovingTime.
+func (s *ActivityDetails) SetMovingTime(val float64) {
+	s.MovingTime = val
+}
+
+// SetAverageHeartrate sets the value of AverageHeartrate.
+func (s *ActivityDetails) SetAverageHeartrate(val OptFloat64) {
+	s.AverageHeartrate = val
+}
+
+// SetKilojoules sets the value of Kilojoules.
+func (s *ActivityDetails) SetKilojoules(val OptFloat64) {
+	s.Kilojoules = val
+}
+
+// SetSplits sets the value of Splits.
+func (s *ActivityDetails) SetSplits(val []Split) {
+	s.Splits = val
+}
+
+// Ref: #/components/schemas/Best
+type Best struct {
+	Distance    string              `json:"distance"`
+	Efforts     []BestEffort        `json:"efforts"`
+	Progression []ProgressionRecord `json:"progression"`
+}
+
+// GetDistance returns the value of Distance.
+func (s *Best) GetDistance() string {
+	return s.Distance
+}
+
+// GetEfforts returns the value of Efforts.
+func (s *Best) GetEfforts() []BestEffort {
+	return s.Efforts
+}
+
+// GetProgression returns the value of Progression.
+func (s *Best) GetProgression() []ProgressionRecord {
+	return s.Progression
+}
+
+// SetDistance sets the value of Distance.
+func (s *Best) SetDistance(val string) {
+	s.Distance = val
+}
+
+// SetEfforts sets the value of Efforts.
+func (s *Best) SetEfforts(val []BestEffort) {
+	s.Efforts = val
+}
+
+// SetProgression sets the value of Progression.
+func (s *Best) SetProgression(val []ProgressionRecord) {
+	s.Progression = val
+}
+
+// Ref: #/components/schemas/BestEffort
+type BestEffort struct {
+	ID               int64     `json:"id"`
+	Date             time.Time `json:"date"`
+	Name             string    `json:"name"`
+	ElapsedTime      float64   `json:"elapsed_time"`
+	ActivityDistance float64   `json:"activity_distance"`
+	ActivityTime     float64   `json:"activity_time"`
+}
+
+// GetID returns the value of ID.
+func (s *BestEffort) GetID() int64 {
+	return s.ID
+}
+
+// GetDate returns the value of Date.
+func (s *BestEffort) GetDate() time.Time {
+	return s.Date
+}
+
+// GetName returns the value of Name.
+func (s *BestEffort) GetName() string {
+	return s.Name
+}
+
+// GetElapsedTime returns the value of ElapsedTime.
+func (s *BestEffort) GetElapsedTime() float64 {
+	return s.ElapsedTime
+}
+
+// GetActivityDistance returns the value of ActivityDistance.
+func (s *BestEffort) GetActivityDistance() float64 {
+	return s.ActivityDistance
+}
+
+// GetActivityTime returns the value of ActivityTime.
+func (s *BestEffort) GetActivityTime() float64 {
+	return s.ActivityTime
+}
+
+// SetID sets the value of ID.
+func (s *BestEffort) SetID(val int64) {
+	s.ID = val
+}
+
+// SetDate sets the value of Date.
+func (s *BestEffort) SetDate(val time.Time) {
+	s.Date = val
+}
+
+// SetName sets the value of Name.
+func (s *BestEffort) SetName(val string) {
+	s.Name = val
+}
+
+// SetElapsedTime sets the value of ElapsedTime.
+func (s *BestEffort) SetElapsedTime(val float64) {
+	s.ElapsedTime = val
+}
+
+// SetActivityDistance sets the value of ActivityDistance.
+func (s *BestEffort) SetActivityDistance(val float64) {
+	s.ActivityDistance = val
+}
+
+// SetActivityTime sets the value of ActivityTime.
+func (s *BestEffort) SetActivityTime(val float64) {
+	s.ActivityTime = val
+}
+
+// Value for each day of year in every year. Steps are cumulative, heart rate is daily.
+// Ref: #/components/schemas/Daily
+type Daily struct {
+	Years  []int       `json:"years"`
+	Values [][]float64 `json:"values"`
+}
+
+// GetYears returns the value of Years.
+func (s *Daily) GetYears() []int {
+	return s.Years
+}
+
+// GetValues returns the value of Values.
+func (s *Daily) GetValues() [][]float64 {
+	return s.Values
+}
+
+// SetYears sets the value of Years.
+func (s *Daily) SetYears(val []int) {
+	s.Years = val
+}
+
+// SetValues sets the value of Values.
+func (s *Daily) SetValues(val [][]float64) {
+	s.Values = val
+}
+
+// Ref: #/components/schemas/Error
+type Error struct {
+	Error string `json:"error"`
+}
+
+// GetError returns the value of Error.
+func (s *Error) GetError() string {
+	return s.Error
+}
+
+// SetError sets the value of Error.
+func (s *Error) SetError(val string) {
+	s.Error = val
+}
+
+// ErrorStatusCode wraps Error with StatusCode.
+type ErrorStatusCode struct {
+	StatusCode int
+	Response   Error
+}
+
+// GetStatusCode returns the value of StatusCode.
+func (s *ErrorStatusCode) GetStatusCode() int {
+	return s.StatusCode
+}
+
+// GetResponse returns the value of Response.
+func (s *ErrorStatusCode) GetResponse() Error {
+	return s.Response
+}
+
+// SetStatusCode sets the value of StatusCode.
+func (s *ErrorStatusCode) SetStatusCode(val int) {
+	s.StatusCode = val
+}
+
+// SetResponse sets the value of Response.
+func (s *ErrorStatusCode) SetResponse(val Error) {
+	s.Response = val
+}
+
+type GetStatsPeriod string
+
+const (
+	GetStatsPeriodMonth GetStatsPeriod = "month"
+	GetStatsPeriodWeek  GetStatsPeriod = "week"
+)
+
+// AllValues returns all GetStatsPeriod values.
+func (GetStatsPeriod) AllValues() []GetStatsPeriod {
+	return []GetStatsPeriod{
+		GetStatsPeriodMonth,
+		GetStatsPeriodWeek,
+	}
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (s GetStatsPeriod) MarshalText() ([]byte, error) {
+	switch s {
+	case GetStatsPeriodMonth:
+		return []byte(s), nil
+	case GetStatsPeriodWeek:
+		return []byte(s), nil
+	default:
+		return nil, errors.Errorf("invalid value: %q", s)
+	}
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (s *GetStatsPeriod) UnmarshalText(data []byte) error {
+	switch GetStatsPeriod(data) {
+	case GetStatsPeriodMonth:
+		*s = GetStatsPeriodMonth
+		return nil
+	case GetStatsPeriodWeek:
+		*s = GetStatsPeriodWeek
+		return nil
+	default:
+		return errors.Errorf("invalid value: %q", data)
+	}
+}
+
+type GetTopPeriod string
+
+const (
+	GetTopPeriodWeek  GetTopPeriod = "week"
+	GetTopPeriodMonth GetTopPeriod = "month"
+	GetTopPeriodDay   GetTopPeriod = "day"
+)
+
+// AllValues returns all GetTopPeriod values.
+func (GetTopPeriod) AllValues() []GetTopPeriod {
+	return []GetTopPeriod{
+		GetTopPeriodWeek,
+		GetTopPeriodMonth,
+		GetTopPeriodDay,
+	}
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (s GetTopPeriod) MarshalText() ([]byte, error) {
+	switch s {
+	case GetTopPeriodWeek:
+		return []byte(s), nil
+	case GetTopPeriodMonth:
+		return []byte(s), nil
+	case GetTopPeriodDay:
+		return []byte(s), nil
+	default:
+		return nil, errors.Errorf("invalid value: %q", s)
+	}
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (s *GetTopPeriod) UnmarshalText(data []byte) error {
+	switch GetTopPeriod(data) {
+	case GetTopPeriodWeek:
+		*s = GetTopPeriodWeek
+		return nil
+	case GetTopPeriodMonth:
+		*s = GetTopPeriodMonth
+		return nil
+	case GetTopPeriodDay:
+		*s = GetTopPeriodDay
+		return nil
+	default:
+		return errors.Errorf("invalid value: %q", data)
+	}
+}
+
+// Ref: #/components/schemas/Measure
+type Measure string
+
+const (
+	MeasureCount          Measure = "count"
+	MeasureDistance       Measure = "distance"
+	MeasureElapsedTime    Measure = "elapsed_time"
+	MeasureElevation      Measure = "elevation"
+	MeasureElevationPerKm Measure = "elevation_per_km"
+	MeasureHeartrate      Measure = "heartrate"
+	MeasureKilojoules     Measure = "kilojoules"
+	MeasureMovingTime     Measure = "moving_time"
+	MeasurePace           Measure = "pace"
+	MeasureSpeed          Measure = "speed"
+)
+
+// AllValues returns all Measure values.
+func (Measure) AllValues() []Measure {
+	return []Measure{
+		MeasureCount,
+		MeasureDistance,
+		MeasureElapsedTime,
+		MeasureElevation,
+		MeasureElevationPerKm,
+		MeasureHeartrate,
+		MeasureKilojoules,
+		MeasureMovingTime,
+		MeasurePace,
+		MeasureSpeed,
+	}
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (s Measure) MarshalText() ([]byte, error) {
+	switch s {
+	case MeasureCount:
+		return []byte(s), nil
+	case MeasureDistance:
+		return []byte(s), nil
+	case MeasureElapsedTime:
+		return []byte(s), nil
+	case MeasureElevation:
+		return []byte(s), nil
+	case MeasureElevationPerKm:
+		return []byte(s), nil
+	case MeasureHeartrate:
+		return []byte(s), nil
+	case MeasureKilojoules:
+		return []byte(s), nil
+	case MeasureMovingTime:
+		return []byte(s), nil
+	case MeasurePace:
+		return []byte(s), nil
+	case MeasureSpeed:
+		return []byte(s), nil
+	default:
+		return nil, errors.Errorf("invalid value: %q", s)
+	}
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (s *Measure) UnmarshalText(data []byte) error {
+	switch Measure(data) {
+	case MeasureCount:
+		*s = MeasureCount
+		return nil
+	case MeasureDistance:
+		*s = MeasureDistance
+		return nil
+	case MeasureElapsedTime:
+		*s = MeasureElapsedTime
+		return nil
+	case MeasureElevation:
+		*s = MeasureElevation
+		return nil
+	case MeasureElevationPerKm:
+		*s = MeasureElevationPerKm
+		return nil
+	case MeasureHeartrate:
+		*s = MeasureHeartrate
+		return nil
+	case MeasureKilojoules:
+		*s = MeasureKilojoules
+		return nil
+	case MeasureMovingTime:
+		*s = MeasureMovingTime
+		return nil
+	case MeasurePace:
+		*s = MeasurePace
+		return nil
+	case MeasureSpeed:
+		*s = MeasureSpeed
+		return nil
+	default:
+		return errors.Errorf("invalid value: %q", data)
+	}
+}
+
+// NewNilFloat64 returns new NilFloat64 with value set to v.
+func NewNilFloat64(v float64) NilFloat64 {
+	return NilFloat64{
+		Value: v,
+	}
+}
+
+// NilFloat64 is nullable float64.
+type NilFloat64 struct {
+	Value float64
+	Null  bool
+}
+
+// SetTo sets value to v.
+func (o *NilFloat64) SetTo(v float64) {
+	o.Null = false
+	o.Value = v
+}
+
+// IsNull returns true if value is Null.
+func (o NilFloat64) IsNull() bool { return o.Null }
+
+// SetToNull sets value to null.
+func (o *NilFloat64) SetToNull() {
+	o.Null = true
+	var v float64
+	o.Value = v
+}
+
+// Get returns value and boolean that denotes whether value was set.
+func (o NilFloat64) Get() (v float64, ok bool) {
+	if o.Null {
+		return v, false
+	}
+	return o.Value, true
+}
+
+// Or returns value if set, or given parameter if does not.
+func (o NilFloat64) Or(d float64) float64 {
+	if v, ok := o.Get(); ok {
+		return v
+	}
+	return d
+}
+
+// NewOptBool returns new OptBool with value set to v.
+func NewOptBool(v bool) OptBool {
+	return OptBool{
+		Value: v,
+		Set:   true,
+	}
+}
+
+// OptBool is optional bool.
+type OptBool struct {
+	Value bool
+	Set   bool
+}
+
+// IsSet returns true if OptBool was set.
+func (o OptBool) IsSet() bool { return o.Set }
+
+// Reset unsets value.
+func (o *OptBool) Reset() {
+	var v bool
+	o.Value = v
+	o.Set = false
+}
+
+// SetTo sets value to v.
+func (o *OptBool) SetTo(v bool) {
+	o.Set = true
+	o.Value = v
+}
+
+// Get returns value and boolean that denotes whether value was set.
+func (o OptBool) Get() (v bool, ok bool) {
+	if !o.Set {
+		return v, false
+	}
+	return o.Value, true
+}
+
+// Or returns value if set, or given parameter if does not.
+func (o OptBool) Or(d bool) bool {
+	if v, ok := o.Get(); ok {
+		return v
+	}
+	return d
+}
+
+// NewOptDate returns new OptDate with value set to v.
+func NewOptDate(v time.Time) OptDate {
+	return OptDate{
+		Value: v,
+		Set:   true,
+	}
+}
+
+// OptDate is optional time.Time.
+type OptDate struct {
+	Value time.Time
+	Set   bool
+}
+
+// IsSet returns true if OptDate was set.
+func (o OptDate) IsSet() bool { return o.Set }
+
+// Reset unsets value.
+func (o *OptDate) Reset() {
+	var v time.Time
+	o.Value = v
+	o.Set = false
+}
+
+// SetTo sets value to v.
+func (o *OptDate) SetTo(v time.Time) {
+	o.Set = true
+	o.Value = v
+}
+
+// Get returns value and boolean that denotes whether value was set.
+func (o OptDate) Get() (v time.Time, ok bool) {
+	if !o.Set {
+		return v, false
+	}
+	return o.Value, true
+}
+
+// Or returns value if set, or given parameter if does not.
+func (o OptDate) Or(d time.Time) time.Time {
+	if v, ok := o.Get(); ok {
+		return v
+	}
+	return d
+}
+
+// NewOptFloat64 returns new OptFloat64 with value set to v.
+func NewOptFloat64(v float64) OptFloat64 {
+	return OptFloat64{
+		Value: v,
+		Set:   true,
+	}
+}
+
+// OptFloat64 is optional float64.
+type OptFloat64 struct {
+	Value float64
+	Set   bool
+}
+
+// IsSet returns true if OptFloat64 was set.
+func (o OptFloat64) IsSet() bool { return o.Set }
+
+// Reset unsets value.
+func (o *OptFloat64) Reset() {
+	var v float64
+	o.Value = v
+	o.Set = false
+}
+
+// SetTo sets value to v.
+func (o *OptFloat64) SetTo(v float64) {
+	o.Set = true
+	o.Value = v
+}
+
+// Get returns value and boolean that denotes whether value was set.
+func (o OptFloat64) Get() (v float64, ok bool) {
+	if !o.Set {
+		return v, false
+	}
+	return o.Value, true
+}
+
+// Or returns value if set, or given parameter if does not.
+func (o OptFloat64) Or(d float64) float64 {
+	if v, ok := o.Get(); ok {
+		return v
+	}
+	return d
+}
+
+// NewOptGetStatsPeriod returns new OptGetStatsPeriod with value set to v.
+func NewOptGetStatsPeriod(v GetStatsPeriod) OptGetStatsPeriod {
+	return OptGetStatsPeriod{
+		Value: v,
+		Set:   true,
+	}
+}
+
+// OptGetStatsPeriod is optional GetStatsPeriod.
+type OptGetStatsPeriod struct {
+	Value GetStatsPeriod
+	Set   bool
+}
+
+// IsSet returns true if OptGetStatsPeriod was set.
+func (o OptGetStatsPeriod) IsSet() bool { return o.Set }
+
+// Reset unsets value.
+func (o *OptGetStatsPeriod) Reset() {
+	var v GetStatsPeriod
+	o.Value = v
+	o.Set = false
+}
+
+// SetTo sets value to v.
+func (o *OptGetStatsPeriod) SetTo(v GetStatsPeriod) {
+	o.Set = true
+	o.Value = v
+}
+
+// Get returns value and boolean that denotes whether value was set.
+func (o OptGetStatsPeriod) Get() (v GetStatsPeriod, ok bool) {
+	if !o.Set {
+		return v, false
+	}
+	return o.Value, true
+}
+
+// Or returns value if set, or given parameter if does not.
+func (o OptGetStatsPeriod) Or(d GetStatsPeriod) GetStatsPeriod {
+	if v, ok := o.Get(); ok {
+		return v
+	}
+	return d
+}
+
+// NewOptGetTopPeriod returns new OptGetTopPeriod with value set to v.
+func NewOptGetTopPeriod(v GetTopPeriod) OptGetTopPeriod {
+	return OptGetTopPeriod{
+		Value: v,
+		Set:   true,
+	}
+}
+
+// OptGetTopPeriod is optional GetTopPeriod.
+type OptGetTopPeriod struct {
+	Value GetTopPeriod
+	Set   bool
+}
+
+// IsSet returns true if OptGetTopPeriod was set.
+func (o OptGetTopPeriod) IsSet() bool { return o.Set }
+
+// Reset unsets value.
+func (o *OptGetTopPeriod) Reset() {
+	var v GetTopPeriod
+	o.Value = v
+	o.Set = false
+}
+
+// SetTo sets value to v.
+func (o *OptGetTopPeriod) SetTo(v GetTopPeriod) {
+	o.Set = true
+	o.Value = v
+}
+
+// Get returns value and boolean that denotes whether value was set.
+func (o OptGetTopPeriod) Get() (v GetTopPeriod, ok bool) {
+	if !o.Set {
+		return v, false
+	}
+	return o.Value, true
+}
+
+// Or returns value if set, or given parameter if does not.
+func (o OptGetTopPeriod) Or(d GetTopPeriod) GetTopPeriod {
+	if v, ok := o.Get(); ok {
+		return v
+	}
+	return d
+}
+
+// NewOptInt returns new OptInt with value set to v.
+func NewOptInt(v int) OptInt {
+	return OptInt{
+		Value: v,
+		Set:   true,
+	}
+}
+
+// OptInt is optional int.
+type OptInt struct {
+	Value int
+	Set   bool
+}
+
+// IsSet returns true if OptInt was set.
+func (o OptInt) IsSet() bool { return o.Set }
+
+// Reset unsets value.
+func (o *OptInt) Reset() {
+	var v int
+	o.Value = v
+	o.Set = false
+}
+
+// SetTo sets value to v.
+func (o *OptInt) SetTo(v int) {
+	o.Set = true
+	o.Value = v
+}
+
+// Get returns value and boolean that denotes whether value was set.
+func (o OptInt) Get() (v int, ok bool) {
+	if !o.Set {
+		return v, false
+	}
+	return o.Value, true
+}
+
+// Or returns value if set, or given parameter if does not.
+func (o OptInt) Or(d int) int {
+	if v, ok := o.Get(); ok {
+		return v
+	}
+	return d
+}
+
+// NewOptMeasure returns new OptMeasure with value set to v.
+func NewOptMeasure(v Measure) OptMeasure {
+	return OptMeasure{
+		Value: v,
+		Set:   true,
+	}
+}
+
+// OptMeasure is optional Measure.
+type OptMeasure struct {
+	Value Measure
+	Set   bool
+}
+
+// IsSet returns true if OptMeasure was set.
+func (o OptMeasure) IsSet() bool { return o.Set }
+
+// Reset unsets value.
+func (o *OptMeasure) Reset() {
+	var v Measure
+	o.Value = v
+	o.Set = false
+}
+
+// SetTo sets value to v.
+func (o *OptMeasure) SetTo(v Measure) {
+	o.Set = true
+	o.Value = v
+}
+
+// Get returns value and boolean that denotes whether value was set.
+func (o OptMeasure) Get() (v Measure, ok bool) {
+	if !o.Set {
+		return v, false
+	}
+	return o.Value, true
+}
+
+// Or returns value if set, or given parameter if does not.
+func (o OptMeasure) Or(d Measure) Measure {
+	if v, ok := o.Get(); ok {
+		return v
+	}
+	return d
+}
+
+// NewOptString returns new OptString with value set to v.
+func NewOptString(v string) OptString {
+	return OptString{
+		Value: v,
+		Set:   true,
+	}
+}
+
+// OptString is optional string.
+type OptString struct {
+	Value string
+	Set   bool
+}
+
+// IsSet returns true if OptString was set.
+func (o OptString) IsSet() bool { return o.Set }
+
+// Reset unsets value.
+func (o *OptString) Reset() {
+	var v string
+	o.Value = v
+	o.Set = false
+}
+
+// SetTo sets value to v.
+func (o *OptString) SetTo(v string) {
+	o.Set = true
+	o.Value = v
+}
+
+// Get returns value and boolean that denotes whether value was set.
+func (o OptString) Get() (v string, ok bool) {
+	if !o.Set {
+		return v, false
+	}
+	return o.Value, true
+}
+
+// Or returns value if set, or given parameter if does not.
+func (o OptString) Or(d string) string {
+	if v, ok := o.Get(); ok {
+		return v
+	}
+	return d
+}
+
+// Ref: #/components/schemas/ProgressionRecord
+type ProgressionRecord struct {
+	ID          int64     `json:"id"`
+	Date        time.Time `json:"date"`
+	Name        string    `json:"name"`
+	ElapsedTime float64   `json:"elapsed_time"`
+	Improvement float64   `json:"improvement"`
+	AllTime     bool      `json:"all_time"`
+}
+
+// GetID returns the value of ID.
+func (s *ProgressionRecord) GetID() int64 {
+	return s.ID
+}
+
+// GetDate returns the value of Date.
+func (s *ProgressionRecord) GetDate() time.Time {
+	return s.Date
+}
+
+// GetName returns the value of Name.
+func (s *ProgressionRecord) GetName() string {
+	return s.Name
+}
+
+// GetElapsedTime returns the value of ElapsedTime.
+func (s *ProgressionRecord) GetElapsedTime() float64 {
+	return s.ElapsedTime
+}
+
+// GetImprovement returns the value of Improvement.
+func (s *ProgressionRecord) GetImprovement() float64 {
+	return s.Improvement
+}
+
+// GetAllTime returns the value of AllTime.
+func (s *ProgressionRecord) GetAllTime() bool {
+	return s.AllTime
+}
+
+// SetID sets the value of ID.
+func (s *ProgressionRecord) SetID(val int64) {
+	s.ID = val
+}
+
+// SetDate sets the value of Date.
+func (s *ProgressionRecord) SetDate(val time.Time) {
+	s.Date = val
+}
+
+// SetName sets the value of Name.
+func (s *ProgressionRecord) SetName(val string) {
+	s.Name = val
+}
+
+// SetElapsedTime sets the value of ElapsedTime.
+func (s *ProgressionRecord) SetElapsedTime(val float64) {
+	s.ElapsedTime = val
+}
+
+// SetImprovement sets the value of Improvement.
+func (s *ProgressionRecord) SetImprovement(val float64) {
+	s.Improvement = val
+}
+
+// SetAllTime sets the value of AllTime.
+func (s *ProgressionRecord) SetAllTime(val bool) {
+	s.AllTime = val
+}
+
+// Ref: #/components/schemas/Split
+type Split struct {
+	Split         int     `json:"split"`
+	Distance      float64 `json:"distance"`
+	ElapsedTime   float64 `json:"elapsed_time"`
+	MovingTime    float64 `json:"moving_time"`
+	ElevationDiff float64 `json:"elevation_diff"`
+}
+
+// GetSplit returns the value of Split.
+func (s *Split) GetSplit() int {
+	return s.Split
+}
+
+// GetDistance returns the value of Distance.
+func (s *Split) GetDistance() float64 {
+	return s.Distance
+}
+
+// GetElapsedTime returns the value of ElapsedTime.
+func (s *Split) GetElapsedTime() float64 {
+	return s.ElapsedTime
+}
+
+// GetMovingTime returns the value of MovingTime.
+func (s *Split) GetMovingTime() float64 {
+	return s.MovingTime
+}
+
+// GetElevationDiff returns the value of ElevationDiff.
+func (s *Split) GetElevationDiff() float64 {
+	return s.ElevationDiff
+}
+
+// SetSplit sets the value of Split.
+func (s *Split) SetSplit(val int) {
+	s.Split = val
+}
+
+// SetDistance sets the value of Distance.
+func (s *Split) SetDistance(val float64) {
+	s.Distance = val
+}
+
+// SetElapsedTime sets the value of ElapsedTime.
+func (s *Split) SetElapsedTime(val float64) {
+	s.ElapsedTime = val
+}
+
+// SetMovingTime sets the value of MovingTime.
+func (s *Split) SetMovingTime(val float64) {
+	s.MovingTime = val
+}
+
+// SetElevationDiff sets the value of ElevationDiff.
+func (s *Split) SetElevationDiff(val float64) {
+	s.ElevationDiff = val
+}
+
+// Value for each period (row) and year (column). Null means no activities.
+// Ref: #/components/schemas/Stats
+type Stats struct {
+	Measure   string         `json:"measure"`
+	Dimension string         `json:"dimension"`
+	Period    string         `json:"period"`
+	Years     []int          `json:"years"`
+	Values    [][]NilFloat64 `json:"values"`
+	Totals    []NilFloat64   `json:"totals"`
+}
+
+// GetMeasure returns the value of Measure.
+func (s *Stats) GetMeasure() string {
+	return s.Measure
+}
+
+// GetDimension returns the value of Dimension.
+func (s *Stats) GetDimension() string {
+	return s.Dimension
+}
+
+// GetPeriod returns the value of Period.
+func (s *Stats) GetPeriod() string {
+	return s.Period
+}
+
+// GetYears returns the value of Years.
+func (s *Stats) GetYears() []int {
+	return s.Years
+}
+
+// GetValues returns the value of Values.
+func (s *Stats) GetValues() [][]NilFloat64 {
+	return s.Values
+}
+
+// GetTotals returns the value of Totals.
+func (s *Stats) GetTotals() []NilFloat64 {
+	return s.Totals
+}
+
+// SetMeasure sets the value of Measure.
+func (s *Stats) SetMeasure(val string) {
+	s.Measure = val
+}
+
+// SetDimension sets the value of Dimension.
+func (s *Stats) SetDimension(val string) {
+	s.Dimension = val
+}
+
+// SetPeriod sets the value of Period.
+func (s *Stats) SetPeriod(val string) {
+	s.Period = val
+}
+
+// SetYears sets the value of Years.
+func (s *Stats) SetYears(val []int) {
+	s.Years = val
+}
+
+// SetValues sets the value of Values.
+func (s *Stats) SetValues(val [][]NilFloat64) {
+	s.Values = val
+}
+
+// SetTotals sets the value of Totals.
+func (s *Stats) SetTotals(val []NilFloat64) {
+	s.Totals = val
+}
+
+// Ref: #/components/schemas/Top
+type Top struct {
+	Measure   string      `json:"measure"`
+	Dimension string      `json:"dimension"`
+	Period    string      `json:"period"`
+	Periods   []TopPeriod `json:"periods"`
+}
+
+// GetMeasure returns the value of Measure.
+func (s *Top) GetMeasure() string {
+	return s.Measure
+}
+
+// GetDimension returns the value of Dimension.
+func (s *Top) GetDimension() string {
+	return s.Dimension
+}
+
+// GetPeriod returns the value of Period.
+func (s *Top) GetPeriod() string {
+	return s.Period
+}
+
+// GetPeriods returns the value of Periods.
+func (s *Top) GetPeriods() []TopPeriod {
+	return s.Periods
+}
+
+// SetMeasure sets the value of Measure.
+func (s *Top) SetMeasure(val string) {
+	s.Measure = val
+}
+
+// SetDimension sets the value of Dimension.
+func (s *Top) SetDimension(val string) {
+	s.Dimension = val
+}
+
+// SetPeriod sets the value of Period.
+func (s *Top) SetPeriod(val string) {
+	s.Period = val
+}
+
+// SetPeriods sets the value of Periods.
+func (s *Top) SetPeriods(val []TopPeriod) {
+	s.Periods = val
+}
+
+// Ref: #/components/schemas/TopPeriod
+type TopPeriod struct {
+	Year   int     `json:"year"`
+	Period int     `json:"period"`
+	Value  float64 `json:"value"`
+}
+
+// GetYear returns the value of Year.
+func (s *TopPeriod) GetYear() int {
+	return s.Year
+}
+
+// GetPeriod returns the value of Period.
+func (s *TopPeriod) GetPeriod() int {
+	return s.Period
+}
+
+// GetValue returns the value of Value.
+func (s *TopPeriod) GetValue() float64 {
+	return s.Value
+}
+
+// SetYear sets the value of Year.
+func (s *TopPeriod) SetYear(val int) {
+	s.Year = val
+}
+
+// SetPeriod sets the value of Period.
+func (s *TopPeriod) SetPeriod(val int) {
+	s.Period = val
+}
+
+// SetValue sets the value of Value.
+func (s *TopPeriod) SetValue(val float64) {
+	s.Value = val
+}
@@ -0,0 +1,76 @@
+// Code generated by ogen, DO NOT EDIT.
+
+package mystats
+
+import (
+	"context"
+)
+
+// Handler handles operations described by OpenAPI v3 specification.
+type Handler interface {
+	// GetActivity implements getActivity operation.
+	//
+	// Summary and splits of activity.
+	//
+	// GET /activities/{id}
+	GetActivity(ctx context.Context, params GetActivityParams) (*ActivityDetails, error)
+	// GetBest implements getBest operation.
+	//
+	// Best efforts for distances.
+	//
+	// GET /best
+	GetBest(ctx context.Context, params GetBestParams) ([]Best, error)
+	// GetHeartRate implements getHeartRate operation.
+	//
+	// Daily resting heart rate.
+	//
+	// GET /heartrate
+	GetHeartRate(ctx context.Context, params GetHeartRateParams) (*Daily, error)
+	// GetStats implements getStats operation.
+	//
+	// Season of selected sports and groups decides which year activity belongs to.
+	//
+	// GET /stats
+	GetStats(ctx context.Context, params GetStatsParams) (*Stats, error)
+	// GetSteps implements getSteps operation.
+	//
+	// Cumulative daily steps.
+	//
+	// GET /steps
+	GetSteps(ctx context.Context, params GetStepsParams) (*Daily, error)
+	// GetTop implements getTop operation.
+	//
+	// Periods with the best values.
+	//
+	// GET /top
+	GetTop(ctx context.Context, params GetTopParams) (*Top, error)
+	// ListActivities implements listActivities operation.
+	//
+	// Activities in chronological order.
+	//
+	// GET /activities
+	ListActivities(ctx context.Context, params ListActivitiesParams) (*Activities, error)
+	// NewError creates *ErrorStatusCode from error returned by handler.
+	//
+	// Used for common default response.
+	NewError(ctx context.Context, err error) *ErrorStatusCode
+}
+
+// Server implements http server based on OpenAPI v3 specification and
+// calls Handler to handle requests.
+type Server struct {
+	h Handler
+	baseServer
+}
+
+// NewServer creates new Server.
+func NewServer(h Handler, opts ...ServerOption) (*Server, error) {
+	s, err := newServerConfig(opts...).baseServer()
+	if err != nil {
+		return nil, err
+	}
+	return &Server{
+		h:          h,
+		baseServer: s,
+	}, nil
+}
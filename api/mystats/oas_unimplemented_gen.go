@@ -0,0 +1,85 @@
+// Code generated by ogen, DO NOT EDIT.
+
+package mystats
+
+import (
+	"context"
+
+	ht "github.com/ogen-go/ogen/http"
+)
+
+// UnimplementedHandler is no-op Handler which returns http.ErrNotImplemented.
+type UnimplementedHandler struct{}
+
+var _ Handler = UnimplementedHandler{}
+
+// GetActivity implements getActivity operation.
+//
+// Summary and splits of activity.
+//
+// GET /activities/{id}
+func (UnimplementedHandler) GetActivity(ctx context.Context, params GetActivityParams) (r *ActivityDetails, _ error) {
+	return r, ht.ErrNotImplemented
+}
+
+// GetBest implements getBest operation.
+//
+// Best efforts for distances.
+//
+// GET /best
+func (UnimplementedHandler) GetBest(ctx context.Context, params GetBestParams) (r []Best, _ error) {
+	return r, ht.ErrNotImplemented
+}
+
+// GetHeartRate implements getHeartRate operation.
+//
+// Daily resting heart rate.
+//
+// GET /heartrate
+func (UnimplementedHandler) GetHeartRate(ctx context.Context, params GetHeartRateParams) (r *Daily, _ error) {
+	return r, ht.ErrNotImplemented
+}
+
+// GetStats implements getStats operation.
+//
+// Season of selected sports and groups decides which year activity belongs to.
+//
+// GET /stats
+func (UnimplementedHandler) GetStats(ctx context.Context, params GetStatsParams) (r *Stats, _ error) {
+	return r, ht.ErrNotImplemented
+}
+
+// GetSteps implements getSteps operation.
+//
+// Cumulative daily steps.
+//
+// GET /steps
+func (UnimplementedHandler) GetSteps(ctx context.Context, params GetStepsParams) (r *Daily, _ error) {
+	return r, ht.ErrNotImplemented
+}
+
+// GetTop implements getTop operation.
+//
+// Periods with the best values.
+//
+// GET /top
+func (UnimplementedHandler) GetTop(ctx context.Context, params GetTopParams) (r *Top, _ error) {
+	return r, ht.ErrNotImplemented
+}
+
+// ListActivities implements listActivities operation.
+//
+// Activities in chronological order.
+//
+// GET /activities
+func (UnimplementedHandler) ListActivities(ctx context.Context, params ListActivitiesParams) (r *Activities, _ error) {
+	return r, ht.ErrNotImplemented
+}
+
+// NewError creates *ErrorStatusCode from error returned by handler.
+//
+// Used for common default response.
+func (UnimplementedHandler) NewError(ctx context.Context, err error) (r *ErrorStatusCode) {
+	r = new(ErrorStatusCode)
+	return r
+}
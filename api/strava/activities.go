@@ -86,9 +86,45 @@ func (as *ActivitySummary) WorkoutType() string {
 	return fmt.Sprintf("Unknown (%d)", as.WorkoutTypeId)
 }
 
+// ActivityDetailed adds heart rate of splits and laps, which go.strava doesn't parse, into detailed activity
+type ActivityDetailed struct {
+	strava.ActivityDetailed
+	SplitsMetric   []*Split `json:"splits_metric"`
+	SplitsStandard []*Split `json:"splits_standard"`
+	Laps           []*Lap   `json:"laps"`
+}
+
+// Split is kilometer or mile split of activity
+type Split struct {
+	Distance            float64 `json:"distance"`
+	ElapsedTime         int     `json:"elapsed_time"`
+	ElevationDifference float64 `json:"elevation_difference"`
+	MovingTime          int     `json:"moving_time"`
+	Split               int     `json:"split"`
+	AverageHeartrate    float64 `json:"average_heartrate"`
+}
+
+// Lap is lap of activity, e.g. one interval or auto lap of device
+type Lap struct {
+	Name               string  `json:"name"`
+	LapIndex           int     `json:"lap_index"`
+	Distance           float64 `json:"distance"`
+	MovingTime         int     `json:"moving_time"`
+	ElapsedTime        int     `json:"elapsed_time"`
+	TotalElevationGain float64 `json:"total_elevation_gain"`
+	AverageHeartrate   float64 `json:"average_heartrate"`
+}
+
 func NewActivitiesService(ctx context.Context, client *Client) *strava.ActivitiesService {
 	_, span := telemetry.NewSpan(ctx, "api.NewActivitiesService")
 	defer span.End()
 	stravaClient := strava.NewClient(client.token, client.httpClient)
 	return strava.NewActivitiesService(stravaClient)
 }
+
+func NewActivityStreamsService(ctx context.Context, client *Client) *strava.ActivityStreamsService {
+	_, span := telemetry.NewSpan(ctx, "api.NewActivityStreamsService")
+	defer span.End()
+	stravaClient := strava.NewClient(client.token, client.httpClient)
+	return strava.NewActivityStreamsService(stravaClient)
+}
@@ -24,6 +24,7 @@ type Config struct {
 	ExpiresAt    int64  `json:"expires_at"    yaml:"expiresAt"`
 	Summaries    string `json:"summaries"     yaml:"summaries"`
 	Activities   string `json:"activities"    yaml:"activities"`
+	Streams      string `json:"streams"       yaml:"streams"`
 }
 
 const tokenURL string = "https://www.strava.com/oauth/token" // #nosec G101
@@ -89,20 +90,21 @@ func (cfg *Config) Refresh() (*Config, bool, error) {
 	tokens.ClientSecret = cfg.ClientSecret
 	tokens.Activities = cfg.Activities
 	tokens.Summaries = cfg.Summaries
+	tokens.Streams = cfg.Streams
 	return &tokens, true, nil
 }
 
-func ReadActivityJSONs(ctx context.Context, fnames []string) ([]strava.ActivityDetailed, error) {
+func ReadActivityJSONs(ctx context.Context, fnames []string) ([]ActivityDetailed, error) {
 	_, span := telemetry.NewSpan(ctx, "api.ReadActivityJSONs")
 	defer span.End()
 
-	acts := []strava.ActivityDetailed{}
+	acts := []ActivityDetailed{}
 	for _, fname := range fnames {
 		body, err := os.ReadFile(filepath.Clean(fname))
 		if err != nil {
 			return acts, telemetry.Error(span, err)
 		}
-		activity := strava.ActivityDetailed{}
+		activity := ActivityDetailed{}
 		if err = json.Unmarshal(body, &activity); err != nil {
 			return acts, telemetry.Error(span, err)
 		}
@@ -111,6 +113,29 @@ func ReadActivityJSONs(ctx context.Context, fnames []string) ([]strava.ActivityD
 	return acts, nil
 }
 
+// ReadStreamJSONs reads time, distance and altitude streams. Key in map is Strava ID of activity.
+func ReadStreamJSONs(ctx context.Context, fnames []string) (map[int64]*strava.StreamSet, error) {
+	_, span := telemetry.NewSpan(ctx, "api.ReadStreamJSONs")
+	defer span.End()
+
+	streams := map[int64]*strava.StreamSet{}
+	for _, fname := range fnames {
+		body, err := os.ReadFile(filepath.Clean(fname))
+		if err != nil {
+			return streams, telemetry.Error(span, err)
+		}
+		stream := struct {
+			ID      int64             `json:"id"`
+			Streams *strava.StreamSet `json:"streams"`
+		}{}
+		if err = json.Unmarshal(body, &stream); err != nil {
+			return streams, telemetry.Error(span, err)
+		}
+		streams[stream.ID] = stream.Streams
+	}
+	return streams, nil
+}
+
 // ReadSummaryJSONs reads on pages JSON files
 func ReadSummaryJSONs(fnames []string) ([]ActivitySummary, error) {
 	ids := map[int64]string{}
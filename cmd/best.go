@@ -1,31 +1,35 @@
 package cmd
 
 import (
-	"fmt"
-
 	_ "github.com/mattn/go-sqlite3"
 	"github.com/spf13/cobra"
 
+	"github.com/jylitalo/mystats/pkg/output"
+	"github.com/jylitalo/mystats/pkg/present"
+	"github.com/jylitalo/mystats/pkg/season"
 	"github.com/jylitalo/mystats/pkg/stats"
+	"github.com/jylitalo/mystats/pkg/units"
 )
 
 // topCmd turns sqlite db into table or csv by week/month/...
-func bestCmd() *cobra.Command {
+func bestCmd(seasons []season.Season) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "best",
 		Short: "Best Run Efforts based on Strava",
 		RunE: func(cmd *cobra.Command, args []string) error {
 			flags := cmd.Flags()
-			format, _ := flags.GetString("format")
 			limit, _ := flags.GetInt("limit")
 			distance, _ := flags.GetString("distance")
 			update, _ := flags.GetBool("update")
-			formatFn := map[string]func(headers []string, results [][]string){
-				"csv":   printTopCSV,
-				"table": printTopTable,
+			progression, _ := flags.GetBool("progression")
+			seasonName, _ := flags.GetString("season")
+			filters, err := dateRange(flags)
+			if err != nil {
+				return err
 			}
-			if _, ok := formatFn[format]; !ok {
-				return fmt.Errorf("unknown format: %s", format)
+			opts, err := outputOptions(flags, false)
+			if err != nil {
+				return err
 			}
 			ctx := cmd.Context()
 			db, err := makeDB(ctx, update)
@@ -33,17 +37,36 @@ func bestCmd() *cobra.Command {
 				return err
 			}
 			defer func() { _ = db.Close() }()
-			headers, results, err := stats.Best(ctx, db, distance, limit)
-			if err != nil {
-				return err
+			var headers []string
+			var results [][]string
+			prefs := units.FromContext(ctx)
+			if progression {
+				// best efforts are runs, so season records follow season of runs
+				_, seasonOpts, err := seasonFilter(seasons, []string{"Run"}, seasonName)
+				if err != nil {
+					return err
+				}
+				records, err := stats.BestProgression(ctx, db, distance, append(filters, seasonOpts...)...)
+				if err != nil {
+					return err
+				}
+				headers, results = present.Progression(distance, records, prefs)
+			} else {
+				efforts, err := stats.Best(ctx, db, distance, limit, filters...)
+				if err != nil {
+					return err
+				}
+				headers, results = present.Best(distance, efforts, prefs)
 			}
-			formatFn[format](headers, results)
-			return nil
+			return output.Write(cmd.OutOrStdout(), output.Table{Headers: headers, Rows: results}, opts)
 		},
 	}
-	cmd.Flags().String("format", "csv", "output format (csv, table)")
 	cmd.Flags().String("distance", "Marathon", "Best Efforts distance")
 	cmd.Flags().Int("limit", 10, "number of entries")
+	cmd.Flags().Bool("progression", false, "list efforts that set new all-time or season best")
+	cmd.Flags().String("season", "", "season of --progression from config (default: season of Run or calendar year)")
 	cmd.Flags().Bool("update", true, "update database")
+	addDateRangeFlags(cmd)
+	addOutputFlags(cmd, "csv", false)
 	return cmd
 }
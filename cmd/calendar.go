@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/spf13/cobra"
+
+	"github.com/jylitalo/mystats/pkg/chart"
+	"github.com/jylitalo/mystats/pkg/output"
+	"github.com/jylitalo/mystats/pkg/present"
+	"github.com/jylitalo/mystats/pkg/sport"
+	"github.com/jylitalo/mystats/pkg/stats"
+	"github.com/jylitalo/mystats/pkg/units"
+)
+
+// calendarCmd shows one year as heatmap like server's calendar page
+func calendarCmd(types []string, groups []sport.Group) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "calendar",
+		Short: "Daily training of one year as heatmap",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			flags := cmd.Flags()
+			measurement, _ := flags.GetString("measure")
+			types, _ := flags.GetStringSlice("type")
+			update, _ := flags.GetBool("update")
+			year, _ := flags.GetInt("year")
+			day, _ := flags.GetString("date")
+			opts, err := outputOptions(flags, true)
+			if err != nil {
+				return err
+			}
+			var selected time.Time
+			if day != "" {
+				if selected, err = time.Parse(time.DateOnly, day); err != nil {
+					return fmt.Errorf("date must be YYYY-MM-DD (not %s)", day)
+				}
+				year = selected.Year()
+			}
+			types, filters := sportFilters(groups, types)
+			ctx := cmd.Context()
+			db, err := makeDB(ctx, update)
+			if err != nil {
+				return err
+			}
+			defer func() { _ = db.Close() }()
+			result, err := stats.Calendar(ctx, db, measurement, year, types, nil, filters...)
+			if err != nil {
+				return err
+			}
+			activities, err := stats.List(ctx, db, types, nil, []int{year}, 0, "", filters...)
+			if err != nil {
+				return err
+			}
+			prefs := units.FromContext(ctx)
+			if opts.Format != chartFormat {
+				headers, results := present.Calendar(result, activities, prefs)
+				return output.Write(cmd.OutOrStdout(), output.Table{Headers: headers, Rows: results}, opts)
+			}
+			format := func(value float64) string {
+				return strings.TrimSpace(present.Value(result.Measure, prefs.System, value))
+			}
+			title := fmt.Sprintf("%s %d", present.Header(result.Measure, prefs.System), year)
+			extras := chart.CalendarExtras{Weeks: result.Weeks, Months: result.Months}
+			err = chart.Calendar(cmd.OutOrStdout(), chart.Detect(os.Stdout), title, year, result.Days, format, extras)
+			if err != nil || selected.IsZero() {
+				return err
+			}
+			dayActivities := []stats.Activity{}
+			for _, a := range activities {
+				if a.Date.Equal(selected) {
+					dayActivities = append(dayActivities, a)
+				}
+			}
+			headers, results := present.List(dayActivities, prefs)
+			caption := strings.TrimSpace(present.Date(selected, prefs.Locale))
+			_, _ = fmt.Fprintln(cmd.OutOrStdout())
+			return output.Write(
+				cmd.OutOrStdout(), output.Table{Caption: caption, Headers: headers, Rows: results},
+				output.Options{Format: "table"},
+			)
+		},
+	}
+	cmd.Flags().String("measure", "distance",
+		fmt.Sprintf("measurement type %v or %s", stats.Measures(), stats.StepsMeasure))
+	cmd.Flags().StringSlice("type", types, "sport types or groups (Run, TrailRun, running, ...)")
+	cmd.Flags().Bool("update", true, "update database")
+	cmd.Flags().Int("year", time.Now().Year(), "year of calendar")
+	cmd.Flags().String("date", "", "list activities of day (YYYY-MM-DD), overrides --year")
+	addOutputFlags(cmd, chartFormat, true)
+	return cmd
+}
@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/jylitalo/mystats/pkg/chart"
+	"github.com/jylitalo/mystats/pkg/present"
+	"github.com/jylitalo/mystats/pkg/season"
+	"github.com/jylitalo/mystats/pkg/stats"
+	"github.com/jylitalo/mystats/pkg/units"
+)
+
+// chartHeight is number of rows in line charts
+const chartHeight = 20
+
+// statsChart draws bar for every year under each period that has activities
+func statsChart(
+	w io.Writer, t chart.Terminal, result *stats.StatsResult, s season.Season, prefs units.Preferences,
+) error {
+	labels, series := present.StatsSeries(result, s)
+	format := func(value float64) string {
+		return strings.TrimSpace(present.Value(result.Measure, prefs.System, value))
+	}
+	return chart.Bars(w, t, present.Header(result.Measure, prefs.System), labels, series, format)
+}
+
+// plotChart draws cumulative values of each year as lines. Values are already in user's units.
+func plotChart(
+	w io.Writer, t chart.Terminal, title, unit string, labels []string, years []int,
+	values map[int][]float64, first time.Time,
+) error {
+	series := make([]chart.Series, len(years))
+	for idx, year := range years {
+		series[idx] = chart.Series{Name: labels[idx], Values: values[year]}
+	}
+	xLabel := func(day int) string {
+		return first.AddDate(0, 0, day).Format("Jan 02")
+	}
+	format := func(value float64) string {
+		return fmt.Sprintf("%.0f%s", value, unit)
+	}
+	return chart.Lines(w, t, title, series, chartHeight, xLabel, format)
+}
+
+// splitSparklines draws pace of splits for each activity
+func splitSparklines(ctx context.Context, db stats.Storage, activities []stats.Activity) ([]string, error) {
+	system := units.FromContext(ctx).System
+	lines := make([]string, len(activities))
+	for idx, a := range activities {
+		splits, err := stats.Split(ctx, db, a.StravaID)
+		if err != nil {
+			return nil, err
+		}
+		lines[idx] = present.Sparkline(present.SplitPaces(splits, system))
+	}
+	return lines, nil
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 
 	"github.com/jylitalo/mystats/config"
+	"github.com/jylitalo/mystats/pkg/units"
 	"github.com/spf13/cobra"
 )
 
@@ -21,11 +22,26 @@ func Execute(ctx context.Context) error {
 	if err != nil {
 		return fmt.Errorf("config.Get due to %w", err)
 	}
+	rootCmd.PersistentFlags().String("units", cfg.Units, "unit system (metric, imperial)")
+	rootCmd.PersistentFlags().String("locale", cfg.Locale, "date format (fi, en-GB, en-US, iso)")
+	rootCmd.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		system, _ := cmd.Flags().GetString("units")
+		locale, _ := cmd.Flags().GetString("locale")
+		prefs, err := units.Parse(system, locale)
+		if err != nil {
+			return err
+		}
+		cmd.SetContext(units.WithContext(cmd.Context(), prefs))
+		return nil
+	}
 	types := cfg.Default.Types
+	groups := cfg.Groups
 	rootCmd.AddCommand(
 		configureCmd(), fetchCmd(), makeCmd(),
-		bestCmd(), listCmd(types), statsCmd(types), topCmd(types),
-		serverCmd(types),
+		bestCmd(cfg.Seasons), calendarCmd(types, groups), listCmd(types, groups), plotCmd(types, groups, cfg.Seasons), queryCmd(),
+		rollingCmd(types, groups),
+		showCmd(), statsCmd(types, groups, cfg.Seasons), topCmd(types, groups),
+		passwdCmd(), serverCmd(types, groups, cfg.Seasons), tuiCmd(types, groups, cfg.Seasons),
 	)
 	return rootCmd.ExecuteContext(ctx)
 }
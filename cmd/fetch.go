@@ -13,6 +13,7 @@ import (
 	"time"
 
 	"github.com/spf13/cobra"
+	stravaapi "github.com/strava/go.strava"
 
 	gogarmin "github.com/jylitalo/go-garmin"
 	"github.com/jylitalo/mystats/api/garmin"
@@ -36,14 +37,26 @@ func fetchCmd() *cobra.Command {
 		RunE: func(cmd *cobra.Command, args []string) error {
 			flags := cmd.Flags()
 			be, _ := flags.GetBool("best_efforts")
-			return fetch(cmd.Context(), be)
+			streams, _ := flags.GetBool("streams")
+			return fetch(cmd.Context(), be, streams)
 		},
 	}
 	cmd.Flags().Bool("best_efforts", true, "Fetch activities best efforts")
+	cmd.Flags().Bool("streams", false, "Fetch time, distance and altitude streams of activities")
 	return cmd
 }
 
-func fetch(ctx context.Context, best_efforts bool) error {
+func fetch(ctx context.Context, best_efforts, streams bool) error {
+	err := fetchAPIs(ctx, best_efforts, streams)
+	if err != nil && strava.IsRateLimitExceeded(err) {
+		slog.Warn("Strava API Rate Limit Exceeded")
+		return nil
+	}
+	return err
+}
+
+// fetchAPIs fetches from Garmin and Strava. Unlike fetch, it returns error, when Strava's rate limit was exceeded.
+func fetchAPIs(ctx context.Context, best_efforts, streams bool) error {
 	ctx, span := telemetry.NewSpan(ctx, "fetch")
 	defer span.End()
 
@@ -71,13 +84,13 @@ func fetch(ctx context.Context, best_efforts bool) error {
 		return telemetry.Error(span, err)
 	}
 	ids, apiCalls, err := saveStravaSummaries(ctx, call, status.pages)
+	// details and streams of older activities may still be missing
+	ids = append(ids, status.ids...)
 	if err == nil && best_efforts {
-		ids = append(ids, status.ids...)
-		err = fetchActivityDetails(ctx, stravaClient, ids, apiCalls)
+		apiCalls, err = fetchActivityDetails(ctx, stravaClient, ids, apiCalls)
 	}
-	if err != nil && strava.IsRateLimitExceeded(err) {
-		slog.Warn("Strava API Rate Limit Exceeded")
-		return nil
+	if err == nil && streams {
+		err = fetchActivityStreams(ctx, stravaClient, ids, apiCalls)
 	}
 	return telemetry.Error(span, err)
 }
@@ -172,36 +185,82 @@ func getStravaClient(ctx context.Context) (context.Context, *strava.Client, erro
 	return ctx, client, nil
 }
 
-func fetchActivityDetails(ctx context.Context, client *strava.Client, ids []int64, apiCalls int) error {
+func fetchActivityDetails(ctx context.Context, client *strava.Client, ids []int64, apiCalls int) (int, error) {
 	ctx, span := telemetry.NewSpan(ctx, "fetchBestEfforts")
 	defer span.End()
 	if len(ids) == 0 {
-		return telemetry.Error(span, errors.New("no stravaIDs found from database"))
+		return apiCalls, telemetry.Error(span, errors.New("no stravaIDs found from database"))
 	}
 	cfg, err := config.Get(ctx)
 	if err != nil {
-		return err
+		return apiCalls, err
 	}
 	path := cfg.Strava.Activities
 	if path == "" {
-		return telemetry.Error(span, errors.New("path is empty"))
+		return apiCalls, telemetry.Error(span, errors.New("path is empty"))
 	}
 	errPath := mkdir(path)
 	alreadyFetched, errAct := alreadyFetchedDetails(path)
 	if err = errors.Join(errPath, errAct); err != nil {
-		return telemetry.Error(span, err)
+		return apiCalls, telemetry.Error(span, err)
 	}
 	service := strava.NewActivitiesService(ctx, client)
 	for idx, id := range data.Reduce(ids, alreadyFetched) {
 		activity, err := service.Get(id).Do()
 		if err != nil {
-			return telemetry.Error(span, err)
+			return apiCalls, telemetry.Error(span, err)
 		}
 		data, err := json.Marshal(activity)
 		if err != nil {
-			return telemetry.Error(span, err)
+			return apiCalls, telemetry.Error(span, err)
 		}
 		if err = os.WriteFile(fmt.Sprintf("%s/activity_%d.json", path, id), data, 0o600); err != nil {
+			return apiCalls, telemetry.Error(span, err)
+		}
+		if apiCalls++; apiCalls >= 90 {
+			slog.Info("Already fetched 90 activities", "left", len(ids)-idx)
+			return apiCalls, nil
+		}
+	}
+	slog.Info("Activity details fetched", "fetched", apiCalls)
+	return apiCalls, nil
+}
+
+// fetchActivityStreams fetches time and distance streams that are used for computing custom best efforts.
+// Altitude stream is for elevation profile of activity page.
+func fetchActivityStreams(ctx context.Context, client *strava.Client, ids []int64, apiCalls int) error {
+	ctx, span := telemetry.NewSpan(ctx, "fetchActivityStreams")
+	defer span.End()
+	if apiCalls >= 90 {
+		return nil
+	}
+	cfg, err := config.Get(ctx)
+	if err != nil {
+		return err
+	}
+	path := cfg.Strava.Streams
+	if path == "" {
+		return telemetry.Error(span, errors.New("path is empty"))
+	}
+	errPath := mkdir(path)
+	alreadyFetched, errStr := alreadyFetchedStreams(path)
+	if err = errors.Join(errPath, errStr); err != nil {
+		return telemetry.Error(span, err)
+	}
+	types := []stravaapi.StreamType{
+		stravaapi.StreamTypes.Time, stravaapi.StreamTypes.Distance, stravaapi.StreamTypes.Elevation,
+	}
+	service := strava.NewActivityStreamsService(ctx, client)
+	for idx, id := range data.Reduce(ids, alreadyFetched) {
+		streams, err := service.Get(id, types).Do()
+		if err != nil {
+			return telemetry.Error(span, err)
+		}
+		data, err := json.Marshal(map[string]interface{}{"id": id, "streams": streams})
+		if err != nil {
+			return telemetry.Error(span, err)
+		}
+		if err = os.WriteFile(fmt.Sprintf("%s/stream_%d.json", path, id), data, 0o600); err != nil {
 			return telemetry.Error(span, err)
 		}
 		if apiCalls++; apiCalls >= 90 {
@@ -209,7 +268,7 @@ func fetchActivityDetails(ctx context.Context, client *strava.Client, ids []int6
 			return nil
 		}
 	}
-	slog.Info("Activity details fetched", "fetched", apiCalls)
+	slog.Info("Activity streams fetched", "fetched", apiCalls)
 	return nil
 }
 
@@ -217,11 +276,28 @@ func activitiesFiles(path string) ([]string, error) {
 	return filepath.Glob(path + "/activity_*.json")
 }
 
+func streamsFiles(path string) ([]string, error) {
+	return filepath.Glob(path + "/stream_*.json")
+}
+
 func alreadyFetchedDetails(path string) ([]int64, error) {
 	files, err := activitiesFiles(path)
 	if err != nil {
 		return nil, err
 	}
+	return fetchedIDs(files)
+}
+
+func alreadyFetchedStreams(path string) ([]int64, error) {
+	files, err := streamsFiles(path)
+	if err != nil {
+		return nil, err
+	}
+	return fetchedIDs(files)
+}
+
+// fetchedIDs parses Strava IDs from filenames like activity_123.json
+func fetchedIDs(files []string) ([]int64, error) {
 	ids := []int64{}
 	for _, actFile := range files {
 		i, err := strconv.Atoi(strings.Split(strings.Split(actFile, "_")[1], ".")[0])
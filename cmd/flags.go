@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/jylitalo/mystats/pkg/output"
+	"github.com/jylitalo/mystats/pkg/season"
+	"github.com/jylitalo/mystats/pkg/sport"
+	"github.com/jylitalo/mystats/storage"
+)
+
+// addDateRangeFlags adds --from and --to flags into command
+func addDateRangeFlags(cmd *cobra.Command) {
+	cmd.Flags().String("from", "", "only include activities from this date onwards (YYYY-MM-DD)")
+	cmd.Flags().String("to", "", "only include activities until this date (YYYY-MM-DD)")
+}
+
+// dateRange turns --from and --to flags into query options
+func dateRange(flags *pflag.FlagSet) ([]storage.QueryOption, error) {
+	from, _ := flags.GetString("from")
+	to, _ := flags.GetString("to")
+	return storage.ParseDateRange(from, to)
+}
+
+// sportFilters splits --type values into plain activity types and sport group filter
+func sportFilters(groups []sport.Group, names []string) ([]string, []storage.QueryOption) {
+	types, selected := sport.Split(groups, names)
+	if len(selected) == 0 {
+		return types, nil
+	}
+	return types, []storage.QueryOption{storage.WithSportGroups(selected...)}
+}
+
+// chartFormat is --format value for terminal charts in commands that support them
+const chartFormat = "chart"
+
+// addOutputFlags adds --format, --columns and --sort flags into command
+func addOutputFlags(cmd *cobra.Command, format string, chart bool) {
+	formats := output.Formats()
+	if chart {
+		formats = append(formats, chartFormat)
+	}
+	cmd.Flags().String("format", format, fmt.Sprintf("output format %v", formats))
+	cmd.Flags().StringSlice("columns", nil, "columns to output (default all)")
+	cmd.Flags().String("sort", "", "column to sort by, prefix with - for descending order")
+}
+
+// outputOptions turns --format, --columns and --sort flags into output options.
+// Chart format is accepted only when command supports it.
+func outputOptions(flags *pflag.FlagSet, chart bool) (output.Options, error) {
+	format, _ := flags.GetString("format")
+	columns, _ := flags.GetStringSlice("columns")
+	sort, _ := flags.GetString("sort")
+	opts := output.Options{Format: format, Columns: columns, Sort: sort}
+	if chart && format == chartFormat {
+		return opts, nil
+	}
+	return opts, output.Validate(format)
+}
+
+// seasonFilter picks season by name or, if name is empty, the season shared by sports.
+// Query options are empty for calendar year.
+func seasonFilter(seasons []season.Season, sports []string, name string) (season.Season, []storage.QueryOption, error) {
+	s := season.For(seasons, sports)
+	if name != "" {
+		var err error
+		if s, err = season.Find(seasons, name); err != nil {
+			return s, nil, err
+		}
+	}
+	if s.IsCalendar() {
+		return s, nil, nil
+	}
+	sm, sd, err := s.MonthDay()
+	if err != nil {
+		return s, nil, err
+	}
+	return s, []storage.QueryOption{storage.WithSeason(sm, sd)}, nil
+}
@@ -1,17 +1,18 @@
 package cmd
 
 import (
-	"os"
-
 	_ "github.com/mattn/go-sqlite3"
-	"github.com/olekukonko/tablewriter"
 	"github.com/spf13/cobra"
 
+	"github.com/jylitalo/mystats/pkg/output"
+	"github.com/jylitalo/mystats/pkg/present"
+	"github.com/jylitalo/mystats/pkg/sport"
 	"github.com/jylitalo/mystats/pkg/stats"
+	"github.com/jylitalo/mystats/pkg/units"
 )
 
 // listCmd turns sqlite db into table or csv by week/month/...
-func listCmd(types []string) *cobra.Command {
+func listCmd(types []string, groups []sport.Group) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "list",
 		Short: "List races or long runs",
@@ -22,27 +23,48 @@ func listCmd(types []string) *cobra.Command {
 			types, _ := flags.GetStringSlice("type")
 			update, _ := flags.GetBool("update")
 			workouts, _ := flags.GetStringSlice("workout")
+			filters, err := dateRange(flags)
+			if err != nil {
+				return err
+			}
+			opts, err := outputOptions(flags, true)
+			if err != nil {
+				return err
+			}
+			types, sportOpts := sportFilters(groups, types)
+			filters = append(filters, sportOpts...)
 			ctx := cmd.Context()
 			db, err := makeDB(ctx, update)
 			if err != nil {
 				return err
 			}
 			defer func() { _ = db.Close() }()
-			table := tablewriter.NewWriter(os.Stdout)
-			headers, results, err := stats.List(ctx, db, types, workouts, nil, limit, name)
+			activities, err := stats.List(ctx, db, types, workouts, nil, limit, name, filters...)
 			if err != nil {
 				return err
 			}
-			table.SetHeader(headers)
-			table.AppendBulk(results)
-			table.Render()
-			return nil
+			headers, results := present.List(activities, units.FromContext(ctx))
+			if opts.Format == chartFormat {
+				// chart format is table with pace sparkline of splits
+				lines, err := splitSparklines(ctx, db, activities)
+				if err != nil {
+					return err
+				}
+				headers = append(headers, "Splits")
+				for idx := range results {
+					results[idx] = append(results[idx], lines[idx])
+				}
+				opts.Format = "table"
+			}
+			return output.Write(cmd.OutOrStdout(), output.Table{Headers: headers, Rows: results}, opts)
 		},
 	}
 	cmd.Flags().Int("limit", 100, "number of activities")
 	cmd.Flags().String("name", "", "name of activity")
-	cmd.Flags().StringSlice("type", types, "sport types (run, trail run, ...)")
+	cmd.Flags().StringSlice("type", types, "sport types or groups (Run, TrailRun, running, ...)")
 	cmd.Flags().Bool("update", true, "update database")
 	cmd.Flags().StringSlice("workout", []string{}, "workout type")
+	addDateRangeFlags(cmd)
+	addOutputFlags(cmd, "table", true)
 	return cmd
 }
@@ -5,9 +5,12 @@ import (
 	"database/sql"
 	"errors"
 	"log/slog"
+	"math"
 	"os"
+	"slices"
 	"time"
 
+	gogarmin "github.com/jylitalo/go-garmin"
 	_ "github.com/mattn/go-sqlite3"
 	"github.com/spf13/cobra"
 	stravaapi "github.com/strava/go.strava"
@@ -15,7 +18,9 @@ import (
 	"github.com/jylitalo/mystats/api/garmin"
 	"github.com/jylitalo/mystats/api/strava"
 	"github.com/jylitalo/mystats/config"
+	"github.com/jylitalo/mystats/pkg/efforts"
 	"github.com/jylitalo/mystats/pkg/telemetry"
+	"github.com/jylitalo/mystats/pkg/units"
 	"github.com/jylitalo/mystats/storage"
 )
 
@@ -23,8 +28,8 @@ type Storage interface {
 	QueryBestEffortDistances(ctx context.Context) ([]string, error)
 	QuerySports(ctx context.Context) ([]string, error)
 	QueryWorkouts(ctx context.Context) ([]string, error)
-	QueryYears(ctx context.Context, opts ...storage.QueryOption) ([]int, error)
-	Query(ctx context.Context, fields []string, opts ...storage.QueryOption) (*sql.Rows, error)
+	QueryYears(ctx context.Context, t storage.Table, opts ...storage.QueryOption) ([]int, error)
+	Query(ctx context.Context, q *storage.Query) (*sql.Rows, error)
 	Close() error
 }
 
@@ -49,6 +54,9 @@ func makeCmd() *cobra.Command {
 }
 
 func skipDB(db *storage.Sqlite3, fnames []string) bool {
+	if version, err := db.Version(); err != nil || version != storage.SchemaVersion {
+		return false
+	}
 	dbMtime, _ := db.LastModified()
 	pagesMtime := time.Date(1970, time.January, 1, 0, 0, 0, 0, time.UTC)
 	for _, fname := range fnames {
@@ -69,52 +77,208 @@ func makeDB(ctx context.Context, update bool) (Storage, error) {
 
 	slog.Info("Fetch activities from Strava")
 	if update {
-		if err := fetch(ctx, true); err != nil {
+		if err := fetch(ctx, true, false); err != nil {
 			return nil, telemetry.Error(span, err)
 		}
 	}
-	cfg, err := config.Get(ctx)
+	db := &storage.Sqlite3{}
+	fnames, err := jsonFiles(ctx)
 	if err != nil {
 		return nil, telemetry.Error(span, err)
 	}
-	pageFnames, errP := pageFiles(cfg.Strava.Summaries)
-	actFnames, errF := activitiesFiles(cfg.Strava.Activities)
-	stepsFiles, errS := stepsFiles(cfg.Garmin.DailySteps)
-	heartRateFiles, errHR := heartRateFiles(cfg.Garmin.HeartRate)
-	if err := errors.Join(errP, errF, errS, errHR); err != nil {
-		return nil, telemetry.Error(span, err)
-	}
-	db := &storage.Sqlite3{}
-	if skipDB(db, append(pageFnames, actFnames...)) {
+	if skipDB(db, fnames.all()) {
 		slog.Info("Database is uptodate")
 		return db, telemetry.Error(span, db.Open())
 	}
 	slog.Info("Making database")
-	summaries, errS := strava.ReadSummaryJSONs(pageFnames)
-	acts, errA := strava.ReadActivityJSONs(ctx, actFnames)
-	dbDailySteps, errDS := garmin.ReadDailyStepsJSONs(ctx, stepsFiles)
-	dbHeartRate, errHR := garmin.ReadHeartRateJSONs(ctx, heartRateFiles)
-	if err := errors.Join(errS, errA, errDS, errHR); err != nil {
+	r, err := readRecords(ctx, fnames)
+	if err != nil {
 		return nil, telemetry.Error(span, err)
 	}
 	ctx, spanDB := telemetry.NewSpan(ctx, "rebuildDB")
 	defer spanDB.End()
 	return db, telemetry.Error(spanDB, errors.Join(
 		db.Remove(), db.Open(), db.Create(),
-		db.InsertSummary(ctx, getDbActivities(summaries)),
-		db.InsertBestEffort(ctx, getDbBestEfforts(acts)),
-		db.InsertSplit(ctx, getDbSplits(acts)),
-		db.InsertDailySteps(ctx, dbDailySteps),
-		db.InsertHeartRate(ctx, dbHeartRate),
+		db.InsertSummary(ctx, r.summaries),
+		db.InsertBestEffort(ctx, r.efforts),
+		db.InsertSplit(ctx, r.splits),
+		db.InsertLap(ctx, r.laps),
+		db.InsertElevationProfile(ctx, r.profiles),
+		db.InsertDailySteps(ctx, r.steps),
+		db.InsertHeartRate(ctx, r.heartRate),
+	))
+}
+
+// files are fetched JSON files
+type files struct {
+	pages, activities, steps, heartRate, streams []string
+}
+
+// all has files that database is made from
+func (f files) all() []string {
+	return slices.Concat(f.pages, f.activities, f.streams)
+}
+
+func jsonFiles(ctx context.Context) (files, error) {
+	cfg, err := config.Get(ctx)
+	if err != nil {
+		return files{}, err
+	}
+	pages, errP := pageFiles(cfg.Strava.Summaries)
+	activities, errF := activitiesFiles(cfg.Strava.Activities)
+	steps, errS := stepsFiles(cfg.Garmin.DailySteps)
+	heartRate, errHR := heartRateFiles(cfg.Garmin.HeartRate)
+	streams, errStr := streamsFiles(cfg.Strava.Streams)
+	return files{pages: pages, activities: activities, steps: steps, heartRate: heartRate, streams: streams},
+		errors.Join(errP, errF, errS, errHR, errStr)
+}
+
+// records are rows of every table
+type records struct {
+	summaries []storage.SummaryRecord
+	efforts   []storage.BestEffortRecord
+	splits    []storage.SplitRecord
+	laps      []storage.LapRecord
+	profiles  []storage.ElevationProfileRecord
+	steps     map[string]gogarmin.DailyStepsStat
+	heartRate map[string]gogarmin.HeartRateStat
+}
+
+func readRecords(ctx context.Context, fnames files) (*records, error) {
+	cfg, err := config.Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	summaries, errS := strava.ReadSummaryJSONs(fnames.pages)
+	acts, errA := strava.ReadActivityJSONs(ctx, fnames.activities)
+	dbDailySteps, errDS := garmin.ReadDailyStepsJSONs(ctx, fnames.steps)
+	dbHeartRate, errHR := garmin.ReadHeartRateJSONs(ctx, fnames.heartRate)
+	streams, errStr := strava.ReadStreamJSONs(ctx, fnames.streams)
+	if err := errors.Join(errS, errA, errDS, errHR, errStr); err != nil {
+		return nil, err
+	}
+	return &records{
+		summaries: getDbActivities(summaries),
+		efforts: append(
+			getDbBestEfforts(acts),
+			getDbCustomEfforts(acts, streams, cfg.Efforts.Distances, cfg.Efforts.Durations)...,
+		),
+		splits:    getDbSplits(acts),
+		laps:      getDbLaps(acts),
+		profiles:  getDbElevationProfiles(streams),
+		steps:     dbDailySteps,
+		heartRate: dbHeartRate,
+	}, nil
+}
+
+// updateDB inserts activities that database doesn't have yet and replaces Garmin's latest days.
+// Unlike makeDB, database stays open, so that server can keep using it. Edited activities are
+// updated only by make.
+func updateDB(ctx context.Context, db *storage.Sqlite3) error {
+	ctx, span := telemetry.NewSpan(ctx, "updateDB")
+	defer span.End()
+
+	fnames, err := jsonFiles(ctx)
+	if err != nil {
+		return telemetry.Error(span, err)
+	}
+	if skipDB(db, slices.Concat(fnames.all(), fnames.steps, fnames.heartRate)) {
+		return nil
+	}
+	r, err := readRecords(ctx, fnames)
+	if err != nil {
+		return telemetry.Error(span, err)
+	}
+	summaryIDs, errS := knownIDs(ctx, db, storage.SummaryTable, storage.Summary.StravaID)
+	effortIDs, errE := knownIDs(ctx, db, storage.BestEffortTable, storage.BestEffort.StravaID)
+	splitIDs, errSp := knownIDs(ctx, db, storage.SplitTable, storage.Split.StravaID)
+	lapIDs, errL := knownIDs(ctx, db, storage.LapTable, storage.Lap.StravaID)
+	profileIDs, errP := knownIDs(ctx, db, storage.ElevationProfileTable, storage.ElevationProfile.StravaID)
+	stepsSince, errDS := latestDay(ctx, db, storage.DailyStepsTable, storage.DailySteps.Date)
+	heartRateSince, errHR := latestDay(ctx, db, storage.HeartRateTable, storage.HeartRate.Date)
+	if err = errors.Join(errS, errE, errSp, errL, errP, errDS, errHR); err != nil {
+		return telemetry.Error(span, err)
+	}
+	return telemetry.Error(span, errors.Join(
+		db.InsertSummary(ctx, unknown(r.summaries, summaryIDs, func(r storage.SummaryRecord) int64 {
+			return r.StravaID
+		})),
+		db.InsertBestEffort(ctx, unknown(r.efforts, effortIDs, func(r storage.BestEffortRecord) int64 {
+			return r.StravaID
+		})),
+		db.InsertSplit(ctx, unknown(r.splits, splitIDs, func(r storage.SplitRecord) int64 { return r.StravaID })),
+		db.InsertLap(ctx, unknown(r.laps, lapIDs, func(r storage.LapRecord) int64 { return r.StravaID })),
+		db.InsertElevationProfile(ctx, unknown(r.profiles, profileIDs, func(r storage.ElevationProfileRecord) int64 {
+			return r.StravaID
+		})),
+		db.DeleteSince(ctx, storage.DailyStepsTable, stepsSince),
+		db.InsertDailySteps(ctx, since(r.steps, stepsSince)),
+		db.DeleteSince(ctx, storage.HeartRateTable, heartRateSince),
+		db.InsertHeartRate(ctx, since(r.heartRate, heartRateSince)),
 	))
 }
 
+// knownIDs has Strava IDs that table already has
+func knownIDs(ctx context.Context, db Storage, t storage.Table, column storage.Column) (map[int64]bool, error) {
+	rows, err := db.Query(ctx, storage.Select(column).From(t).GroupBy(column))
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+	ids := map[int64]bool{}
+	for rows.Next() {
+		var id int64
+		if err = rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids[id] = true
+	}
+	return ids, rows.Err()
+}
+
+// unknown drops records of activities that table already has
+func unknown[T any](records []T, known map[int64]bool, id func(T) int64) []T {
+	return slices.DeleteFunc(records, func(r T) bool { return known[id(r)] })
+}
+
+// latestDay is the latest day in Garmin's table. Empty table returns zero time.
+func latestDay(ctx context.Context, db Storage, t storage.Table, column storage.Column) (time.Time, error) {
+	rows, err := db.Query(ctx, storage.Select(storage.Max(column)).From(t))
+	if err != nil {
+		return time.Time{}, err
+	}
+	defer func() { _ = rows.Close() }()
+	var latest sql.NullString
+	if rows.Next() {
+		if err = rows.Scan(&latest); err != nil {
+			return time.Time{}, err
+		}
+	}
+	if !latest.Valid {
+		return time.Time{}, rows.Err()
+	}
+	return time.Parse(time.DateTime, latest.String)
+}
+
+// since drops Garmin's days that are before day
+func since[T any](records map[string]T, day time.Time) map[string]T {
+	from := day.Format(time.DateOnly)
+	result := map[string]T{}
+	for key, r := range records {
+		if key >= from {
+			result[key] = r
+		}
+	}
+	return result
+}
+
 func getDbActivities(activities []strava.ActivitySummary) []storage.SummaryRecord {
 	dbActivities := []storage.SummaryRecord{}
 	for _, activity := range activities {
 		t := activity.StartDateLocal
 		weekYear, week := t.ISOWeek()
 		dbActivities = append(dbActivities, storage.SummaryRecord{
+			StartDate:   t,
 			StravaID:    activity.Id,
 			Year:        int(t.Year()),
 			Month:       int(t.Month()),
@@ -129,12 +293,16 @@ func getDbActivities(activities []strava.ActivitySummary) []storage.SummaryRecor
 			Elevation:   activity.TotalElevationGain,
 			MovingTime:  activity.MovingTime,
 			ElapsedTime: activity.ElapsedTime,
+			// AverageHeartrate and Kilojoules are zero, if device didn't record them
+			AverageHeartrate: activity.AverageHeartrate,
+			Kilojoules:       activity.Kilojoules,
+			SummaryPolyline:  string(activity.Map.SummaryPolyline),
 		})
 	}
 	return dbActivities
 }
 
-func getDbBestEfforts(activities []stravaapi.ActivityDetailed) []storage.BestEffortRecord {
+func getDbBestEfforts(activities []strava.ActivityDetailed) []storage.BestEffortRecord {
 	dbEfforts := []storage.BestEffortRecord{}
 	for _, activity := range activities {
 		for _, be := range activity.BestEfforts {
@@ -150,19 +318,118 @@ func getDbBestEfforts(activities []stravaapi.ActivityDetailed) []storage.BestEff
 	return dbEfforts
 }
 
-func getDbSplits(activities []stravaapi.ActivityDetailed) []storage.SplitRecord {
-	dbSplits := []storage.SplitRecord{}
+// getDbCustomEfforts computes user defined efforts from streams or, if those are missing, from splits.
+// Efforts from other sports than running are prefixed with sport type (e.g. "Ride 20 min"),
+// so that they don't mix with running efforts. Efforts that Strava already provides for activity are skipped.
+func getDbCustomEfforts(
+	activities []strava.ActivityDetailed, streams map[int64]*stravaapi.StreamSet, distances, durations []int,
+) []storage.BestEffortRecord {
+	dbEfforts := []storage.BestEffortRecord{}
+	if len(distances) == 0 && len(durations) == 0 {
+		return dbEfforts
+	}
 	for _, activity := range activities {
-		for _, split := range activity.SplitsMetric {
-			dbSplits = append(dbSplits, storage.SplitRecord{
-				StravaID:      activity.Id,
-				Split:         split.Split,
-				MovingTime:    split.MovingTime,
-				ElapsedTime:   split.ElapsedTime,
-				ElevationDiff: split.ElevationDifference,
-				Distance:      split.Distance,
+		var points []efforts.Point
+		if s, ok := streams[activity.Id]; ok && s != nil && s.Time != nil && s.Distance != nil {
+			points = efforts.FromStreams(s.Time.Data, s.Distance.Data)
+		} else {
+			times := []int{}
+			meters := []float64{}
+			for _, split := range activity.SplitsMetric {
+				times = append(times, split.ElapsedTime)
+				meters = append(meters, split.Distance)
+			}
+			points = efforts.FromSplits(times, meters)
+		}
+		prefix := ""
+		if activity.Type != stravaapi.ActivityTypes.Run {
+			prefix = activity.Type.String() + " "
+		}
+		// Strava's own efforts (e.g. 5k) win, so that activity doesn't have two efforts with the same name
+		provided := map[string]bool{}
+		for _, be := range activity.BestEfforts {
+			provided[be.Name] = true
+		}
+		for _, r := range efforts.Compute(points, distances, durations) {
+			if provided[prefix+r.Name] {
+				continue
+			}
+			seconds := int(math.Round(r.Time))
+			dbEfforts = append(dbEfforts, storage.BestEffortRecord{
+				StravaID:    activity.Id,
+				Name:        prefix + r.Name,
+				MovingTime:  seconds,
+				ElapsedTime: seconds,
+				Distance:    int(math.Round(r.Distance)),
 			})
 		}
 	}
+	return dbEfforts
+}
+
+// getDbSplits stores both kilometer (metric) and mile (standard) splits
+func getDbSplits(activities []strava.ActivityDetailed) []storage.SplitRecord {
+	dbSplits := []storage.SplitRecord{}
+	for _, activity := range activities {
+		for system, splits := range map[units.System][]*strava.Split{
+			units.Metric:   activity.SplitsMetric,
+			units.Imperial: activity.SplitsStandard,
+		} {
+			for _, split := range splits {
+				dbSplits = append(dbSplits, storage.SplitRecord{
+					StravaID:      activity.Id,
+					Units:         string(system),
+					Split:         split.Split,
+					MovingTime:    split.MovingTime,
+					ElapsedTime:   split.ElapsedTime,
+					ElevationDiff: split.ElevationDifference,
+					Distance:      split.Distance,
+					// AverageHeartrate is zero, if device didn't record it
+					AverageHeartrate: split.AverageHeartrate,
+				})
+			}
+		}
+	}
 	return dbSplits
 }
+
+func getDbLaps(activities []strava.ActivityDetailed) []storage.LapRecord {
+	dbLaps := []storage.LapRecord{}
+	for _, activity := range activities {
+		for _, lap := range activity.Laps {
+			dbLaps = append(dbLaps, storage.LapRecord{
+				StravaID:         activity.Id,
+				Lap:              lap.LapIndex,
+				Name:             lap.Name,
+				ElapsedTime:      lap.ElapsedTime,
+				MovingTime:       lap.MovingTime,
+				Distance:         lap.Distance,
+				Elevation:        lap.TotalElevationGain,
+				AverageHeartrate: lap.AverageHeartrate,
+			})
+		}
+	}
+	return dbLaps
+}
+
+// profilePoints is the maximum number of points in elevation profile of activity
+const profilePoints = 500
+
+// getDbElevationProfiles picks evenly spaced points from distance and altitude streams.
+// Streams that were fetched without altitude are skipped and activity page falls back to splits.
+func getDbElevationProfiles(streams map[int64]*stravaapi.StreamSet) []storage.ElevationProfileRecord {
+	dbProfiles := []storage.ElevationProfileRecord{}
+	for id, s := range streams {
+		if s == nil || s.Distance == nil || s.Elevation == nil {
+			continue
+		}
+		count := min(len(s.Distance.Data), len(s.Elevation.Data))
+		step := max(1, (count+profilePoints-1)/profilePoints)
+		for idx := 0; idx < count; idx += step {
+			dbProfiles = append(dbProfiles, storage.ElevationProfileRecord{
+				StravaID: id, Distance: s.Distance.Data[idx], Altitude: s.Elevation.Data[idx],
+			})
+		}
+	}
+	return dbProfiles
+}
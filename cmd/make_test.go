@@ -0,0 +1,66 @@
+package cmd //nolint:testpackage
+
+import (
+	"slices"
+	"testing"
+
+	stravaapi "github.com/strava/go.strava"
+
+	"github.com/jylitalo/mystats/api/strava"
+)
+
+// detailed is activity with ten 5 min/km splits and Strava's best efforts of given names
+func detailed(id int64, sport stravaapi.ActivityType, bestEfforts ...string) strava.ActivityDetailed {
+	activity := strava.ActivityDetailed{}
+	activity.Id = id
+	activity.Type = sport
+	for _, name := range bestEfforts {
+		be := &stravaapi.BestEffort{}
+		be.Name = name
+		be.Distance = 5000
+		be.MovingTime = 1450
+		be.ElapsedTime = 1450
+		activity.BestEfforts = append(activity.BestEfforts, be)
+	}
+	for range 10 {
+		activity.SplitsMetric = append(activity.SplitsMetric, &strava.Split{Distance: 1000, ElapsedTime: 300})
+	}
+	return activity
+}
+
+func TestGetDbCustomEfforts(t *testing.T) {
+	values := []struct {
+		name     string
+		activity strava.ActivityDetailed
+		expected []string // names of custom efforts
+	}{
+		{name: "strava", activity: detailed(1, stravaapi.ActivityTypes.Run, "5k"), expected: []string{"20 min"}},
+		{name: "computed", activity: detailed(2, stravaapi.ActivityTypes.Run), expected: []string{"5k", "20 min"}},
+		{
+			name: "prefixed", activity: detailed(3, stravaapi.ActivityTypes.Ride, "5k"),
+			expected: []string{"Ride 5k", "Ride 20 min"},
+		},
+	}
+	for _, value := range values {
+		t.Run(value.name, func(t *testing.T) {
+			activities := []strava.ActivityDetailed{value.activity}
+			got := []string{}
+			for _, effort := range getDbCustomEfforts(activities, nil, []int{5000}, []int{1200}) {
+				got = append(got, effort.Name)
+			}
+			if !slices.Equal(got, value.expected) {
+				t.Errorf("custom efforts mismatch: %v vs. %v", got, value.expected)
+			}
+			// every effort of activity has unique name
+			names := map[string]bool{}
+			for _, effort := range getDbBestEfforts(activities) {
+				names[effort.Name] = true
+			}
+			for _, name := range got {
+				if names[name] {
+					t.Errorf("%s is both Strava's and custom effort", name)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/jylitalo/mystats/server"
+)
+
+// passwdCmd prints line for users file of server
+func passwdCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "passwd [user]",
+		Short: "Hash password of server's local user",
+		Long:  "Reads password from stdin and prints line to append into users file of server (--users)",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			_, _ = fmt.Fprint(cmd.ErrOrStderr(), "Password: ")
+			password, err := bufio.NewReader(cmd.InOrStdin()).ReadString('\n')
+			password = strings.TrimRight(password, "\r\n")
+			if password == "" {
+				return errors.Join(errors.New("password missing"), err)
+			}
+			line, err := server.HashPassword(args[0], password)
+			if err != nil {
+				return err
+			}
+			_, err = fmt.Fprintln(cmd.OutOrStdout(), line)
+			return err
+		},
+	}
+	return cmd
+}
@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/spf13/cobra"
+
+	"github.com/jylitalo/mystats/pkg/chart"
+	"github.com/jylitalo/mystats/pkg/output"
+	"github.com/jylitalo/mystats/pkg/present"
+	"github.com/jylitalo/mystats/pkg/season"
+	"github.com/jylitalo/mystats/pkg/sport"
+	"github.com/jylitalo/mystats/pkg/stats"
+	"github.com/jylitalo/mystats/pkg/units"
+)
+
+// plotCmd outputs cumulative sums of each year like server's plot page
+func plotCmd(types []string, groups []sport.Group, seasons []season.Season) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "plot",
+		Short: "Cumulative sums compared across years",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			flags := cmd.Flags()
+			measurement, _ := flags.GetString("measure")
+			types, _ := flags.GetStringSlice("type")
+			update, _ := flags.GetBool("update")
+			month, _ := flags.GetInt("month")
+			day, _ := flags.GetInt("day")
+			seasonName, _ := flags.GetString("season")
+			step, _ := flags.GetInt("step")
+			years, _ := flags.GetIntSlice("year")
+			filters, err := dateRange(flags)
+			if err != nil {
+				return err
+			}
+			opts, err := outputOptions(flags, true)
+			if err != nil {
+				return err
+			}
+			if step < 1 {
+				return fmt.Errorf("step must be at least one day (not %d)", step)
+			}
+			m, err := stats.MeasureByName(measurement)
+			if err != nil {
+				return err
+			}
+			s, seasonOpts, err := seasonFilter(seasons, types, seasonName)
+			if err != nil {
+				return err
+			}
+			filters = append(filters, seasonOpts...)
+			types, sportOpts := sportFilters(groups, types)
+			filters = append(filters, sportOpts...)
+			ctx := cmd.Context()
+			db, err := makeDB(ctx, update)
+			if err != nil {
+				return err
+			}
+			defer func() { _ = db.Close() }()
+			values, err := stats.Cumulative(ctx, db, s, m.Name, types, nil, month, day, years, filters...)
+			if err != nil {
+				return err
+			}
+			system := units.FromContext(ctx).System
+			foundYears := []int{}
+			for year, days := range values {
+				for idx := range days {
+					days[idx] = present.Convert(m, system, days[idx])
+				}
+				foundYears = append(foundYears, year)
+			}
+			slices.Sort(foundYears)
+			labels := s.Labels(foundYears)
+			first := plotStart(s)
+			if opts.Format == chartFormat {
+				return plotChart(
+					cmd.OutOrStdout(), chart.Detect(os.Stdout), present.Header(m, system), present.Unit(m, system),
+					labels, foundYears, values, first,
+				)
+			}
+			headers, results := dayTable(labels, foundYears, values, step, func(day int) string {
+				return first.AddDate(0, 0, day).Format("Jan 02")
+			})
+			return output.Write(cmd.OutOrStdout(), output.Table{Headers: headers, Rows: results}, opts)
+		},
+	}
+	cmd.Flags().String("measure", "distance", fmt.Sprintf("measurement type %v", stats.CumulativeMeasures()))
+	cmd.Flags().StringSlice("type", types, "sport types or groups (Run, TrailRun, running, ...)")
+	cmd.Flags().Bool("update", true, "update database")
+	cmd.Flags().Int("month", 12, "only search number of months")
+	cmd.Flags().Int("day", 31, "only search number of days from last --month")
+	cmd.Flags().String("season", "", "season from config (default: season shared by --type or calendar year)")
+	cmd.Flags().Int("step", 7, "days between output rows in table formats")
+	cmd.Flags().IntSlice("year", nil, "years to compare (default all)")
+	addDateRangeFlags(cmd)
+	addOutputFlags(cmd, chartFormat, true)
+	return cmd
+}
+
+// plotStart is first day of season in a season year that has 29th of February, so that every day has a name
+func plotStart(s season.Season) time.Time {
+	if month, _, _ := s.MonthDay(); month > 2 {
+		return s.First(2023, time.UTC)
+	}
+	return s.First(2024, time.UTC)
+}
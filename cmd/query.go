@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/spf13/cobra"
+
+	"github.com/jylitalo/mystats/pkg/output"
+	"github.com/jylitalo/mystats/pkg/query"
+	"github.com/jylitalo/mystats/pkg/units"
+)
+
+// queryCmd runs ad-hoc query written in filter language
+func queryCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "query [expression]",
+		Short: "Ad-hoc query, e.g. type in (Run, TrailRun) and distance > 20km group by month agg sum(elevation)",
+		Long: fmt.Sprintf(`Ad-hoc query over activities.
+
+  [condition [and condition ...]] [group by field, ...] [agg function(field), ...]
+  [order by field|function(field) [asc|desc]] [limit n]
+
+Conditions are field = != < <= > >= value, field ~ pattern (SQL like), field [not] in (value, ...).
+Fields are %v (time is elapsed_time).
+Functions are sum, avg, min, max and count. Group by without agg counts activities.
+Distances accept km, mi, m and ft, times h, min, s or h:mm:ss and dates YYYY-MM-DD.
+Numbers without unit are in --units (km or mi, m or ft, hours).`, query.Fields()),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			flags := cmd.Flags()
+			update, _ := flags.GetBool("update")
+			opts, err := outputOptions(flags, false)
+			if err != nil {
+				return err
+			}
+			ctx := cmd.Context()
+			prefs := units.FromContext(ctx)
+			q, err := query.Parse(strings.Join(args, " "), prefs.System)
+			if err != nil {
+				return err
+			}
+			db, err := makeDB(ctx, update)
+			if err != nil {
+				return err
+			}
+			defer func() { _ = db.Close() }()
+			table, err := query.Table(ctx, db, q, prefs)
+			if err != nil {
+				return err
+			}
+			return output.Write(cmd.OutOrStdout(), table, opts)
+		},
+	}
+	cmd.Flags().Bool("update", true, "update database")
+	addOutputFlags(cmd, "table", false)
+	return cmd
+}
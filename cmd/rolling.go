@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/spf13/cobra"
+
+	"github.com/jylitalo/mystats/pkg/output"
+	"github.com/jylitalo/mystats/pkg/present"
+	"github.com/jylitalo/mystats/pkg/sport"
+	"github.com/jylitalo/mystats/pkg/stats"
+	"github.com/jylitalo/mystats/pkg/units"
+)
+
+// rollingCmd outputs rolling window sums or averages for each day of year
+func rollingCmd(types []string, groups []sport.Group) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "rolling",
+		Short: "Rolling window sums and averages compared across years",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			flags := cmd.Flags()
+			average, _ := flags.GetBool("average")
+			measure, _ := flags.GetString("measure")
+			step, _ := flags.GetInt("step")
+			types, _ := flags.GetStringSlice("type")
+			update, _ := flags.GetBool("update")
+			window, _ := flags.GetInt("window")
+			years, _ := flags.GetIntSlice("year")
+			types, filters := sportFilters(groups, types)
+			opts, err := outputOptions(flags, false)
+			if err != nil {
+				return err
+			}
+			if step < 1 {
+				return fmt.Errorf("step must be at least one day (not %d)", step)
+			}
+			ctx := cmd.Context()
+			db, err := makeDB(ctx, update)
+			if err != nil {
+				return err
+			}
+			defer func() { _ = db.Close() }()
+			m, _, err := stats.RollingMeasure(measure)
+			if err != nil {
+				return err
+			}
+			foundYears, values, err := stats.Rolling(ctx, db, measure, window, average, types, nil, years, filters...)
+			if err != nil {
+				return err
+			}
+			system := units.FromContext(ctx).System
+			for _, yearValues := range values {
+				for idx := range yearValues {
+					yearValues[idx] = present.Convert(m, system, yearValues[idx])
+				}
+			}
+			labels := make([]string, len(foundYears))
+			for idx, year := range foundYears {
+				labels[idx] = strconv.Itoa(year)
+			}
+			// index is day of year in every year, so dates would be off by one after February in leap years
+			headers, results := dayTable(labels, foundYears, values, step, func(day int) string {
+				return strconv.Itoa(day + 1)
+			})
+			return output.Write(cmd.OutOrStdout(), output.Table{Headers: headers, Rows: results}, opts)
+		},
+	}
+	cmd.Flags().Bool("average", false, "average instead of sum (averages and ratios are always averaged)")
+	cmd.Flags().String("measure", "distance", fmt.Sprintf("measurement type %v", stats.RollingMeasures()))
+	cmd.Flags().Int("step", 7, "days between output rows")
+	cmd.Flags().StringSlice("type", types, "sport types or groups (Run, TrailRun, running, ...)")
+	cmd.Flags().Bool("update", true, "update database")
+	cmd.Flags().Int("window", 28, fmt.Sprintf("window length in days (e.g. %v)", stats.RollingWindows()))
+	cmd.Flags().IntSlice("year", nil, "years to compare (default all)")
+	addOutputFlags(cmd, "csv", false)
+	return cmd
+}
+
+// dayTable has row for every step day and column for every year. Rows are named with dayLabel.
+func dayTable(
+	labels []string, years []int, values map[int][]float64, step int, dayLabel func(day int) string,
+) ([]string, [][]string) {
+	headers := append([]string{"day"}, labels...)
+	days := 0
+	for _, year := range years {
+		days = max(days, len(values[year]))
+	}
+	results := [][]string{}
+	for day := step - 1; day < days; day += step {
+		row := []string{dayLabel(day)}
+		for _, year := range years {
+			value := ""
+			if day < len(values[year]) {
+				value = fmt.Sprintf("%.1f", values[year][day])
+			}
+			row = append(row, value)
+		}
+		results = append(results, row)
+	}
+	return headers, results
+}
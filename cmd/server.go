@@ -1,33 +1,139 @@
 package cmd
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"log/slog"
+	"net"
+	"os"
 
 	_ "github.com/mattn/go-sqlite3"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 
+	"github.com/jylitalo/mystats/api/strava"
+	"github.com/jylitalo/mystats/pkg/season"
+	"github.com/jylitalo/mystats/pkg/sport"
 	"github.com/jylitalo/mystats/server"
+	"github.com/jylitalo/mystats/storage"
 )
 
 // serverCmd turns sqlite db into table or csv by week/month/...
-func serverCmd(types []string) *cobra.Command {
+func serverCmd(types []string, groups []sport.Group, seasons []season.Season) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "server",
 		Short: "Start web service",
-		RunE: func(cmd *cobra.Command, args []string) error {
+		RunE: func(cmd *cobra.Command, args []string) (err error) {
 			flags := cmd.Flags()
 			port, _ := flags.GetInt("port")
 			update, _ := flags.GetBool("update")
+			assetsDir, _ := flags.GetString("assets-dir")
+			interval, _ := flags.GetDuration("sync-interval")
+			opts, err := listenOptions(flags)
+			if err != nil {
+				return err
+			}
 			db, err := makeDB(cmd.Context(), update)
 			if err != nil {
 				return err
 			}
-			defer func() { _ = db.Close() }()
-			slog.Info("start service", "port", port)
-			return server.Start(cmd.Context(), db, types, port)
+			// server has stopped requests and sync before database is closed
+			defer func() { err = errors.Join(err, db.Close()) }()
+			if interval > 0 {
+				sq, ok := db.(*storage.Sqlite3)
+				if !ok {
+					return errors.New("background sync requires sqlite3 database")
+				}
+				opts = append(opts, server.WithSync(syncDB(sq), interval))
+			}
+			slog.Info("start service", "port", port, "sync-interval", interval)
+			return server.Start(cmd.Context(), db, types, groups, seasons, port, assetsDir, opts...)
 		},
 	}
+	cmd.Flags().String("address", "127.0.0.1", "Address to listen (e.g. 0.0.0.0 for LAN)")
 	cmd.Flags().Int("port", 8000, "Port number for service")
+	cmd.Flags().String("tls-cert", "", "Certificate file (PEM) for https")
+	cmd.Flags().String("tls-key", "", "Private key file (PEM) of certificate")
+	cmd.Flags().String("users", "", "Users file with name:bcrypt hash lines (see passwd command)")
+	cmd.Flags().String("oidc-issuer", "", "OpenID Connect provider for login (e.g. https://accounts.google.com)")
+	cmd.Flags().String("oidc-client-id", "", "Client ID at OpenID Connect provider. Secret is read from "+oidcSecretEnv)
+	cmd.Flags().StringSlice("oidc-allow", nil, "Subjects, emails or @domains that may log in with OpenID Connect")
 	cmd.Flags().Bool("update", true, "Update database")
+	cmd.Flags().Duration("sync-interval", 0, "Fetch new activities on interval (e.g. 30m), 0 disables")
+	cmd.Flags().String("assets-dir", "", "Directory with views, css and js (e.g. server) instead of embedded files")
 	return cmd
 }
+
+// oidcSecretEnv keeps client secret out of process list
+const oidcSecretEnv = "MYSTATS_OIDC_CLIENT_SECRET"
+
+// listenOptions reads address, TLS and login flags. Outside of loopback address login needs https,
+// since browsers keep secure session cookie only from https and localhost.
+func listenOptions(flags *pflag.FlagSet) ([]server.StartOption, error) {
+	address, _ := flags.GetString("address")
+	certFile, _ := flags.GetString("tls-cert")
+	keyFile, _ := flags.GetString("tls-key")
+	usersFile, _ := flags.GetString("users")
+	issuer, _ := flags.GetString("oidc-issuer")
+	clientID, _ := flags.GetString("oidc-client-id")
+	allow, _ := flags.GetStringSlice("oidc-allow")
+	opts := []server.StartOption{server.WithAddress(address)}
+	if (certFile == "") != (keyFile == "") {
+		return nil, errors.New("--tls-cert and --tls-key must be given together")
+	}
+	if certFile != "" {
+		opts = append(opts, server.WithTLS(certFile, keyFile))
+	}
+	if usersFile != "" {
+		f, err := os.Open(usersFile) //nolint:gosec // file is given by user
+		if err != nil {
+			return nil, err
+		}
+		defer func() { _ = f.Close() }()
+		users, err := server.ReadUsers(f)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", usersFile, err)
+		}
+		opts = append(opts, server.WithUsers(users))
+	}
+	if (issuer == "") != (clientID == "") {
+		return nil, errors.New("--oidc-issuer and --oidc-client-id must be given together")
+	}
+	if issuer != "" && len(allow) == 0 {
+		return nil, errors.New("--oidc-issuer needs --oidc-allow, since provider may let anyone log in")
+	}
+	if issuer != "" {
+		opts = append(opts, server.WithOIDC(server.OIDCConfig{
+			Issuer: issuer, ClientID: clientID, ClientSecret: os.Getenv(oidcSecretEnv), Allow: allow,
+		}))
+	}
+	ip := net.ParseIP(address)
+	loopback := address == "localhost" || (ip != nil && ip.IsLoopback())
+	switch {
+	case loopback: // browsers accept secure cookies from http://localhost
+	case usersFile == "" && issuer == "":
+		slog.Warn("server without login is open to everyone in network", "address", address)
+	case certFile == "":
+		return nil, errors.New("login outside of localhost needs --tls-cert and --tls-key")
+	}
+	return opts, nil
+}
+
+// syncDB fetches new activities and adds them into open database.
+// What was fetched before Strava's rate limit is still added.
+func syncDB(db *storage.Sqlite3) server.SyncFn {
+	return func(ctx context.Context) error {
+		errFetch := fetchAPIs(ctx, true, false)
+		if errFetch != nil && !strava.IsRateLimitExceeded(errFetch) {
+			return errFetch
+		}
+		if err := updateDB(ctx, db); err != nil {
+			return err
+		}
+		if errFetch != nil {
+			return fmt.Errorf("%w: %w", server.ErrRateLimited, errFetch)
+		}
+		return nil
+	}
+}
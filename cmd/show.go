@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/spf13/cobra"
+
+	"github.com/jylitalo/mystats/pkg/output"
+	"github.com/jylitalo/mystats/pkg/present"
+	"github.com/jylitalo/mystats/pkg/stats"
+	"github.com/jylitalo/mystats/pkg/units"
+)
+
+// showCmd outputs summary and splits of single activity
+func showCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "show <id>",
+		Short: "Show activity with splits",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			flags := cmd.Flags()
+			update, _ := flags.GetBool("update")
+			id, err := strconv.ParseInt(args[0], 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid activity id: %s", args[0])
+			}
+			opts, err := outputOptions(flags, false)
+			if err != nil {
+				return err
+			}
+			ctx := cmd.Context()
+			db, err := makeDB(ctx, update)
+			if err != nil {
+				return err
+			}
+			defer func() { _ = db.Close() }()
+			details, err := stats.Details(ctx, db, id)
+			if err != nil {
+				return err
+			}
+			splits, err := stats.Split(ctx, db, id)
+			if err != nil {
+				return err
+			}
+			prefs := units.FromContext(ctx)
+			headers, results := present.Details(details, splits, prefs)
+			summary := output.Table{Caption: details.Name, Headers: headers, Rows: results}
+			headers, results = present.Splits(splits, prefs)
+			// --columns and --sort apply to splits
+			splitTable, err := output.Table{Headers: headers, Rows: results}.Select(opts)
+			if err != nil {
+				return err
+			}
+			return output.WriteAll(cmd.OutOrStdout(), opts.Format, summary, splitTable)
+		},
+	}
+	cmd.Flags().Bool("update", true, "update database")
+	addOutputFlags(cmd, "table", false)
+	return cmd
+}
@@ -3,99 +3,93 @@ package cmd
 import (
 	"fmt"
 	"os"
-	"strconv"
 	"strings"
-	"time"
 
 	_ "github.com/mattn/go-sqlite3"
-	"github.com/olekukonko/tablewriter"
 	"github.com/spf13/cobra"
 
+	"github.com/jylitalo/mystats/pkg/chart"
+	"github.com/jylitalo/mystats/pkg/output"
+	"github.com/jylitalo/mystats/pkg/present"
+	"github.com/jylitalo/mystats/pkg/season"
+	"github.com/jylitalo/mystats/pkg/sport"
 	"github.com/jylitalo/mystats/pkg/stats"
+	"github.com/jylitalo/mystats/pkg/units"
 )
 
-// printCSV outputs results in CSV format
-func printCSV(period, measurement string, years []int, results [][]string, totals []string) {
-	fmt.Printf("# %s stats:\n", measurement)
-	fmt.Printf("%-5s", period)
-	for _, year := range years {
-		fmt.Printf(",%d", year)
+// statsTable has row for every period with activities in season order and totals as footer
+func statsTable(
+	period, measurement string, s season.Season, years []int, results [][]string, totals []string,
+) output.Table {
+	t := output.Table{
+		Caption: measurement,
+		Headers: append([]string{period}, s.Labels(years)...),
+		Footer:  append([]string{"total"}, totals...),
 	}
-	fmt.Println()
-	for idx := range results {
-		if strings.TrimSpace(strings.Join(results[idx], "")) != "" {
-			fmt.Printf("%5d,%s\n", idx+1, strings.Join(results[idx], ","))
+	for _, p := range s.Order(period) {
+		if p <= len(results) && strings.TrimSpace(strings.Join(results[p-1], "")) != "" {
+			t.Rows = append(t.Rows, append([]string{present.Period(period, p)}, results[p-1]...))
 		}
 	}
-	fmt.Printf("TOTAL,%s\n", strings.Join(totals, ","))
-}
-
-// printTable outputs results in CSV format
-func printTable(period, measurement string, years []int, results [][]string, totals []string) {
-	first := func(i int) string {
-		return time.Month(i).String()
-	}
-	if period == "week" {
-		first = strconv.Itoa
-	}
-	table := tablewriter.NewWriter(os.Stdout)
-	table.SetFooterAlignment(tablewriter.ALIGN_RIGHT)
-	header := []string{period}
-	for _, year := range years {
-		header = append(header, strconv.Itoa(year))
-	}
-	table.SetCaption(true, measurement)
-	table.SetHeader(header)
-	for idx := range results {
-		if strings.TrimSpace(strings.Join(results[idx], "")) != "" {
-			table.Append(append([]string{first(idx + 1)}, results[idx]...))
-		}
-	}
-	table.SetFooter(append([]string{"total"}, totals...))
-	table.Render()
+	return t
 }
 
 // statsCmd turns sqlite db into table or csv by week/month/...
-func statsCmd(types []string) *cobra.Command {
+func statsCmd(types []string, groups []sport.Group, seasons []season.Season) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "stats",
 		Short: "Create year to year comparisons",
 		RunE: func(cmd *cobra.Command, args []string) error {
 			flags := cmd.Flags()
-			format, _ := flags.GetString("format")
 			measurement, _ := flags.GetString("measure")
 			period, _ := flags.GetString("period")
 			types, _ := flags.GetStringSlice("type")
 			update, _ := flags.GetBool("update")
 			month, _ := flags.GetInt("month")
 			day, _ := flags.GetInt("day")
-			formatFn := map[string]func(period string, measurement string, years []int, results [][]string, totals []string){
-				"csv":   printCSV,
-				"table": printTable,
+			seasonName, _ := flags.GetString("season")
+			filters, err := dateRange(flags)
+			if err != nil {
+				return err
 			}
-			if _, ok := formatFn[format]; !ok {
-				return fmt.Errorf("unknown format: %s", format)
+			opts, err := outputOptions(flags, true)
+			if err != nil {
+				return err
 			}
+			s, seasonOpts, err := seasonFilter(seasons, types, seasonName)
+			if err != nil {
+				return err
+			}
+			filters = append(filters, seasonOpts...)
+			types, sportOpts := sportFilters(groups, types)
+			filters = append(filters, sportOpts...)
 			ctx := cmd.Context()
 			db, err := makeDB(ctx, update)
 			if err != nil {
 				return err
 			}
 			defer func() { _ = db.Close() }()
-			years, results, totals, err := stats.Stats(ctx, db, measurement, period, types, nil, month, day, nil)
+			result, err := stats.Stats(ctx, db, measurement, period, types, nil, month, day, nil, filters...)
 			if err != nil {
 				return err
 			}
-			formatFn[format](period, measurement, years, results, totals)
-			return nil
+			prefs := units.FromContext(ctx)
+			if opts.Format == chartFormat {
+				return statsChart(cmd.OutOrStdout(), chart.Detect(os.Stdout), result, s, prefs)
+			}
+			results, totals := present.Stats(result, prefs)
+			t := statsTable(period, measurement, s, result.Years, results, totals)
+			return output.Write(cmd.OutOrStdout(), t, opts)
 		},
 	}
-	cmd.Flags().String("format", "csv", "output format (csv, table)")
-	cmd.Flags().String("measure", "sum(distance)", "measurement type (sum(distance), max(elevation), ...)")
+	cmd.Flags().String("measure", "distance", fmt.Sprintf("measurement type %v", stats.Measures()))
 	cmd.Flags().String("period", "week", "time period (week, month)")
-	cmd.Flags().StringSlice("type", types, "sport types (run, trail run, ...)")
+	cmd.Flags().StringSlice("type", types, "sport types or groups (Run, TrailRun, running, ...)")
 	cmd.Flags().Bool("update", true, "update database")
 	cmd.Flags().Int("month", 12, "only search number of months")
 	cmd.Flags().Int("day", 31, "only search number of days from last --month")
+	cmd.Flags().String("season", "", "season from config (default: season shared by --type or calendar year)")
+	addDateRangeFlags(cmd)
+	addOutputFlags(cmd, "csv", true)
 	return cmd
 }
@@ -2,46 +2,39 @@ package cmd
 
 import (
 	"fmt"
-	"os"
-	"strings"
 
 	_ "github.com/mattn/go-sqlite3"
-	"github.com/olekukonko/tablewriter"
 	"github.com/spf13/cobra"
 
+	"github.com/jylitalo/mystats/pkg/output"
+	"github.com/jylitalo/mystats/pkg/present"
+	"github.com/jylitalo/mystats/pkg/sport"
 	"github.com/jylitalo/mystats/pkg/stats"
+	"github.com/jylitalo/mystats/pkg/units"
 )
 
-// printTopCSV outputs results in CSV format
-func printTopCSV(headers []string, results [][]string) {
-	fmt.Print(strings.Join(headers, ","))
-	fmt.Println()
-	for idx := range results {
-		fmt.Println(strings.Join(results[idx], ","))
-	}
-}
-
-// printTopTable outputs results in CSV format
-func printTopTable(headers []string, results [][]string) {
-	table := tablewriter.NewWriter(os.Stdout)
-	table.SetHeader(headers)
-	table.AppendBulk(results)
-	table.Render()
-}
-
 // topCmd turns sqlite db into table or csv by week/month/...
-func topCmd(types []string) *cobra.Command {
+func topCmd(types []string, groups []sport.Group) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "top",
 		Short: "Create top list",
 		RunE: func(cmd *cobra.Command, args []string) error {
 			flags := cmd.Flags()
-			format, _ := flags.GetString("format")
 			limit, _ := flags.GetInt("limit")
 			measurement, _ := flags.GetString("measure")
 			period, _ := flags.GetString("period")
 			types, _ := flags.GetStringSlice("type")
 			update, _ := flags.GetBool("update")
+			filters, err := dateRange(flags)
+			if err != nil {
+				return err
+			}
+			opts, err := outputOptions(flags, false)
+			if err != nil {
+				return err
+			}
+			types, sportOpts := sportFilters(groups, types)
+			filters = append(filters, sportOpts...)
 			inYear := map[string]int{
 				"month": 12,
 				"week":  53,
@@ -49,32 +42,26 @@ func topCmd(types []string) *cobra.Command {
 			if _, ok := inYear[period]; !ok {
 				return fmt.Errorf("unknown period: %s", period)
 			}
-			formatFn := map[string]func(headers []string, results [][]string){
-				"csv":   printTopCSV,
-				"table": printTopTable,
-			}
-			if _, ok := formatFn[format]; !ok {
-				return fmt.Errorf("unknown format: %s", format)
-			}
 			ctx := cmd.Context()
 			db, err := makeDB(ctx, update)
 			if err != nil {
 				return err
 			}
 			defer func() { _ = db.Close() }()
-			headers, results, err := stats.Top(ctx, db, measurement, period, types, nil, limit, nil)
+			result, err := stats.Top(ctx, db, measurement, period, types, nil, limit, nil, filters...)
 			if err != nil {
 				return err
 			}
-			formatFn[format](headers, results)
-			return nil
+			headers, results := present.Top(result, units.FromContext(ctx))
+			return output.Write(cmd.OutOrStdout(), output.Table{Headers: headers, Rows: results}, opts)
 		},
 	}
-	cmd.Flags().String("format", "csv", "output format (csv, table)")
 	cmd.Flags().Int("limit", 10, "number of entries")
-	cmd.Flags().String("measure", "distance", "measurement type (distance, elevation, time)")
+	cmd.Flags().String("measure", "distance", fmt.Sprintf("measurement type %v", stats.Measures()))
 	cmd.Flags().String("period", "week", "time period (week, month)")
-	cmd.Flags().StringSlice("type", types, "sport types (run, trail run, ...)")
+	cmd.Flags().StringSlice("type", types, "sport types or groups (Run, TrailRun, running, ...)")
 	cmd.Flags().Bool("update", true, "update database")
+	addDateRangeFlags(cmd)
+	addOutputFlags(cmd, "csv", false)
 	return cmd
 }
@@ -0,0 +1,32 @@
+package cmd
+
+import (
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/spf13/cobra"
+
+	"github.com/jylitalo/mystats/pkg/season"
+	"github.com/jylitalo/mystats/pkg/sport"
+	"github.com/jylitalo/mystats/tui"
+)
+
+// tuiCmd starts interactive terminal dashboard with same pages as server
+func tuiCmd(types []string, groups []sport.Group, seasons []season.Season) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "tui",
+		Short: "Interactive terminal dashboard",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			flags := cmd.Flags()
+			types, _ := flags.GetStringSlice("type")
+			update, _ := flags.GetBool("update")
+			db, err := makeDB(cmd.Context(), update)
+			if err != nil {
+				return err
+			}
+			defer func() { _ = db.Close() }()
+			return tui.Run(cmd.Context(), db, types, groups, seasons)
+		},
+	}
+	cmd.Flags().StringSlice("type", types, "sport types or groups checked in filter (Run, TrailRun, running, ...)")
+	cmd.Flags().Bool("update", true, "update database")
+	return cmd
+}
@@ -13,6 +13,8 @@ import (
 	"github.com/jylitalo/mystats/api/garmin"
 	"github.com/jylitalo/mystats/api/strava"
 	"github.com/jylitalo/mystats/pkg/data"
+	"github.com/jylitalo/mystats/pkg/season"
+	"github.com/jylitalo/mystats/pkg/sport"
 )
 
 type Config struct {
@@ -21,6 +23,17 @@ type Config struct {
 	Default struct {
 		Types []string `yaml:"types"`
 	} `yaml:"default"`
+	// Efforts are computed from splits and streams in addition to Strava's best efforts
+	Efforts struct {
+		Distances []int `yaml:"distances"` // meters
+		Durations []int `yaml:"durations"` // seconds
+	} `yaml:"efforts"`
+	// Groups combine activity types and sport types, e.g. running = Run + TrailRun + VirtualRun
+	Groups  []sport.Group   `yaml:"groups"`
+	Seasons []season.Season `yaml:"seasons"`
+	// Units is metric or imperial and Locale picks date format (fi, en-GB, en-US or iso)
+	Units  string `yaml:"units,omitempty"`
+	Locale string `yaml:"locale,omitempty"`
 }
 
 type configCtxKey string
@@ -63,6 +76,7 @@ func Read(ctx context.Context, refresh bool) (context.Context, error) {
 	}
 	cfg.Garmin.DailySteps = data.Coalesce(cfg.Garmin.DailySteps, "daily_steps")
 	cfg.Strava.Activities = data.Coalesce(cfg.Strava.Activities, "activities")
+	cfg.Strava.Streams = data.Coalesce(cfg.Strava.Streams, "streams")
 	cfg.Strava.Summaries = data.Coalesce(cfg.Strava.Summaries, "pages")
 	ctx = context.WithValue(ctx, configKey, &cfg)
 	if !refresh {
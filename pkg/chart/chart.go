@@ -0,0 +1,282 @@
+// Package chart draws bar and line charts and calendar heatmaps into terminal with Unicode block characters
+// and ANSI colours. The same charts can also be drawn as SVG.
+package chart
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Series is named list of values. NaN marks missing value.
+type Series struct {
+	Name   string
+	Values []float64
+}
+
+// colors follow the order of colors in server's plot page (blue, green, red, ...)
+var colors = []int{34, 32, 31, 36, 33, 35, 94, 92, 91, 96, 93}
+
+// markers separate series, when colours aren't available
+var markers = []rune("●○×+◆◇■□▲△*")
+
+// blocks are partial bars in eighths
+var blocks = []rune(" ▏▎▍▌▋▊▉█")
+
+func (t Terminal) paint(idx int, text string) string {
+	if !t.Color {
+		return text
+	}
+	return fmt.Sprintf("\x1b[%dm%s\x1b[0m", colors[idx%len(colors)], text)
+}
+
+func (t Terminal) marker(idx int) string {
+	if t.Color {
+		return t.paint(idx, "●")
+	}
+	return string(markers[idx%len(markers)])
+}
+
+func pad(text string, width int) string {
+	return text + strings.Repeat(" ", max(0, width-utf8.RuneCountInString(text)))
+}
+
+// bounds returns smallest and largest value. Zero is always included, so that bars and lines start from it.
+func bounds(series []Series) (float64, float64) {
+	lowest, highest := 0.0, 0.0
+	for _, s := range series {
+		for _, value := range s.Values {
+			if !math.IsNaN(value) {
+				lowest, highest = min(lowest, value), max(highest, value)
+			}
+		}
+	}
+	return lowest, highest
+}
+
+// bar draws value as blocks. Length is in characters.
+func bar(value, highest float64, length int) string {
+	if highest <= 0 || value <= 0 {
+		return ""
+	}
+	eighths := int(math.Round(value / highest * float64(length) * 8))
+	return strings.Repeat(string(blocks[8]), eighths/8) + strings.TrimSpace(string(blocks[eighths%8]))
+}
+
+// Bars draws horizontal bar of every series under each label, e.g. one bar per year under each month.
+// Missing values are skipped.
+func Bars(
+	w io.Writer, t Terminal, title string, labels []string, series []Series, format func(float64) string,
+) error {
+	nameWidth, textWidth := 0, 0
+	for _, s := range series {
+		nameWidth = max(nameWidth, utf8.RuneCountInString(s.Name))
+		for _, value := range s.Values {
+			if !math.IsNaN(value) {
+				textWidth = max(textWidth, utf8.RuneCountInString(format(value)))
+			}
+		}
+	}
+	length := max(10, t.Width-nameWidth-textWidth-4)
+	_, highest := bounds(series)
+	var sb strings.Builder
+	if title != "" {
+		sb.WriteString(title + "\n")
+	}
+	for idx, label := range labels {
+		sb.WriteString(label + "\n")
+		for sIdx, s := range series {
+			if idx >= len(s.Values) || math.IsNaN(s.Values[idx]) {
+				continue
+			}
+			value := s.Values[idx]
+			fmt.Fprintf(&sb, "  %s %s %s\n",
+				pad(s.Name, nameWidth), t.paint(sIdx, bar(value, highest, length)), format(value))
+		}
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// Lines draws series as lines, e.g. cumulative distance of each year. Values are spread over terminal width,
+// so index of value is position on x axis. xLabel names index for x axis and format names value for y axis.
+func Lines(
+	w io.Writer, t Terminal, title string, series []Series, height int,
+	xLabel func(int) string, format func(float64) string,
+) error {
+	count := 0
+	for _, s := range series {
+		count = max(count, len(s.Values))
+	}
+	if count == 0 || height < 2 {
+		return nil
+	}
+	lowest, highest := bounds(series)
+	if highest == lowest {
+		highest = lowest + 1
+	}
+	yLabels := map[int]string{
+		0: format(highest), (height - 1) / 2: format((highest + lowest) / 2), height - 1: format(lowest),
+	}
+	labelWidth := 0
+	for _, label := range yLabels {
+		labelWidth = max(labelWidth, utf8.RuneCountInString(label))
+	}
+	width := min(count, max(10, t.Width-labelWidth-2))
+	index := func(column int) int {
+		if width == 1 {
+			return 0
+		}
+		return column * (count - 1) / (width - 1)
+	}
+	// grid has series index (+1) for each cell, zero means empty
+	grid := make([][]int, height)
+	for row := range grid {
+		grid[row] = make([]int, width)
+	}
+	for sIdx, s := range series {
+		previous := -1
+		for column := range width {
+			idx := index(column)
+			if idx >= len(s.Values) || math.IsNaN(s.Values[idx]) {
+				previous = -1
+				continue
+			}
+			row := int(math.Round((s.Values[idx] - lowest) / (highest - lowest) * float64(height-1)))
+			from, to := row, row
+			if previous >= 0 { // fill vertical gap, so that steep parts stay connected
+				from, to = min(row, previous+1), max(row, previous-1)
+			}
+			for r := from; r <= to; r++ {
+				grid[height-1-r][column] = sIdx + 1
+			}
+			previous = row
+		}
+	}
+	var sb strings.Builder
+	if title != "" {
+		sb.WriteString(title + "\n")
+	}
+	for row, cells := range grid {
+		label, axis := yLabels[row], "│"
+		if label != "" {
+			axis = "┤"
+		}
+		sb.WriteString(strings.Repeat(" ", labelWidth-utf8.RuneCountInString(label)) + label + " " + axis)
+		for _, cell := range cells {
+			if cell == 0 {
+				sb.WriteRune(' ')
+				continue
+			}
+			sb.WriteString(t.marker(cell - 1))
+		}
+		sb.WriteString("\n")
+	}
+	sb.WriteString(strings.Repeat(" ", labelWidth+1) + "└" + strings.Repeat("─", width) + "\n")
+	sb.WriteString(strings.Repeat(" ", labelWidth+2) + xAxis(width, count, index, xLabel) + "\n")
+	legend := make([]string, len(series))
+	for sIdx, s := range series {
+		legend[sIdx] = t.marker(sIdx) + " " + s.Name
+	}
+	sb.WriteString(strings.Repeat(" ", labelWidth+2) + strings.Join(legend, "  ") + "\n")
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// xAxis places labels at the start, quarters and end of axis, skipping labels that would overlap
+func xAxis(width, count int, index func(int) int, xLabel func(int) string) string {
+	line := []rune(strings.Repeat(" ", width))
+	next := 0
+	for _, column := range []int{0, width / 4, width / 2, width * 3 / 4, width - 1} {
+		label := []rune(xLabel(min(index(column), count-1)))
+		start := min(column, width-len(label))
+		if start < next || start < 0 {
+			continue
+		}
+		copy(line[start:], label)
+		next = start + len(label) + 1
+	}
+	return strings.TrimRight(string(line), " ")
+}
+
+// heatCells are calendar levels from the lowest to the highest value, when colours aren't available
+var heatCells = []string{" ·", "░░", "▒▒", "▓▓", "██"}
+
+// heatCodes are 256 colour codes closest to heatColors of SVG calendar
+var heatCodes = []int{240, 151, 77, 71, 22}
+
+func (t Terminal) heat(level int) string {
+	if !t.Color {
+		return heatCells[level]
+	}
+	if level == 0 {
+		return fmt.Sprintf("\x1b[38;5;%dm ·\x1b[0m", heatCodes[level])
+	}
+	return fmt.Sprintf("\x1b[38;5;%dm██\x1b[0m", heatCodes[level])
+}
+
+// Calendar draws daily values of one year as heatmap with a row for every week (Monday first).
+// First value is January 1st. Week totals are at the end of rows and month totals on rows where months start.
+// Notes and links of extras aren't used in terminal.
+func Calendar(
+	w io.Writer, t Terminal, title string, year int, values []float64, format func(float64) string,
+	extras CalendarExtras,
+) error {
+	first := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+	days := first.AddDate(1, 0, -1).YearDay()
+	offset := (int(first.Weekday()) + 6) % 7 // Monday is zero
+	weeks := (offset + days + 6) / 7
+	highest := 0.0
+	for _, value := range values {
+		if !math.IsNaN(value) {
+			highest = max(highest, value)
+		}
+	}
+	totalWidth := 0
+	for _, value := range extras.Weeks {
+		if !math.IsNaN(value) {
+			totalWidth = max(totalWidth, utf8.RuneCountInString(format(value)))
+		}
+	}
+	var sb strings.Builder
+	if title != "" {
+		sb.WriteString(title + "\n")
+	}
+	sb.WriteString("        MoTuWeThFrSaSu\n")
+	for week := range weeks {
+		monday := first.AddDate(0, 0, week*7-offset)
+		sb.WriteString(monday.Format("Jan 02") + " │")
+		month := -1
+		for weekday := range 7 {
+			day := week*7 + weekday - offset
+			if day < 0 || day >= days {
+				sb.WriteString("  ")
+				continue
+			}
+			if date := first.AddDate(0, 0, day); date.Day() == 1 {
+				month = int(date.Month()) - 1
+			}
+			value := math.NaN()
+			if day < len(values) {
+				value = values[day]
+			}
+			sb.WriteString(t.heat(heatLevel(value, highest)))
+		}
+		sb.WriteString("│")
+		if week < len(extras.Weeks) && !math.IsNaN(extras.Weeks[week]) {
+			total := format(extras.Weeks[week])
+			sb.WriteString(" " + strings.Repeat(" ", totalWidth-utf8.RuneCountInString(total)) + total)
+		} else if month >= 0 && month < len(extras.Months) {
+			sb.WriteString(" " + strings.Repeat(" ", totalWidth))
+		}
+		if month >= 0 && month < len(extras.Months) && !math.IsNaN(extras.Months[month]) {
+			fmt.Fprintf(&sb, "   %s %s", time.Month(month+1).String()[:3], format(extras.Months[month]))
+		}
+		sb.WriteString("\n")
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
@@ -0,0 +1,127 @@
+package chart //nolint:testpackage
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestBars(t *testing.T) {
+	series := []Series{
+		{Name: "2023", Values: []float64{10, math.NaN()}},
+		{Name: "2024", Values: []float64{5, 2.5}},
+	}
+	format := func(value float64) string { return fmt.Sprintf("%.0f", value) }
+	var b bytes.Buffer
+	// bars have 30 - 4 - 2 - 4 = 20 characters at most
+	if err := Bars(&b, Terminal{Width: 30}, "km", []string{"Jan", "Feb"}, series, format); err != nil {
+		t.Fatal(err)
+	}
+	expected := "km\nJan\n  2023 " + strings.Repeat("█", 20) + " 10\n  2024 " + strings.Repeat("█", 10) + " 5\n" +
+		"Feb\n  2024 █████ 2\n"
+	if b.String() != expected {
+		t.Errorf("bars mismatch:\n%s\nvs.\n%s", b.String(), expected)
+	}
+}
+
+func TestLines(t *testing.T) {
+	series := []Series{{Name: "2024", Values: []float64{0, 1, 2, 3}}}
+	format := func(value float64) string { return fmt.Sprintf("%.0f", value) }
+	xLabel := func(idx int) string { return fmt.Sprintf("d%d", idx) }
+	var b bytes.Buffer
+	if err := Lines(&b, Terminal{Width: 80}, "", series, 4, xLabel, format); err != nil {
+		t.Fatal(err)
+	}
+	// middle label is rounded 1.5 and there is room only for the first x label
+	expected := "3 ┤   ●\n2 ┤  ● \n  │ ●  \n0 ┤●   \n  └────\n   d0\n   ● 2024\n"
+	if b.String() != expected {
+		t.Errorf("lines mismatch:\n%s\nvs.\n%s", b.String(), expected)
+	}
+}
+
+func TestLinesSVG(t *testing.T) {
+	series := []Series{{Name: "2024", Values: []float64{0, 1, math.NaN(), 3}}}
+	format := func(value float64) string { return fmt.Sprintf("%.0f", value) }
+	xLabel := func(idx int) string { return fmt.Sprintf("d%d", idx) }
+	var b bytes.Buffer
+	if err := LinesSVG(&b, Size{Width: 190, Height: 180}, "km", series, xLabel, format); err != nil {
+		t.Fatal(err)
+	}
+	// plot area is 100x100 pixels and missing value starts new line
+	if !strings.Contains(b.String(), `d="M70.0 130.0 L103.3 96.7 M170.0 30.0"`) {
+		t.Errorf("path mismatch:\n%s", b.String())
+	}
+}
+
+func TestCalendarSVG(t *testing.T) {
+	format := func(value float64) string { return fmt.Sprintf("%.0f", value) }
+	var b bytes.Buffer
+	if err := CalendarSVG(&b, "", 2024, []float64{0, 5, math.NaN(), 10}, format, CalendarExtras{}); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		text  string
+		count int
+	}{
+		{"<rect ", 366},
+		{"<title>2024-01-02: 5</title>", 1},
+		{"<title>2024-01-03</title>", 1},
+		{heatColors[len(heatColors)-1], 1},
+		{heatColors[2], 1},
+	}
+	for _, test := range tests {
+		if count := strings.Count(b.String(), test.text); count != test.count {
+			t.Errorf("%s found %d times, expected %d", test.text, count, test.count)
+		}
+	}
+}
+
+func TestRouteSVG(t *testing.T) {
+	tests := []struct {
+		route [][2]float64
+		text  string
+	}{
+		{nil, ">No route</text>"},
+		// north is up: start in south is drawn at the bottom
+		{[][2]float64{{60, 24}, {60.01, 24}}, `<polyline points="250.0,490.0 250.0,10.0"`},
+	}
+	for _, test := range tests {
+		var b bytes.Buffer
+		if err := RouteSVG(&b, Size{Width: 500, Height: 500}, test.route); err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(b.String(), test.text) {
+			t.Errorf("%s not found in %s", test.text, b.String())
+		}
+	}
+}
+
+func TestCalendar(t *testing.T) {
+	format := func(value float64) string { return fmt.Sprintf("%.0f", value) }
+	values := make([]float64, 366)
+	values[0], values[31] = 10, 5
+	weeks, months := make([]float64, 53), make([]float64, 12)
+	weeks[0], months[0], months[1] = 10, 10, 5
+	var b bytes.Buffer
+	err := Calendar(&b, Terminal{}, "", 2024, values, format, CalendarExtras{Weeks: weeks, Months: months})
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(b.String(), "\n")
+	tests := []struct {
+		line int
+		text string
+	}{
+		// 2024 starts on Monday
+		{1, "Jan 01 │██ · · · · · ·│ 10   Jan 10"},
+		{5, "Jan 29 │ · · ·▒▒ · · ·│  0   Feb 5"},
+		{53, "Dec 30 │ · ·          │  0"},
+	}
+	for _, test := range tests {
+		if lines[test.line] != test.text {
+			t.Errorf("line %d is %q, expected %q", test.line, lines[test.line], test.text)
+		}
+	}
+}
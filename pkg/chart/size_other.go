@@ -0,0 +1,10 @@
+//go:build !unix
+
+package chart
+
+import "os"
+
+// size can't detect terminal on this platform, so charts use COLUMNS or default width without colours
+func size(_ *os.File) (int, bool) {
+	return 0, false
+}
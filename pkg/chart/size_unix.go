@@ -0,0 +1,18 @@
+//go:build unix
+
+package chart
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// size returns width of terminal and false, if file isn't terminal
+func size(f *os.File) (int, bool) {
+	ws, err := unix.IoctlGetWinsize(int(f.Fd()), unix.TIOCGWINSZ) // #nosec G115
+	if err != nil {
+		return 0, false
+	}
+	return int(ws.Col), true
+}
@@ -0,0 +1,406 @@
+package chart
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"math"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Size of SVG chart in pixels
+type Size struct {
+	Width  int
+	Height int
+}
+
+// svgColors follow the order of colors in server's plot page and terminal colours
+var svgColors = []string{
+	"#0000ff", "#00ff00", "#ff0000", "#00ffff", "#ffff00", "#ff00ff",
+	"#000088", "#008800", "#880000", "#00f000", "#0000f0",
+}
+
+// heatColors are calendar levels from the lowest to the highest value. Empty days use the first one.
+var heatColors = []string{"#88888833", "#9be9a8", "#40c463", "#30a14e", "#216e39"}
+
+// margins around plot area leave room for title, axis labels and legend
+const (
+	marginLeft   = 70
+	marginRight  = 20
+	marginTop    = 30
+	marginBottom = 50
+	yTicks       = 5
+)
+
+// textColor is readable on both light and dark theme of server
+const textColor = "#888888"
+
+type svg struct {
+	sb strings.Builder
+}
+
+func newSVG(width, height int) *svg {
+	s := &svg{}
+	fmt.Fprintf(&s.sb, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" `+
+		`font-family="sans-serif" font-size="12" fill="%s">`+"\n", width, height, width, height, textColor)
+	return s
+}
+
+func (s *svg) text(x, y float64, anchor, text string) {
+	fmt.Fprintf(&s.sb, `<text x="%.1f" y="%.1f" text-anchor="%s">%s</text>`+"\n",
+		x, y, anchor, html.EscapeString(text))
+}
+
+func (s *svg) line(x1, y1, x2, y2 float64, opacity float64) {
+	fmt.Fprintf(&s.sb, `<line x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f" stroke="%s" stroke-opacity="%.1f"/>`+"\n",
+		x1, y1, x2, y2, textColor, opacity)
+}
+
+// rect draws rectangle with tooltip, when title is given
+func (s *svg) rect(x, y, width, height float64, color, title string) {
+	fmt.Fprintf(&s.sb, `<rect x="%.1f" y="%.1f" width="%.1f" height="%.1f" fill="%s">`, x, y, width, height, color)
+	if title != "" {
+		fmt.Fprintf(&s.sb, `<title>%s</title>`, html.EscapeString(title))
+	}
+	s.sb.WriteString("</rect>\n")
+}
+
+func (s *svg) write(w io.Writer) error {
+	s.sb.WriteString("</svg>\n")
+	_, err := io.WriteString(w, s.sb.String())
+	return err
+}
+
+// plotArea maps values into pixels of area between margins
+type plotArea struct {
+	left, top, width, height float64
+	lowest, highest          float64
+}
+
+func newPlotArea(size Size, series []Series) plotArea {
+	lowest, highest := bounds(series)
+	if highest == lowest {
+		highest = lowest + 1
+	}
+	return plotArea{
+		left: marginLeft, top: marginTop,
+		width:  float64(max(1, size.Width-marginLeft-marginRight)),
+		height: float64(max(1, size.Height-marginTop-marginBottom)),
+		lowest: lowest, highest: highest,
+	}
+}
+
+func (a plotArea) y(value float64) float64 {
+	return a.top + a.height - (value-a.lowest)/(a.highest-a.lowest)*a.height
+}
+
+func (a plotArea) bottom() float64 {
+	return a.top + a.height
+}
+
+// axes draws title, y axis with grid lines and x axis
+func (a plotArea) axes(s *svg, title string, format func(float64) string) {
+	if title != "" {
+		s.text(a.left, marginTop/2+4, "start", title)
+	}
+	for tick := range yTicks {
+		value := a.lowest + (a.highest-a.lowest)*float64(tick)/(yTicks-1)
+		y := a.y(value)
+		s.line(a.left, y, a.left+a.width, y, 0.3)
+		s.text(a.left-6, y+4, "end", format(value))
+	}
+	s.line(a.left, a.top, a.left, a.bottom(), 1)
+	s.line(a.left, a.bottom(), a.left+a.width, a.bottom(), 1)
+}
+
+// legend has colour and name of every series under x axis labels
+func (a plotArea) legend(s *svg, series []Series) {
+	x, y := a.left, a.bottom()+38
+	for idx, sr := range series {
+		s.rect(x, y-9, 10, 10, svgColors[idx%len(svgColors)], "")
+		s.text(x+14, y, "start", sr.Name)
+		x += 14 + 8*float64(len(sr.Name)) + 16
+	}
+}
+
+// LinesSVG draws series as lines, e.g. cumulative distance of each year. Index of value is position on x axis.
+// xLabel names index for x axis and format names value for y axis. Missing values break the line.
+func LinesSVG(
+	w io.Writer, size Size, title string, series []Series, xLabel func(int) string, format func(float64) string,
+) error {
+	count := 0
+	for _, sr := range series {
+		count = max(count, len(sr.Values))
+	}
+	s := newSVG(size.Width, size.Height)
+	a := newPlotArea(size, series)
+	a.axes(s, title, format)
+	x := func(idx int) float64 {
+		if count < 2 {
+			return a.left
+		}
+		return a.left + float64(idx)/float64(count-1)*a.width
+	}
+	for idx, sr := range series {
+		var path strings.Builder
+		move := true
+		for vIdx, value := range sr.Values {
+			if math.IsNaN(value) {
+				move = true
+				continue
+			}
+			cmd := "L"
+			if move {
+				cmd, move = "M", false
+			}
+			fmt.Fprintf(&path, "%s%.1f %.1f ", cmd, x(vIdx), a.y(value))
+		}
+		if path.Len() == 0 {
+			continue
+		}
+		fmt.Fprintf(&s.sb, `<path d="%s" fill="none" stroke="%s" stroke-width="2"><title>%s</title></path>`+"\n",
+			strings.TrimSpace(path.String()), svgColors[idx%len(svgColors)], html.EscapeString(sr.Name))
+	}
+	if count > 0 {
+		for quarter := range 5 {
+			idx := (count - 1) * quarter / 4
+			anchor := "middle"
+			switch quarter {
+			case 0:
+				anchor = "start"
+			case 4:
+				anchor = "end"
+			}
+			s.text(x(idx), a.bottom()+16, anchor, xLabel(idx))
+		}
+	}
+	a.legend(s, series)
+	return s.write(w)
+}
+
+// BarsSVG draws vertical bar of every series for each label, e.g. one bar per year for each month.
+// Bars have value as tooltip and missing values are skipped.
+func BarsSVG(
+	w io.Writer, size Size, title string, labels []string, series []Series, format func(float64) string,
+) error {
+	s := newSVG(size.Width, size.Height)
+	a := newPlotArea(size, series)
+	a.axes(s, title, format)
+	if len(labels) > 0 && len(series) > 0 {
+		group := a.width / float64(len(labels))
+		width := group * 0.8 / float64(len(series))
+		zero := a.y(0)
+		// label every nth group, so that labels don't overlap
+		maxLabel := 1
+		for _, label := range labels {
+			maxLabel = max(maxLabel, len(label))
+		}
+		every := max(1, int(math.Ceil(float64(maxLabel*8)/group)))
+		for lIdx, label := range labels {
+			left := a.left + float64(lIdx)*group + group*0.1
+			for sIdx, sr := range series {
+				if lIdx >= len(sr.Values) || math.IsNaN(sr.Values[lIdx]) {
+					continue
+				}
+				value := sr.Values[lIdx]
+				y := a.y(value)
+				s.rect(left+float64(sIdx)*width, min(y, zero), width, math.Abs(zero-y),
+					svgColors[sIdx%len(svgColors)], fmt.Sprintf("%s %s: %s", sr.Name, label, format(value)))
+			}
+			if lIdx%every == 0 {
+				s.text(a.left+(float64(lIdx)+0.5)*group, a.bottom()+16, "middle", label)
+			}
+		}
+	}
+	a.legend(s, series)
+	return s.write(w)
+}
+
+// calendar cells are squares with gap between them
+const (
+	cellSize     = 11
+	cellStep     = 13
+	calendarLeft = 35
+	calendarTop  = 45
+)
+
+// heatLevel picks colour for value. Zero and missing values are empty.
+func heatLevel(value, highest float64) int {
+	if math.IsNaN(value) || value <= 0 || highest <= 0 {
+		return 0
+	}
+	return max(1, min(len(heatColors)-1, int(math.Ceil(value/highest*float64(len(heatColors)-1)))))
+}
+
+// CalendarExtras adds totals into margins and links into days of CalendarSVG. Zero value adds nothing.
+type CalendarExtras struct {
+	Months []float64            // totals of months, written under month names
+	Weeks  []float64            // totals of columns, drawn as bars under calendar
+	Notes  []string             // extra lines in tooltip of each day, e.g. names of activities
+	Link   func(day int) string // link of each day, day is index from January 1st
+}
+
+// weekBarHeight is height of the highest week total
+const weekBarHeight = 30
+
+// CalendarSVG draws daily values of one year as heatmap with a column for every week (Monday first).
+// First value is January 1st. Days have date and value as tooltip. NaN and zero are empty days.
+func CalendarSVG(
+	w io.Writer, title string, year int, values []float64, format func(float64) string, extras CalendarExtras,
+) error {
+	first := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+	days := first.AddDate(1, 0, -1).YearDay()
+	offset := (int(first.Weekday()) + 6) % 7 // Monday is zero
+	weeks := (offset + days + 6) / 7
+	top, height := calendarTop, calendarTop+7*cellStep+10
+	if len(extras.Months) > 0 {
+		top += 14
+		height += 14
+	}
+	if len(extras.Weeks) > 0 {
+		height += weekBarHeight + 10
+	}
+	s := newSVG(calendarLeft+weeks*cellStep+10, height)
+	if title != "" {
+		s.text(calendarLeft, 16, "start", title)
+	}
+	for row, name := range []string{"Mon", "", "Wed", "", "Fri"} {
+		if name != "" {
+			s.text(calendarLeft-6, float64(top+row*cellStep+cellSize-1), "end", name)
+		}
+	}
+	highest := 0.0
+	for _, value := range values {
+		if !math.IsNaN(value) {
+			highest = max(highest, value)
+		}
+	}
+	for day := range days {
+		date := first.AddDate(0, 0, day)
+		column, row := (offset+day)/7, (offset+day)%7
+		x, y := float64(calendarLeft+column*cellStep), float64(top+row*cellStep)
+		if date.Day() == 1 {
+			s.text(x, float64(calendarTop-6), "start", date.Format("Jan"))
+			if month := int(date.Month()) - 1; month < len(extras.Months) && !math.IsNaN(extras.Months[month]) {
+				s.text(x, float64(top-6), "start", format(extras.Months[month]))
+			}
+		}
+		value, tooltip := math.NaN(), date.Format(time.DateOnly)
+		if day < len(values) && !math.IsNaN(values[day]) {
+			value = values[day]
+			tooltip += ": " + format(value)
+		}
+		if day < len(extras.Notes) && extras.Notes[day] != "" {
+			tooltip += "\n" + extras.Notes[day]
+		}
+		if extras.Link != nil {
+			fmt.Fprintf(&s.sb, `<a href="%s">`, html.EscapeString(extras.Link(day)))
+		}
+		s.rect(x, y, cellSize, cellSize, heatColors[heatLevel(value, highest)], tooltip)
+		if extras.Link != nil {
+			s.sb.WriteString("</a>\n")
+		}
+	}
+	if len(extras.Weeks) > 0 {
+		bottom := float64(top + 7*cellStep + 4 + weekBarHeight)
+		s.text(calendarLeft-6, bottom, "end", "Wk")
+		highestWeek := 0.0
+		for _, value := range extras.Weeks {
+			if !math.IsNaN(value) {
+				highestWeek = max(highestWeek, value)
+			}
+		}
+		for column, value := range extras.Weeks {
+			if math.IsNaN(value) || value <= 0 || highestWeek <= 0 {
+				continue
+			}
+			barHeight := max(1, value/highestWeek*weekBarHeight)
+			monday := first.AddDate(0, 0, column*7-offset)
+			s.rect(float64(calendarLeft+column*cellStep), bottom-barHeight, cellSize, barHeight, heatColors[2],
+				fmt.Sprintf("Week of %s: %s", monday.Format("Jan 02"), format(value)))
+		}
+	}
+	return s.write(w)
+}
+
+// ProfileSVG draws values along distance as filled area, e.g. elevation profile of activity.
+// Distances need to grow. Unlike other charts, y axis doesn't start from zero.
+func ProfileSVG(
+	w io.Writer, size Size, title string, distances, values []float64, xFormat, yFormat func(float64) string,
+) error {
+	count := min(len(distances), len(values))
+	s := newSVG(size.Width, size.Height)
+	a := newPlotArea(size, nil)
+	if count > 0 {
+		a.lowest, a.highest = slices.Min(values[:count]), slices.Max(values[:count])
+		if a.highest == a.lowest {
+			a.highest = a.lowest + 1
+		}
+	}
+	a.axes(s, title, yFormat)
+	if count < 2 {
+		return s.write(w)
+	}
+	first, last := distances[0], distances[count-1]
+	x := func(distance float64) float64 {
+		if last == first {
+			return a.left
+		}
+		return a.left + (distance-first)/(last-first)*a.width
+	}
+	var path strings.Builder
+	fmt.Fprintf(&path, "M%.1f %.1f", x(first), a.bottom())
+	for idx := range count {
+		fmt.Fprintf(&path, " L%.1f %.1f", x(distances[idx]), a.y(values[idx]))
+	}
+	fmt.Fprintf(&path, " L%.1f %.1f Z", x(last), a.bottom())
+	fmt.Fprintf(&s.sb, `<path d="%s" fill="%s" fill-opacity="0.4" stroke="%s"/>`+"\n",
+		path.String(), svgColors[0], svgColors[0])
+	for quarter := range 5 {
+		distance := first + (last-first)*float64(quarter)/4
+		s.text(x(distance), a.bottom()+16, "middle", xFormat(distance))
+	}
+	return s.write(w)
+}
+
+// RouteSVG draws route of latitude and longitude points. Map is scaled to fit size without distorting it.
+// Green dot is the start and red dot the end of route.
+func RouteSVG(w io.Writer, size Size, route [][2]float64) error {
+	s := newSVG(size.Width, size.Height)
+	if len(route) == 0 {
+		s.text(float64(size.Width)/2, float64(size.Height)/2, "middle", "No route")
+		return s.write(w)
+	}
+	minLat, maxLat, minLng, maxLng := route[0][0], route[0][0], route[0][1], route[0][1]
+	for _, point := range route {
+		minLat, maxLat = min(minLat, point[0]), max(maxLat, point[0])
+		minLng, maxLng = min(minLng, point[1]), max(maxLng, point[1])
+	}
+	// longitude degrees get shorter towards poles
+	scaleLng := math.Cos((minLat + maxLat) / 2 * math.Pi / 180)
+	const padding = 10.0
+	width, height := float64(size.Width)-2*padding, float64(size.Height)-2*padding
+	scale := math.Min(width/math.Max((maxLng-minLng)*scaleLng, 1e-9), height/math.Max(maxLat-minLat, 1e-9))
+	left := padding + (width-(maxLng-minLng)*scaleLng*scale)/2
+	top := padding + (height-(maxLat-minLat)*scale)/2
+	xy := func(point [2]float64) (float64, float64) {
+		return left + (point[1]-minLng)*scaleLng*scale, top + (maxLat-point[0])*scale
+	}
+	points := make([]string, len(route))
+	for idx, point := range route {
+		x, y := xy(point)
+		points[idx] = fmt.Sprintf("%.1f,%.1f", x, y)
+	}
+	fmt.Fprintf(&s.sb, `<polyline points="%s" fill="none" stroke="%s" stroke-width="3" stroke-linejoin="round"/>`+"\n",
+		strings.Join(points, " "), svgColors[2])
+	for _, end := range []struct {
+		point [2]float64
+		color string
+	}{{route[0], "#00aa00"}, {route[len(route)-1], "#cc0000"}} {
+		x, y := xy(end.point)
+		fmt.Fprintf(&s.sb, `<circle cx="%.1f" cy="%.1f" r="5" fill="%s"/>`+"\n", x, y, end.color)
+	}
+	return s.write(w)
+}
@@ -0,0 +1,32 @@
+package chart
+
+import (
+	"os"
+	"strconv"
+)
+
+// defaultWidth is used, when output isn't terminal and COLUMNS isn't set
+const defaultWidth = 80
+
+// Terminal tells how wide charts can be and if ANSI colours can be used
+type Terminal struct {
+	Width int
+	Color bool
+}
+
+// Detect checks width and colour capability of terminal behind file.
+// COLUMNS overrides detected width, NO_COLOR and TERM=dumb disable colours.
+func Detect(f *os.File) Terminal {
+	width, isTerminal := size(f)
+	if columns, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && columns > 0 {
+		width = columns
+	}
+	if width <= 0 {
+		width = defaultWidth
+	}
+	_, noColor := os.LookupEnv("NO_COLOR")
+	return Terminal{
+		Width: width,
+		Color: isTerminal && !noColor && os.Getenv("TERM") != "dumb",
+	}
+}
@@ -0,0 +1,140 @@
+package efforts
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+)
+
+// Point is cumulative elapsed time (seconds) and distance (meters) from the start of activity
+type Point struct {
+	Time     float64
+	Distance float64
+}
+
+// Result is the fastest segment found for given distance or duration
+type Result struct {
+	Name     string
+	Time     float64
+	Distance float64
+}
+
+// FromSplits turns splits into cumulative points. Split boundaries are only known points,
+// so speed is expected to be constant within split.
+func FromSplits(times []int, distances []float64) []Point {
+	points := []Point{{}}
+	total := Point{}
+	for idx := range min(len(times), len(distances)) {
+		total.Time += float64(times[idx])
+		total.Distance += distances[idx]
+		points = append(points, total)
+	}
+	return points
+}
+
+// FromStreams turns Strava's time and distance streams into cumulative points
+func FromStreams(times []int, distances []float64) []Point {
+	points := []Point{}
+	for idx := range min(len(times), len(distances)) {
+		points = append(points, Point{Time: float64(times[idx]), Distance: distances[idx]})
+	}
+	return points
+}
+
+// DistanceName turns meters into name like 3k or 1.5k
+func DistanceName(meters int) string {
+	return strconv.FormatFloat(float64(meters)/1000, 'f', -1, 64) + "k"
+}
+
+// DurationName turns seconds into name like 20 min or 1 h
+func DurationName(seconds int) string {
+	if seconds%3600 == 0 {
+		return fmt.Sprintf("%d h", seconds/3600)
+	}
+	if seconds%60 == 0 {
+		return fmt.Sprintf("%d min", seconds/60)
+	}
+	return fmt.Sprintf("%d s", seconds)
+}
+
+// Compute finds fastest segments for all given distances (meters) and durations (seconds).
+// Targets longer than the activity are skipped.
+func Compute(points []Point, distances, durations []int) []Result {
+	results := []Result{}
+	for _, d := range distances {
+		if t, ok := FastestDistance(points, float64(d)); ok {
+			results = append(results, Result{Name: DistanceName(d), Time: t, Distance: float64(d)})
+		}
+	}
+	for _, d := range durations {
+		if dist, ok := LongestDuration(points, float64(d)); ok {
+			results = append(results, Result{Name: DurationName(d), Time: float64(d), Distance: dist})
+		}
+	}
+	return results
+}
+
+// FastestDistance returns shortest time needed to cover distance in contiguous segment.
+// Optimal segment on piecewise linear series always starts or ends at known point,
+// so it is enough to test both of those.
+func FastestDistance(points []Point, distance float64) (float64, bool) {
+	if len(points) < 2 || distance <= 0 || points[len(points)-1].Distance-points[0].Distance < distance {
+		return 0, false
+	}
+	best := math.Inf(1)
+	for _, p := range points {
+		if end, ok := timeAt(points, p.Distance+distance); ok {
+			best = min(best, end-p.Time)
+		}
+		if start, ok := timeAt(points, p.Distance-distance); ok {
+			best = min(best, p.Time-start)
+		}
+	}
+	return best, !math.IsInf(best, 1)
+}
+
+// LongestDuration returns longest distance covered within duration in contiguous segment
+func LongestDuration(points []Point, duration float64) (float64, bool) {
+	if len(points) < 2 || duration <= 0 || points[len(points)-1].Time-points[0].Time < duration {
+		return 0, false
+	}
+	best := math.Inf(-1)
+	for _, p := range points {
+		if end, ok := distanceAt(points, p.Time+duration); ok {
+			best = max(best, end-p.Distance)
+		}
+		if start, ok := distanceAt(points, p.Time-duration); ok {
+			best = max(best, p.Distance-start)
+		}
+	}
+	return best, !math.IsInf(best, -1)
+}
+
+// timeAt interpolates time when given distance was reached
+func timeAt(points []Point, distance float64) (float64, bool) {
+	idx := sort.Search(len(points), func(i int) bool { return points[i].Distance >= distance })
+	switch {
+	case idx == len(points) || (idx == 0 && points[0].Distance > distance):
+		return 0, false
+	case points[idx].Distance == distance || idx == 0:
+		return points[idx].Time, true
+	}
+	prev, next := points[idx-1], points[idx]
+	ratio := (distance - prev.Distance) / (next.Distance - prev.Distance)
+	return prev.Time + ratio*(next.Time-prev.Time), true
+}
+
+// distanceAt interpolates distance covered at given time
+func distanceAt(points []Point, t float64) (float64, bool) {
+	idx := sort.Search(len(points), func(i int) bool { return points[i].Time >= t })
+	switch {
+	case idx == len(points) || (idx == 0 && points[0].Time > t):
+		return 0, false
+	case points[idx].Time == t || idx == 0:
+		return points[idx].Distance, true
+	}
+	prev, next := points[idx-1], points[idx]
+	ratio := (t - prev.Time) / (next.Time - prev.Time)
+	return prev.Distance + ratio*(next.Distance-prev.Distance), true
+}
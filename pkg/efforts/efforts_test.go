@@ -0,0 +1,75 @@
+package efforts //nolint:testpackage
+
+import (
+	"math"
+	"testing"
+)
+
+func TestFastestDistance(t *testing.T) {
+	values := []struct {
+		name     string
+		times    []int
+		meters   []float64
+		distance float64
+		expected float64
+		found    bool
+	}{
+		{
+			name:     "exact_splits",
+			times:    []int{300, 240, 260, 320},
+			meters:   []float64{1000, 1000, 1000, 1000},
+			distance: 2000,
+			expected: 500,
+			found:    true,
+		},
+		{
+			name:     "interpolated",
+			times:    []int{300, 240, 260, 320},
+			meters:   []float64{1000, 1000, 1000, 1000},
+			distance: 1500,
+			expected: 370,
+			found:    true,
+		},
+		{
+			name:     "too_long",
+			times:    []int{300, 240},
+			meters:   []float64{1000, 1000},
+			distance: 3000,
+			found:    false,
+		},
+	}
+	for _, value := range values {
+		t.Run(value.name, func(t *testing.T) {
+			got, ok := FastestDistance(FromSplits(value.times, value.meters), value.distance)
+			if ok != value.found || math.Abs(got-value.expected) > 0.001 {
+				t.Errorf("got %f (%t) vs. expected %f (%t)", got, ok, value.expected, value.found)
+			}
+		})
+	}
+}
+
+func TestLongestDuration(t *testing.T) {
+	points := FromStreams([]int{0, 100, 200, 300, 400}, []float64{0, 300, 700, 1000, 1200})
+	got, ok := LongestDuration(points, 200)
+	if !ok || math.Abs(got-700) > 0.001 {
+		t.Errorf("got %f (%t) vs. expected 700", got, ok)
+	}
+	if _, ok = LongestDuration(points, 500); ok {
+		t.Error("duration longer than activity should not be found")
+	}
+}
+
+func TestNames(t *testing.T) {
+	values := map[string]string{
+		DistanceName(3000): "3k",
+		DistanceName(1500): "1.5k",
+		DurationName(1200): "20 min",
+		DurationName(3600): "1 h",
+		DurationName(45):   "45 s",
+	}
+	for got, expected := range values {
+		if got != expected {
+			t.Errorf("got %s vs. expected %s", got, expected)
+		}
+	}
+}
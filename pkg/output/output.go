@@ -0,0 +1,353 @@
+// Package output writes tables from commands in csv, tsv, table, json, ndjson, markdown or html format.
+package output
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/olekukonko/tablewriter"
+)
+
+// Table is output of a command. Footer (e.g. totals) is optional.
+type Table struct {
+	Caption string
+	Headers []string
+	Rows    [][]string
+	Footer  []string
+}
+
+// Options select format, columns and sort order
+type Options struct {
+	Format  string
+	Columns []string // header names, empty means all columns
+	Sort    string   // header name, "-" prefix sorts in descending order
+}
+
+type writeFn func(w io.Writer, t Table) error
+
+var writers = map[string]writeFn{
+	"csv":      writeCSV(','),
+	"tsv":      writeCSV('\t'),
+	"table":    writeTable,
+	"json":     writeJSON,
+	"ndjson":   writeNDJSON,
+	"markdown": writeMarkdown,
+	"html":     writeHTML,
+}
+
+// Formats lists supported output formats
+func Formats() []string {
+	return []string{"csv", "tsv", "table", "json", "ndjson", "markdown", "html"}
+}
+
+// Validate checks that format is known, so that commands can fail before querying database
+func Validate(format string) error {
+	if _, ok := writers[format]; !ok {
+		return fmt.Errorf("unknown format: %s (valid formats are %v)", format, Formats())
+	}
+	return nil
+}
+
+// Write sorts and selects columns from table and writes it in requested format
+func Write(w io.Writer, t Table, opts Options) error {
+	if err := Validate(opts.Format); err != nil {
+		return err
+	}
+	t, err := t.Select(opts)
+	if err != nil {
+		return err
+	}
+	return writers[opts.Format](w, t)
+}
+
+// WriteAll writes several tables as one document. Tables are separated by empty line,
+// JSON formats output one array (json) or one set of lines (ndjson) per table and
+// html has all tables in the same page.
+func WriteAll(w io.Writer, format string, tables ...Table) error {
+	if err := Validate(format); err != nil {
+		return err
+	}
+	if format == "html" {
+		return htmlTemplate.Execute(w, tables)
+	}
+	for idx, t := range tables {
+		if idx > 0 && format != "json" && format != "ndjson" {
+			if _, err := io.WriteString(w, "\n"); err != nil {
+				return err
+			}
+		}
+		if err := writers[format](w, t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Select sorts rows and selects columns as given in options
+func (t Table) Select(opts Options) (Table, error) {
+	if opts.Sort != "" {
+		t.Rows = slices.Clone(t.Rows)
+		if err := t.sort(opts.Sort); err != nil {
+			return t, err
+		}
+	}
+	if len(opts.Columns) > 0 {
+		return t.selectColumns(opts.Columns)
+	}
+	return t, nil
+}
+
+// column finds index of header. Comparison ignores case.
+func (t Table) column(name string) (int, error) {
+	for idx, header := range t.Headers {
+		if strings.EqualFold(header, name) {
+			return idx, nil
+		}
+	}
+	return -1, fmt.Errorf("unknown column: %s (valid columns are %q)", name, t.Headers)
+}
+
+func (t Table) selectColumns(names []string) (Table, error) {
+	indexes := make([]int, len(names))
+	for i, name := range names {
+		idx, err := t.column(name)
+		if err != nil {
+			return t, err
+		}
+		indexes[i] = idx
+	}
+	pick := func(row []string) []string {
+		if row == nil {
+			return nil
+		}
+		picked := make([]string, len(indexes))
+		for i, idx := range indexes {
+			if idx < len(row) {
+				picked[i] = row[idx]
+			}
+		}
+		return picked
+	}
+	selected := Table{Caption: t.Caption, Headers: pick(t.Headers), Footer: pick(t.Footer)}
+	for _, row := range t.Rows {
+		selected.Rows = append(selected.Rows, pick(row))
+	}
+	return selected, nil
+}
+
+func (t Table) sort(name string) error {
+	descending := strings.HasPrefix(name, "-")
+	idx, err := t.column(strings.TrimPrefix(name, "-"))
+	if err != nil {
+		return err
+	}
+	slices.SortStableFunc(t.Rows, func(a, b []string) int {
+		// empty values are last in both directions
+		valueA, valueB := strings.TrimSpace(a[idx]), strings.TrimSpace(b[idx])
+		switch {
+		case valueA == valueB:
+			return 0
+		case valueA == "":
+			return 1
+		case valueB == "":
+			return -1
+		case descending:
+			return compare(valueB, valueA)
+		}
+		return compare(valueA, valueB)
+	})
+	return nil
+}
+
+// compare compares digit runs as numbers, so that "9.1km" < "12.3km" and "0:45:00" < "1:30:00".
+func compare(a, b string) int {
+	for a != "" && b != "" {
+		var chunkA, chunkB string
+		chunkA, a = nextChunk(a)
+		chunkB, b = nextChunk(b)
+		numA, errA := strconv.Atoi(chunkA)
+		numB, errB := strconv.Atoi(chunkB)
+		if errA == nil && errB == nil {
+			if numA != numB {
+				return numA - numB
+			}
+			continue
+		}
+		if c := strings.Compare(chunkA, chunkB); c != 0 {
+			return c
+		}
+	}
+	return len(a) - len(b)
+}
+
+// nextChunk splits run of digits or non-digits from start of value
+func nextChunk(value string) (string, string) {
+	digit := unicode.IsDigit(rune(value[0]))
+	for idx, r := range value {
+		if unicode.IsDigit(r) != digit {
+			return value[:idx], value[idx:]
+		}
+	}
+	return value, ""
+}
+
+// records has rows and footer. Footer is the last row in formats that don't have separate footer.
+func (t Table) records() [][]string {
+	if t.Footer == nil {
+		return t.Rows
+	}
+	return append(slices.Clone(t.Rows), t.Footer)
+}
+
+func writeCSV(comma rune) writeFn {
+	return func(w io.Writer, t Table) error {
+		cw := csv.NewWriter(w)
+		cw.Comma = comma
+		if err := cw.Write(t.Headers); err != nil {
+			return err
+		}
+		for _, row := range t.records() {
+			if err := cw.Write(trim(row)); err != nil {
+				return err
+			}
+		}
+		cw.Flush()
+		return cw.Error()
+	}
+}
+
+func writeTable(w io.Writer, t Table) error {
+	table := tablewriter.NewWriter(w)
+	if t.Caption != "" {
+		table.SetCaption(true, t.Caption)
+	}
+	table.SetHeader(t.Headers)
+	table.AppendBulk(t.Rows)
+	if t.Footer != nil {
+		table.SetFooterAlignment(tablewriter.ALIGN_RIGHT)
+		table.SetFooter(t.Footer)
+	}
+	table.Render()
+	return nil
+}
+
+// object turns row into JSON object. Numbers become JSON numbers and empty values null.
+func object(headers, row []string) map[string]any {
+	obj := make(map[string]any, len(headers))
+	for idx, header := range headers {
+		var value any
+		if idx < len(row) {
+			if cell := strings.TrimSpace(row[idx]); cell != "" {
+				value = cell
+				if num, err := strconv.ParseFloat(cell, 64); err == nil {
+					value = num
+				}
+			}
+		}
+		obj[header] = value
+	}
+	return obj
+}
+
+func writeJSON(w io.Writer, t Table) error {
+	objects := []map[string]any{}
+	for _, row := range t.records() {
+		objects = append(objects, object(t.Headers, row))
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(objects)
+}
+
+func writeNDJSON(w io.Writer, t Table) error {
+	enc := json.NewEncoder(w)
+	for _, row := range t.records() {
+		if err := enc.Encode(object(t.Headers, row)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeMarkdown(w io.Writer, t Table) error {
+	line := func(row []string) string {
+		cells := make([]string, len(row))
+		for idx, cell := range row {
+			cells[idx] = strings.ReplaceAll(strings.TrimSpace(cell), "|", `\|`)
+		}
+		return "| " + strings.Join(cells, " | ") + " |\n"
+	}
+	var sb strings.Builder
+	if t.Caption != "" {
+		sb.WriteString("**" + t.Caption + "**\n\n")
+	}
+	sb.WriteString(line(t.Headers))
+	sb.WriteString("|" + strings.Repeat(" --- |", len(t.Headers)) + "\n")
+	for _, row := range t.Rows {
+		sb.WriteString(line(row))
+	}
+	if t.Footer != nil {
+		footer := make([]string, len(t.Footer))
+		for idx, cell := range t.Footer {
+			if cell = strings.TrimSpace(cell); cell != "" {
+				footer[idx] = "**" + cell + "**"
+			}
+		}
+		sb.WriteString(line(footer))
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+var htmlTemplate = template.Must(template.New("html").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{ with index . 0 }}{{ if .Caption }}{{ .Caption }}{{ else }}mystats{{ end }}{{ end }}</title>
+<style>
+body { font-family: sans-serif; }
+table { border-collapse: collapse; margin-bottom: 1em; }
+th, td { border: 1px solid #ccc; padding: 2px 8px; }
+td { text-align: right; }
+thead, tfoot { background-color: #eee; font-weight: bold; }
+</style>
+</head>
+<body>
+{{- range . }}
+<table>
+{{- if .Caption }}
+<caption>{{ .Caption }}</caption>
+{{- end }}
+<thead><tr>{{ range .Headers }}<th>{{ . }}</th>{{ end }}</tr></thead>
+<tbody>
+{{- range .Rows }}
+<tr>{{ range . }}<td>{{ . }}</td>{{ end }}</tr>
+{{- end }}
+</tbody>
+{{- if .Footer }}
+<tfoot><tr>{{ range .Footer }}<td>{{ . }}</td>{{ end }}</tr></tfoot>
+{{- end }}
+</table>
+{{- end }}
+</body>
+</html>
+`))
+
+func writeHTML(w io.Writer, t Table) error {
+	return htmlTemplate.Execute(w, []Table{t})
+}
+
+func trim(row []string) []string {
+	trimmed := make([]string, len(row))
+	for idx, cell := range row {
+		trimmed[idx] = strings.TrimSpace(cell)
+	}
+	return trimmed
+}
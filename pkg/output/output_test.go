@@ -0,0 +1,72 @@
+package output //nolint:testpackage
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestWrite(t *testing.T) {
+	table := Table{
+		Caption: "distance",
+		Headers: []string{"Name", "Distance", "Time"},
+		Rows: [][]string{
+			{"Long run", "21.1km", " 1:50:00"},
+			{"Easy | slow", " 9.5km", " 0:55:00"},
+			{"Race", "42.2km", ""},
+		},
+		Footer: []string{"total", "72.8km", " 2:45:00"},
+	}
+	values := []struct {
+		name   string
+		opts   Options
+		output string
+	}{
+		{
+			name:   "csv",
+			opts:   Options{Format: "csv", Columns: []string{"name", "distance"}, Sort: "distance"},
+			output: "Name,Distance\nEasy | slow,9.5km\nLong run,21.1km\nRace,42.2km\ntotal,72.8km\n",
+		},
+		{
+			name:   "tsv",
+			opts:   Options{Format: "tsv", Columns: []string{"Time"}, Sort: "-time"},
+			output: "Time\n1:50:00\n0:55:00\n\n2:45:00\n",
+		},
+		{
+			name: "ndjson",
+			opts: Options{Format: "ndjson", Columns: []string{"Name", "Time"}},
+			output: "{\"Name\":\"Long run\",\"Time\":\"1:50:00\"}\n{\"Name\":\"Easy | slow\",\"Time\":\"0:55:00\"}\n" +
+				"{\"Name\":\"Race\",\"Time\":null}\n{\"Name\":\"total\",\"Time\":\"2:45:00\"}\n",
+		},
+		{
+			name: "markdown",
+			opts: Options{Format: "markdown", Columns: []string{"Name", "Distance"}},
+			output: "**distance**\n\n| Name | Distance |\n| --- | --- |\n| Long run | 21.1km |\n" +
+				"| Easy \\| slow | 9.5km |\n| Race | 42.2km |\n| **total** | **72.8km** |\n",
+		},
+	}
+	for _, value := range values {
+		t.Run(value.name, func(t *testing.T) {
+			var b bytes.Buffer
+			if err := Write(&b, table, value.opts); err != nil {
+				t.Fatal(err)
+			}
+			if b.String() != value.output {
+				t.Errorf("output mismatch:\n%s\nvs.\n%s", b.String(), value.output)
+			}
+		})
+	}
+	var b bytes.Buffer
+	if err := Write(&b, table, Options{Format: "html"}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(b.String(), "<td>Easy | slow</td>") || !strings.Contains(b.String(), "<tfoot>") {
+		t.Errorf("unexpected html: %s", b.String())
+	}
+	if err := Write(&b, table, Options{Format: "xml"}); err == nil {
+		t.Error("unknown format didn't cause error")
+	}
+	if err := Write(&b, table, Options{Format: "csv", Columns: []string{"pace"}}); err == nil {
+		t.Error("unknown column didn't cause error")
+	}
+}
@@ -0,0 +1,396 @@
+// Package present turns typed results from pkg/stats into headers and rows of strings
+// in user's unit system and locale. Both cmd and server use it.
+package present
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jylitalo/mystats/pkg/chart"
+	"github.com/jylitalo/mystats/pkg/season"
+	"github.com/jylitalo/mystats/pkg/stats"
+	"github.com/jylitalo/mystats/pkg/units"
+)
+
+// Duration formats duration as hours, minutes and seconds
+func Duration(d time.Duration) string {
+	seconds := int(d.Round(time.Second).Seconds())
+	return fmt.Sprintf("%2d:%02d:%02d", seconds/3600, seconds/60%60, seconds%60)
+}
+
+// Pace formats seconds per distance unit as minutes and seconds
+func Pace(seconds float64) string {
+	rounded := int(math.Round(seconds))
+	return fmt.Sprintf("%d:%02d", rounded/60, rounded%60)
+}
+
+// Date formats date in locale
+func Date(t time.Time, locale units.Locale) string {
+	return locale.Date(t.Year(), int(t.Month()), t.Day())
+}
+
+// Link is URL into activity in Strava
+func Link(stravaID int64) string {
+	return fmt.Sprintf("https://strava.com/activities/%d", stravaID)
+}
+
+// Unit returns unit of measure in unit system
+func Unit(m stats.Measure, s units.System) string {
+	switch m.Dimension {
+	case stats.DimensionDistance:
+		return s.DistanceUnit()
+	case stats.DimensionElevation:
+		return s.ElevationUnit()
+	case stats.DimensionDuration:
+		return "h"
+	case stats.DimensionPace:
+		return "min/" + s.DistanceUnit()
+	case stats.DimensionSpeed:
+		return s.DistanceUnit() + "/h"
+	case stats.DimensionGradient:
+		return s.ElevationUnit() + "/" + s.DistanceUnit()
+	case stats.DimensionHeartRate:
+		return "bpm"
+	case stats.DimensionEnergy:
+		return "kJ"
+	}
+	return ""
+}
+
+// Convert turns value from base units into unit system
+func Convert(m stats.Measure, s units.System, value float64) float64 {
+	switch m.Dimension {
+	case stats.DimensionDistance:
+		return s.Distance(value)
+	case stats.DimensionElevation:
+		return s.Elevation(value)
+	case stats.DimensionDuration:
+		return value / 3600
+	case stats.DimensionPace:
+		return value / s.Distance(1)
+	case stats.DimensionSpeed:
+		return s.Distance(value * 3600)
+	case stats.DimensionGradient:
+		return s.Elevation(value) / s.Distance(1)
+	}
+	return value
+}
+
+// Value formats value with unit
+func Value(m stats.Measure, s units.System, value float64) string {
+	converted := Convert(m, s, value)
+	switch m.Dimension {
+	case stats.DimensionPace:
+		return Pace(converted) + strings.TrimPrefix(Unit(m, s), "min")
+	case stats.DimensionDistance, stats.DimensionDuration, stats.DimensionSpeed, stats.DimensionGradient:
+		return fmt.Sprintf("%4.1f", converted) + Unit(m, s)
+	}
+	return fmt.Sprintf("%4.0f", converted) + Unit(m, s)
+}
+
+// Header is measure's name with unit
+func Header(m stats.Measure, s units.System) string {
+	if unit := Unit(m, s); unit != "" {
+		return m.Name + " (" + unit + ")"
+	}
+	return m.Name
+}
+
+// Period names month or returns week/day number
+func Period(period string, value int) string {
+	if period == "month" {
+		return time.Month(value).String()
+	}
+	return strconv.Itoa(value)
+}
+
+// StatsSeries has series for every year and labels of periods that have activities, e.g. for bar charts.
+// Values are in base units and missing values are NaN.
+func StatsSeries(result *stats.StatsResult, s season.Season) ([]string, []chart.Series) {
+	series := make([]chart.Series, len(result.Years))
+	for idx, label := range s.Labels(result.Years) {
+		series[idx].Name = label
+	}
+	found := func(v *float64) bool { return v != nil }
+	labels := []string{}
+	for _, p := range s.Order(result.Period) {
+		if p > len(result.Values) || !slices.ContainsFunc(result.Values[p-1], found) {
+			continue
+		}
+		labels = append(labels, Period(result.Period, p))
+		for idx, value := range result.Values[p-1] {
+			v := math.NaN()
+			if value != nil {
+				v = *value
+			}
+			series[idx].Values = append(series[idx].Values, v)
+		}
+	}
+	return labels, series
+}
+
+// List formats activities
+func List(activities []stats.Activity, p units.Preferences) ([]string, [][]string) {
+	results := [][]string{}
+	for _, a := range activities {
+		results = append(results, []string{
+			strconv.FormatInt(a.StravaID, 10),
+			Date(a.Date, p.Locale), a.Name,
+			fmt.Sprintf("%.1f", p.System.Distance(a.Distance)), fmt.Sprintf("%.0f", p.System.Elevation(a.Elevation)),
+			Duration(a.ElapsedTime),
+			a.Type, a.WorkoutType, Link(a.StravaID),
+		})
+	}
+	return []string{
+		"ID", "Date", "Name", "Distance (" + p.System.DistanceUnit() + ")",
+		"Elevation (" + p.System.ElevationUnit() + ")", "Time", "Type", "Workout Type", "Link",
+	}, results
+}
+
+// Details formats activity as field and value rows. Pace and elevation profile of splits are sparklines.
+func Details(d *stats.ActivityDetails, splits []stats.ActivitySplit, p units.Preferences) ([]string, [][]string) {
+	results := [][]string{
+		{"ID", strconv.FormatInt(d.StravaID, 10)},
+		{"Date", Date(d.Date, p.Locale)},
+		{"Name", d.Name},
+		{"Type", d.Type},
+		{"Sport Type", d.SportType},
+		{"Workout Type", d.WorkoutType},
+		{"Distance", fmt.Sprintf("%.2f%s", p.System.Distance(d.Distance), p.System.DistanceUnit())},
+		{"Elevation", fmt.Sprintf("%.0f%s", p.System.Elevation(d.Elevation), p.System.ElevationUnit())},
+		{"Elapsed Time", Duration(d.ElapsedTime)},
+		{"Moving Time", Duration(d.MovingTime)},
+	}
+	if d.Distance > 0 {
+		pace := d.MovingTime.Seconds() / p.System.Distance(d.Distance)
+		results = append(results, []string{"Pace", Pace(pace) + "/" + p.System.DistanceUnit()})
+	}
+	if d.AverageHeartrate > 0 {
+		results = append(results, []string{"Heart Rate", fmt.Sprintf("%.0fbpm", d.AverageHeartrate)})
+	}
+	if d.Kilojoules > 0 {
+		results = append(results, []string{"Energy", fmt.Sprintf("%.0fkJ", d.Kilojoules)})
+	}
+	if len(splits) > 0 {
+		profile := make([]float64, len(splits)+1)
+		for idx, s := range splits {
+			profile[idx+1] = profile[idx] + s.ElevationDiff
+		}
+		results = append(results,
+			[]string{"Pace Splits", Sparkline(SplitPaces(splits, p.System))},
+			[]string{"Elevation Profile", Sparkline(profile)},
+		)
+	}
+	results = append(results, []string{"Link", Link(d.StravaID)})
+	return []string{"Field", "Value"}, results
+}
+
+// splitPace is seconds per distance unit
+func splitPace(s stats.ActivitySplit, system units.System) float64 {
+	if s.Distance <= 0 {
+		return 0
+	}
+	return s.ElapsedTime.Seconds() / system.Distance(s.Distance)
+}
+
+// SplitPaces lists pace (seconds per distance unit) of splits. Splits without distance are skipped.
+func SplitPaces(splits []stats.ActivitySplit, system units.System) []float64 {
+	paces := []float64{}
+	for _, s := range splits {
+		if s.Distance > 0 {
+			paces = append(paces, splitPace(s, system))
+		}
+	}
+	return paces
+}
+
+// Splits formats splits with running totals
+func Splits(splits []stats.ActivitySplit, p units.Preferences) ([]string, [][]string) {
+	var totalTime time.Duration
+	var ascent, descent float64
+
+	results := [][]string{}
+	for _, s := range splits {
+		totalTime += s.ElapsedTime
+		if s.ElevationDiff < 0 {
+			descent += -s.ElevationDiff
+		} else {
+			ascent += s.ElevationDiff
+		}
+		seconds := int(s.ElapsedTime.Seconds())
+		results = append(results, []string{
+			strconv.Itoa(s.Split),
+			fmt.Sprintf("%02d:%02d", seconds/60%60, seconds%60),
+			Pace(splitPace(s, p.System)),
+			fmt.Sprintf("%.0f", p.System.Elevation(s.ElevationDiff)),
+			Duration(totalTime),
+			fmt.Sprintf("%.0f", p.System.Elevation(ascent)), fmt.Sprintf("%.0f", p.System.Elevation(descent)),
+			heartrate(s.AverageHeartrate),
+		})
+	}
+	unit := " (" + p.System.ElevationUnit() + ")"
+	return []string{
+		"Split", "Time", "Pace (min/" + p.System.DistanceUnit() + ")", "Elevation" + unit, "Total Time", "Ascent" + unit, "Descent" + unit,
+		"Heart Rate",
+	}, results
+}
+
+// heartrate formats average heart rate. Zero means that activity doesn't have heart rate.
+func heartrate(bpm float64) string {
+	if bpm <= 0 {
+		return ""
+	}
+	return fmt.Sprintf("%.0f", bpm)
+}
+
+// Laps formats laps of activity
+func Laps(laps []stats.ActivityLap, p units.Preferences) ([]string, [][]string) {
+	results := [][]string{}
+	for _, l := range laps {
+		pace := 0.0
+		if l.Distance > 0 {
+			pace = l.ElapsedTime.Seconds() / p.System.Distance(l.Distance)
+		}
+		results = append(results, []string{
+			strconv.Itoa(l.Lap), l.Name,
+			fmt.Sprintf("%.2f", p.System.Distance(l.Distance)),
+			strings.TrimSpace(Duration(l.ElapsedTime)), Pace(pace),
+			fmt.Sprintf("%.0f", p.System.Elevation(l.Elevation)),
+			heartrate(l.AverageHeartrate),
+		})
+	}
+	return []string{
+		"Lap", "Name", "Distance (" + p.System.DistanceUnit() + ")", "Time", "Pace (min/" + p.System.DistanceUnit() + ")",
+		"Elevation (" + p.System.ElevationUnit() + ")", "Heart Rate",
+	}, results
+}
+
+// Best formats best efforts. Link is the last column.
+func Best(distance string, efforts []stats.BestEffort, p units.Preferences) ([]string, [][]string) {
+	results := [][]string{}
+	for _, e := range efforts {
+		results = append(results, []string{
+			Date(e.Date, p.Locale), e.Name,
+			Duration(e.ElapsedTime),
+			fmt.Sprintf("%.2f", p.System.Distance(e.ActivityDistance)),
+			Duration(e.ActivityTime),
+			Link(e.StravaID),
+		})
+	}
+	return []string{
+		"Date", distance, "Time", "Total (" + p.System.DistanceUnit() + ")", "Total (time)", "Link",
+	}, results
+}
+
+// Progression formats BestProgression results. Link is the last column.
+func Progression(distance string, records []stats.ProgressionRecord, p units.Preferences) ([]string, [][]string) {
+	results := [][]string{}
+	for _, r := range records {
+		improvement := ""
+		if r.Improvement > 0 {
+			seconds := int(r.Improvement.Seconds())
+			improvement = fmt.Sprintf("-%d:%02d", seconds/60, seconds%60)
+		}
+		best := "season"
+		if r.AllTime {
+			best = "all-time"
+		}
+		results = append(results, []string{
+			Date(r.Date, p.Locale), r.Name, Duration(r.ElapsedTime), improvement, best, Link(r.StravaID),
+		})
+	}
+	return []string{"Date", distance, "Time", "Improvement", "Best", "Link"}, results
+}
+
+// Top formats top periods
+func Top(result *stats.TopResult, p units.Preferences) ([]string, [][]string) {
+	results := [][]string{}
+	for _, tp := range result.Periods {
+		results = append(results, []string{
+			Value(result.Measure, p.System, tp.Value), strconv.Itoa(tp.Year), Period(result.Period, tp.Period),
+		})
+	}
+	return []string{Header(result.Measure, p.System), "year", result.Period}, results
+}
+
+// CalendarNotes has names of activities for every day of year, one name per line.
+// Index is day from January 1st.
+func CalendarNotes(year int, activities []stats.Activity) []string {
+	first := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+	notes := make([]string, first.AddDate(1, 0, -1).YearDay())
+	for _, a := range activities {
+		if day := a.Date.YearDay() - 1; a.Date.Year() == year && day < len(notes) {
+			notes[day] = strings.TrimPrefix(notes[day]+"\n"+a.Name, "\n")
+		}
+	}
+	return notes
+}
+
+// Calendar formats days that have value or activities
+func Calendar(
+	result *stats.CalendarResult, activities []stats.Activity, p units.Preferences,
+) ([]string, [][]string) {
+	notes := CalendarNotes(result.Year, activities)
+	first := time.Date(result.Year, time.January, 1, 0, 0, 0, 0, time.UTC)
+	results := [][]string{}
+	for day, value := range result.Days {
+		if math.IsNaN(value) && notes[day] == "" {
+			continue
+		}
+		formatted := ""
+		if !math.IsNaN(value) {
+			formatted = Value(result.Measure, p.System, value)
+		}
+		results = append(results, []string{
+			Date(first.AddDate(0, 0, day), p.Locale), formatted, strings.ReplaceAll(notes[day], "\n", "; "),
+		})
+	}
+	return []string{"Date", Header(result.Measure, p.System), "Activities"}, results
+}
+
+// Stats formats values and totals. Rows are indexed by period and missing values are blank.
+func Stats(result *stats.StatsResult, p units.Preferences) ([][]string, []string) {
+	format := func(value *float64) string {
+		if value == nil {
+			return "    " // helps CSV formatting
+		}
+		return Value(result.Measure, p.System, *value)
+	}
+	rows := make([][]string, len(result.Values))
+	for idx, values := range result.Values {
+		rows[idx] = make([]string, len(values))
+		for col, value := range values {
+			rows[idx][col] = format(value)
+		}
+	}
+	totals := make([]string, len(result.Totals))
+	for idx, value := range result.Totals {
+		if value != nil {
+			totals[idx] = format(value)
+		}
+	}
+	return rows, totals
+}
+
+var sparks = []rune("▁▂▃▄▅▆▇█")
+
+// Sparkline draws values as one line of block characters scaled between minimum and maximum
+func Sparkline(values []float64) string {
+	if len(values) == 0 {
+		return ""
+	}
+	lowest, highest := slices.Min(values), slices.Max(values)
+	line := make([]rune, len(values))
+	for idx, value := range values {
+		level := 0
+		if highest > lowest {
+			level = int(math.Round((value - lowest) / (highest - lowest) * float64(len(sparks)-1)))
+		}
+		line[idx] = sparks[level]
+	}
+	return string(line)
+}
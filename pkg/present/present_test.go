@@ -0,0 +1,78 @@
+package present //nolint:testpackage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jylitalo/mystats/pkg/stats"
+	"github.com/jylitalo/mystats/pkg/units"
+)
+
+func TestValue(t *testing.T) {
+	values := []struct {
+		name    string
+		measure string
+		system  units.System
+		value   float64
+		result  string
+	}{
+		{name: "distance", measure: "distance", system: units.Metric, value: 12345, result: "12.3km"},
+		{name: "imperial", measure: "distance", system: units.Imperial, value: 16093.44, result: "10.0mi"},
+		{name: "elevation", measure: "elevation", system: units.Metric, value: 250, result: " 250m"},
+		{name: "pace", measure: "pace", system: units.Metric, value: 0.3, result: "5:00/km"},
+		{name: "time", measure: "time", system: units.Metric, value: 5400, result: " 1.5h"},
+		{name: "count", measure: "count", system: units.Imperial, value: 7, result: "   7"},
+	}
+	for _, value := range values {
+		t.Run(value.name, func(t *testing.T) {
+			m, err := stats.MeasureByName(value.measure)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result := Value(m, value.system, value.value); result != value.result {
+				t.Errorf("value mismatch: %q vs. %q", result, value.result)
+			}
+		})
+	}
+}
+
+func TestStats(t *testing.T) {
+	m, err := stats.MeasureByName("distance")
+	if err != nil {
+		t.Fatal(err)
+	}
+	value := 5000.0
+	result := &stats.StatsResult{
+		Measure: m, Period: "month", Years: []int{2023, 2024},
+		Values: [][]*float64{{&value, nil}}, Totals: []*float64{&value, nil},
+	}
+	rows, totals := Stats(result, units.Default)
+	if rows[0][0] != " 5.0km" || rows[0][1] != "    " {
+		t.Errorf("rows mismatch: %q", rows)
+	}
+	if totals[0] != " 5.0km" || totals[1] != "" {
+		t.Errorf("totals mismatch: %q", totals)
+	}
+	if d := Duration(90 * time.Minute); d != " 1:30:00" {
+		t.Errorf("duration mismatch: %q", d)
+	}
+}
+
+func TestSparkline(t *testing.T) {
+	values := []struct {
+		name   string
+		values []float64
+		result string
+	}{
+		{name: "empty", values: nil, result: ""},
+		{name: "flat", values: []float64{3, 3, 3}, result: "▁▁▁"},
+		{name: "range", values: []float64{0, 7, 3.5, 1}, result: "▁█▅▂"},
+	}
+	for _, value := range values {
+		t.Run(value.name, func(t *testing.T) {
+			if result := Sparkline(value.values); result != value.result {
+				t.Errorf("sparkline mismatch: %s vs. %s", result, value.result)
+			}
+		})
+	}
+}
@@ -0,0 +1,524 @@
+// Package query parses filter language of query command, e.g.
+// `type in (Run, TrailRun) and distance > 20km and year >= 2022 group by month agg sum(elevation)`,
+// and compiles it into storage queries. Values are always bound as query parameters.
+package query
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/jylitalo/mystats/pkg/present"
+	"github.com/jylitalo/mystats/pkg/stats"
+	"github.com/jylitalo/mystats/pkg/units"
+	"github.com/jylitalo/mystats/storage"
+)
+
+type kind int
+
+const (
+	kindText kind = iota
+	kindInteger
+	kindDate
+	kindMeasure
+)
+
+// column is Summary table's column in group by and its header in output
+type column struct {
+	column storage.Column
+	header string
+}
+
+// field is name in query language for column (or expression) in Summary table
+type field struct {
+	column    storage.Expr
+	kind      kind
+	dimension string   // dimension of measure fields
+	group     []column // columns of group by
+}
+
+var summary = storage.Summary
+
+var (
+	yearColumn  = column{column: summary.Year, header: "Year"}
+	monthColumn = column{column: summary.Month, header: "Month"}
+)
+
+var fields = map[string]field{
+	"type":    {column: summary.Type, group: []column{{column: summary.Type, header: "Type"}}},
+	"sport":   {column: summary.SportType, group: []column{{column: summary.SportType, header: "Sport"}}},
+	"workout": {column: summary.WorkoutType, group: []column{{column: summary.WorkoutType, header: "Workout"}}},
+	"name":    {column: summary.Name},
+	"year":    {column: summary.Year, kind: kindInteger, group: []column{yearColumn}},
+	"month":   {column: summary.Month, kind: kindInteger, group: []column{yearColumn, monthColumn}},
+	"week": {
+		column: summary.Week, kind: kindInteger,
+		group: []column{{column: summary.WeekYear, header: "Year"}, {column: summary.Week, header: "Week"}},
+	},
+	"day": {
+		column: summary.Day, kind: kindInteger,
+		group: []column{yearColumn, monthColumn, {column: summary.Day, header: "Day"}},
+	},
+	"date":         {column: storage.Date(summary.StartDate), kind: kindDate},
+	"distance":     {column: summary.Distance, kind: kindMeasure, dimension: stats.DimensionDistance},
+	"elevation":    {column: summary.Elevation, kind: kindMeasure, dimension: stats.DimensionElevation},
+	"elapsed_time": {column: summary.ElapsedTime, kind: kindMeasure, dimension: stats.DimensionDuration},
+	"moving_time":  {column: summary.MovingTime, kind: kindMeasure, dimension: stats.DimensionDuration},
+	// activities without heart rate or power have zero in database
+	"heartrate": {
+		column: storage.NullIfZero(summary.AverageHeartrate), kind: kindMeasure, dimension: stats.DimensionHeartRate,
+	},
+	"kilojoules": {
+		column: storage.NullIfZero(summary.Kilojoules), kind: kindMeasure, dimension: stats.DimensionEnergy,
+	},
+}
+
+// fieldAliases are shorter names for fields
+var fieldAliases = map[string]string{
+	"time": "elapsed_time",
+}
+
+// Fields lists names that can be used in queries
+func Fields() []string {
+	return slices.Sorted(maps.Keys(fields))
+}
+
+func fieldByName(name string) (string, field, error) {
+	name = strings.ToLower(name)
+	if alias, ok := fieldAliases[name]; ok {
+		name = alias
+	}
+	f, ok := fields[name]
+	if !ok {
+		return name, f, fmt.Errorf("unknown field: %s (valid fields are %v)", name, Fields())
+	}
+	return name, f, nil
+}
+
+// functions are aggregate functions. Count doesn't need field.
+var functions = []string{"sum", "avg", "min", "max", "count"}
+
+// aggregates build aggregate functions over field
+var aggregates = map[string]func(storage.Expr) storage.Expr{
+	"sum": storage.Sum, "avg": storage.Avg, "min": storage.Min, "max": storage.Max,
+}
+
+// Aggregate is function over field, e.g. sum(elevation)
+type Aggregate struct {
+	Function string
+	Field    string
+}
+
+func (a Aggregate) String() string {
+	if a.Field == "" {
+		return a.Function
+	}
+	return a.Function + "(" + a.Field + ")"
+}
+
+// expression is aggregate over field's column
+func (a Aggregate) expression() storage.Expr {
+	if a.Field == "" {
+		return storage.Count()
+	}
+	return aggregates[a.Function](fields[a.Field].column)
+}
+
+// Query is parsed query
+type Query struct {
+	Conditions []storage.Condition
+	GroupBy    []string // field names
+	Aggregates []Aggregate
+	OrderBy    string // field name or aggregate (e.g. sum(elevation))
+	Descending bool
+	Limit      int
+}
+
+// IsList tells that query lists activities instead of aggregating them
+func (q *Query) IsList() bool {
+	return len(q.GroupBy) == 0 && len(q.Aggregates) == 0
+}
+
+// lengthUnits are meters in unit
+var lengthUnits = map[string]float64{
+	"m": 1, "km": 1 / units.Metric.Distance(1),
+	"mi": 1 / units.Imperial.Distance(1), "ft": 1 / units.Imperial.Elevation(1),
+}
+
+// durationUnits are seconds in unit
+var durationUnits = map[string]float64{"h": 3600, "min": 60, "s": 1}
+
+type token struct {
+	text   string
+	quoted bool
+}
+
+// tokenize splits text into words, numbers (with units), quoted strings and symbols
+func tokenize(text string) ([]token, error) {
+	tokens := []token{}
+	runes := []rune(text)
+	for idx := 0; idx < len(runes); {
+		r := runes[idx]
+		switch {
+		case unicode.IsSpace(r):
+			idx++
+		case r == '"' || r == '\'':
+			end := slices.Index(runes[idx+1:], r)
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated string at position %d", idx)
+			}
+			tokens = append(tokens, token{text: string(runes[idx+1 : idx+1+end]), quoted: true})
+			idx += end + 2
+		case strings.ContainsRune("(),*~=", r):
+			tokens = append(tokens, token{text: string(r)})
+			idx++
+		case strings.ContainsRune("<>!", r):
+			if idx+1 < len(runes) && runes[idx+1] == '=' {
+				tokens = append(tokens, token{text: string(runes[idx : idx+2])})
+				idx += 2
+				continue
+			}
+			if r == '!' {
+				return nil, fmt.Errorf("unexpected ! at position %d", idx)
+			}
+			tokens = append(tokens, token{text: string(r)})
+			idx++
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune("_.-:%", r):
+			end := idx
+			for end < len(runes) && (unicode.IsLetter(runes[end]) || unicode.IsDigit(runes[end]) ||
+				strings.ContainsRune("_.-:%", runes[end])) {
+				end++
+			}
+			tokens = append(tokens, token{text: string(runes[idx:end])})
+			idx = end
+		default:
+			return nil, fmt.Errorf("unexpected %c at position %d", r, idx)
+		}
+	}
+	return tokens, nil
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+	system units.System
+}
+
+func (p *parser) done() bool {
+	return p.pos >= len(p.tokens)
+}
+
+func (p *parser) peek() string {
+	if p.done() {
+		return ""
+	}
+	return p.tokens[p.pos].text
+}
+
+// keyword consumes next token, if it is given (unquoted) keyword
+func (p *parser) keyword(words ...string) bool {
+	if p.done() || p.tokens[p.pos].quoted || !slices.Contains(words, strings.ToLower(p.peek())) {
+		return false
+	}
+	p.pos++
+	return true
+}
+
+func (p *parser) next(expected string) (token, error) {
+	if p.done() {
+		return token{}, fmt.Errorf("expected %s, but query ended", expected)
+	}
+	p.pos++
+	return p.tokens[p.pos-1], nil
+}
+
+func (p *parser) expect(text string) error {
+	if !p.keyword(text) {
+		if p.done() {
+			return fmt.Errorf("expected %s, but query ended", text)
+		}
+		return fmt.Errorf("expected %s, but got %s", text, p.peek())
+	}
+	return nil
+}
+
+// clauses start parts of query after conditions
+var clauses = []string{"group", "agg", "order", "limit"}
+
+// Parse turns text into query. Numbers without unit are in unit system's units (km or mi, m or ft, hours).
+func Parse(text string, system units.System) (*Query, error) {
+	tokens, err := tokenize(text)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens, system: system}
+	q := &Query{}
+	if !p.done() && !slices.Contains(clauses, strings.ToLower(p.peek())) {
+		for {
+			c, err := p.condition()
+			if err != nil {
+				return nil, err
+			}
+			q.Conditions = append(q.Conditions, c)
+			if !p.keyword("and") {
+				break
+			}
+		}
+	}
+	seen := map[string]bool{}
+	for !p.done() {
+		clause := strings.ToLower(p.peek())
+		if !p.keyword(clauses...) {
+			return nil, fmt.Errorf("expected and, %s, but got %s", strings.Join(clauses, ", "), p.peek())
+		}
+		if seen[clause] {
+			return nil, fmt.Errorf("%s is given twice", clause)
+		}
+		seen[clause] = true
+		if err := p.clause(clause, q); err != nil {
+			return nil, err
+		}
+	}
+	if len(q.GroupBy) > 0 && len(q.Aggregates) == 0 {
+		q.Aggregates = []Aggregate{{Function: "count"}}
+	}
+	return q, q.validate()
+}
+
+func (p *parser) clause(clause string, q *Query) error {
+	switch clause {
+	case "group":
+		if err := p.expect("by"); err != nil {
+			return err
+		}
+		for {
+			t, err := p.next("field")
+			if err != nil {
+				return err
+			}
+			name, f, err := fieldByName(t.text)
+			if err != nil {
+				return err
+			}
+			if len(f.group) == 0 {
+				return fmt.Errorf("can't group by %s", name)
+			}
+			q.GroupBy = append(q.GroupBy, name)
+			if !p.keyword(",") {
+				return nil
+			}
+		}
+	case "agg":
+		for {
+			a, err := p.aggregate()
+			if err != nil {
+				return err
+			}
+			q.Aggregates = append(q.Aggregates, a)
+			if !p.keyword(",") {
+				return nil
+			}
+		}
+	case "order":
+		if err := p.expect("by"); err != nil {
+			return err
+		}
+		if slices.Contains(functions, strings.ToLower(p.peek())) {
+			a, err := p.aggregate()
+			if err != nil {
+				return err
+			}
+			q.OrderBy = a.String()
+		} else {
+			t, err := p.next("field")
+			if err != nil {
+				return err
+			}
+			if q.OrderBy, _, err = fieldByName(t.text); err != nil {
+				return err
+			}
+		}
+		if p.keyword("desc") {
+			q.Descending = true
+		} else {
+			p.keyword("asc")
+		}
+	case "limit":
+		t, err := p.next("number")
+		if err != nil {
+			return err
+		}
+		if q.Limit, err = strconv.Atoi(t.text); err != nil || q.Limit < 1 {
+			return fmt.Errorf("limit must be positive number (not %s)", t.text)
+		}
+	}
+	return nil
+}
+
+func (p *parser) aggregate() (Aggregate, error) {
+	t, err := p.next("aggregate")
+	if err != nil {
+		return Aggregate{}, err
+	}
+	a := Aggregate{Function: strings.ToLower(t.text)}
+	if !slices.Contains(functions, a.Function) {
+		return a, fmt.Errorf("unknown aggregate: %s (valid aggregates are %v)", t.text, functions)
+	}
+	if a.Function == "count" {
+		if p.keyword("(") {
+			p.keyword("*")
+			return a, p.expect(")")
+		}
+		return a, nil
+	}
+	if err := p.expect("("); err != nil {
+		return a, err
+	}
+	if t, err = p.next("field"); err != nil {
+		return a, err
+	}
+	name, f, err := fieldByName(t.text)
+	if err != nil {
+		return a, err
+	}
+	if f.kind != kindMeasure {
+		return a, fmt.Errorf("can't calculate %s of %s", a.Function, name)
+	}
+	a.Field = name
+	return a, p.expect(")")
+}
+
+// condition parses `field op value` or `field [not] in (value, ...)`.
+func (p *parser) condition() (storage.Condition, error) {
+	c := storage.Condition{}
+	t, err := p.next("field")
+	if err != nil {
+		return c, err
+	}
+	name, f, err := fieldByName(t.text)
+	if err != nil {
+		return c, err
+	}
+	c.Column = f.column
+	switch {
+	case p.keyword("in"):
+		c.Operator = "in"
+	case p.keyword("not"):
+		if err := p.expect("in"); err != nil {
+			return c, err
+		}
+		c.Operator = "not in"
+	case p.keyword("=", "!=", "<", "<=", ">", ">="):
+		c.Operator = p.tokens[p.pos-1].text
+	case p.keyword("~"):
+		if f.kind != kindText {
+			return c, fmt.Errorf("~ works only with text fields (not %s)", name)
+		}
+		c.Operator = "like"
+	default:
+		return c, fmt.Errorf("expected operator after %s, but got %q", name, p.peek())
+	}
+	texts := []string{}
+	if c.Operator == "in" || c.Operator == "not in" {
+		if err := p.expect("("); err != nil {
+			return c, err
+		}
+		for {
+			t, err := p.next("value")
+			if err != nil {
+				return c, err
+			}
+			texts = append(texts, t.text)
+			if !p.keyword(",") {
+				break
+			}
+		}
+		if err := p.expect(")"); err != nil {
+			return c, err
+		}
+	} else {
+		t, err := p.next("value")
+		if err != nil {
+			return c, err
+		}
+		texts = append(texts, t.text)
+	}
+	for _, text := range texts {
+		value, err := p.value(f, text)
+		if err != nil {
+			return c, fmt.Errorf("%s: %w", name, err)
+		}
+		c.Values = append(c.Values, value)
+	}
+	return c, nil
+}
+
+// value converts text into type of field. Measures are converted into base units (meters, seconds).
+func (p *parser) value(f field, text string) (any, error) {
+	switch f.kind {
+	case kindInteger:
+		return strconv.Atoi(text)
+	case kindDate:
+		t, err := time.Parse(time.DateOnly, text)
+		if err != nil {
+			return nil, fmt.Errorf("date should be YYYY-MM-DD (not %s)", text)
+		}
+		return t.Format(time.DateOnly), nil
+	case kindMeasure:
+		return p.measure(f.dimension, text)
+	}
+	return text, nil
+}
+
+func (p *parser) measure(dimension, text string) (float64, error) {
+	if dimension == stats.DimensionDuration && strings.Contains(text, ":") {
+		seconds := 0.0
+		for part := range strings.SplitSeq(text, ":") {
+			value, err := strconv.ParseUint(part, 10, 32)
+			if err != nil {
+				return 0, fmt.Errorf("duration should be h:mm:ss or mm:ss (not %s)", text)
+			}
+			seconds = seconds*60 + float64(value)
+		}
+		return seconds, nil
+	}
+	end := strings.IndexFunc(text, func(r rune) bool { return !unicode.IsDigit(r) && r != '.' && r != '-' })
+	if end < 0 {
+		end = len(text)
+	}
+	value, err := strconv.ParseFloat(text[:end], 64)
+	if err != nil {
+		return 0, fmt.Errorf("expected number (not %s)", text)
+	}
+	unit := strings.ToLower(text[end:])
+	switch {
+	case unit == "":
+		return value / present.Convert(stats.Measure{Dimension: dimension}, p.system, 1), nil
+	case dimension == stats.DimensionDistance || dimension == stats.DimensionElevation:
+		if scale, ok := lengthUnits[unit]; ok {
+			return value * scale, nil
+		}
+	case dimension == stats.DimensionDuration:
+		if scale, ok := durationUnits[unit]; ok {
+			return value * scale, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown unit %s in %s", unit, text)
+}
+
+// validate checks that order by refers to something that query returns
+func (q *Query) validate() error {
+	if q.OrderBy == "" || q.IsList() {
+		return nil
+	}
+	if slices.Contains(q.GroupBy, q.OrderBy) ||
+		slices.ContainsFunc(q.Aggregates, func(a Aggregate) bool { return a.String() == q.OrderBy }) {
+		return nil
+	}
+	return errors.New("order by needs to be one of group by fields or aggregates: " + q.OrderBy)
+}
@@ -0,0 +1,74 @@
+package query //nolint:testpackage
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/jylitalo/mystats/pkg/units"
+)
+
+func TestParse(t *testing.T) {
+	values := []struct {
+		name   string
+		text   string
+		system units.System
+		query  string
+		err    bool
+	}{
+		{name: "empty", text: "", query: "{[] [] []  false 0}"},
+		{
+			name: "example",
+			text: "type in (Run, TrailRun) and distance > 20km and year >= 2022 group by month agg sum(elevation)",
+			query: "{[{Summary.Type in [Run TrailRun]} {Summary.Distance > [20000]} {Summary.Year >= [2022]}] " +
+				"[month] [sum(elevation)]  false 0}",
+		},
+		{
+			name:   "imperial",
+			text:   `name ~ "%Marathon%" and distance >= 26.2 and time < 3:30:00 order by date desc limit 5`,
+			system: units.Imperial,
+			query: "{[{Summary.Name like [%Marathon%]} {Summary.Distance >= [42164.8128]} " +
+				"{Summary.ElapsedTime < [12600]}] [] [] date true 5}",
+		},
+		{
+			name:  "count",
+			text:  "DATE != 2024-01-01 GROUP BY type, year ORDER BY count DESC",
+			query: "{[{date(Summary.StartDate) != [2024-01-01]}] [type year] [count] count true 0}",
+		},
+		{name: "unknown_field", text: "pace < 5", err: true},
+		{name: "unknown_unit", text: "distance > 5kg", err: true},
+		{name: "like_number", text: "distance ~ 5", err: true},
+		{name: "injection", text: "type = Run; drop table Summary", err: true},
+		{name: "order", text: "group by year agg sum(distance) order by sum(elevation)", err: true},
+	}
+	for _, value := range values {
+		t.Run(value.name, func(t *testing.T) {
+			system := value.system
+			if system == "" {
+				system = units.Metric
+			}
+			q, err := Parse(value.text, system)
+			if (err != nil) != value.err {
+				t.Fatalf("error mismatch: %v", err)
+			}
+			if err != nil {
+				return
+			}
+			if result := fmt.Sprintf("%v", *q); result != value.query {
+				t.Errorf("query mismatch:\n%s\nvs.\n%s", result, value.query)
+			}
+		})
+	}
+	q, err := Parse("month = 3 group by month agg avg(heartrate) order by month desc", units.Metric)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sql, _, err := q.statement().SQL()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "select Summary.Year,Summary.Month,avg(nullif(Summary.AverageHeartrate, 0)) from Summary " +
+		"where Summary.Month=? group by Summary.Year,Summary.Month order by Summary.Year desc,Summary.Month desc"
+	if sql != expected {
+		t.Errorf("sql mismatch:\n%s\nvs.\n%s", sql, expected)
+	}
+}
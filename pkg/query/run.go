@@ -0,0 +1,148 @@
+package query
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/jylitalo/mystats/pkg/output"
+	"github.com/jylitalo/mystats/pkg/present"
+	"github.com/jylitalo/mystats/pkg/stats"
+	"github.com/jylitalo/mystats/pkg/telemetry"
+	"github.com/jylitalo/mystats/pkg/units"
+	"github.com/jylitalo/mystats/storage"
+)
+
+// groupColumns are columns of group by without duplicates (e.g. group by year, month)
+func (q *Query) groupColumns() []column {
+	columns := []column{}
+	for _, name := range q.GroupBy {
+		for _, c := range fields[name].group {
+			if !slices.ContainsFunc(columns, func(other column) bool { return other.column == c.column }) {
+				columns = append(columns, c)
+			}
+		}
+	}
+	return columns
+}
+
+// orderBy sorts by field, aggregate or columns of group by
+func (q *Query) orderBy() []storage.Order {
+	direction := storage.Asc
+	if q.Descending {
+		direction = storage.Desc
+	}
+	switch {
+	case q.OrderBy == "":
+		return nil
+	case q.IsList():
+		return []storage.Order{direction(fields[q.OrderBy].column)}
+	}
+	for _, a := range q.Aggregates {
+		if a.String() == q.OrderBy {
+			return []storage.Order{direction(a.expression())}
+		}
+	}
+	order := []storage.Order{}
+	for _, c := range fields[q.OrderBy].group {
+		order = append(order, direction(c.column))
+	}
+	return order
+}
+
+// statement builds query with columns of group by and aggregates. Rows are ordered by group by by default.
+func (q *Query) statement() *storage.Query {
+	selected := []storage.Expr{}
+	group := []storage.Expr{}
+	order := q.orderBy()
+	for _, c := range q.groupColumns() {
+		selected = append(selected, c.column)
+		group = append(group, c.column)
+		if q.OrderBy == "" {
+			order = append(order, storage.Asc(c.column))
+		}
+	}
+	for _, a := range q.Aggregates {
+		selected = append(selected, a.expression())
+	}
+	return storage.Select(selected...).From(storage.SummaryTable).
+		Where(storage.WithConditions(q.Conditions...)).GroupBy(group...).OrderBy(order...).Limit(q.Limit)
+}
+
+// Table runs query and formats results in user's units. Query without group by or aggregates lists activities
+// like list command.
+func Table(ctx context.Context, db stats.Storage, q *Query, prefs units.Preferences) (output.Table, error) {
+	ctx, span := telemetry.NewSpan(ctx, "query.Table")
+	defer span.End()
+
+	if q.IsList() {
+		activities, err := stats.Activities(ctx, db, q.orderBy(), q.Limit, 0, storage.WithConditions(q.Conditions...))
+		if err != nil {
+			return output.Table{}, telemetry.Error(span, err)
+		}
+		headers, rows := present.List(activities, prefs)
+		return output.Table{Headers: headers, Rows: rows}, nil
+	}
+	columns := q.groupColumns()
+	headers := []string{}
+	for _, c := range columns {
+		headers = append(headers, c.header)
+	}
+	for _, a := range q.Aggregates {
+		headers = append(headers, a.String())
+	}
+	rows, err := db.Query(ctx, q.statement())
+	if err != nil {
+		return output.Table{}, telemetry.Error(span, fmt.Errorf("query caused: %w", err))
+	}
+	defer func() { _ = rows.Close() }()
+	t := output.Table{Headers: headers, Rows: [][]string{}}
+	for rows.Next() {
+		values := make([]any, len(headers))
+		pointers := make([]any, len(headers))
+		for idx := range values {
+			pointers[idx] = &values[idx]
+		}
+		if err := rows.Scan(pointers...); err != nil {
+			return t, telemetry.Error(span, err)
+		}
+		row := make([]string, len(values))
+		for idx, value := range values {
+			if idx < len(columns) {
+				row[idx] = groupValue(columns[idx], value)
+				continue
+			}
+			row[idx] = aggregateValue(q.Aggregates[idx-len(columns)], prefs.System, value)
+		}
+		t.Rows = append(t.Rows, row)
+	}
+	return t, telemetry.Error(span, rows.Err())
+}
+
+func groupValue(c column, value any) string {
+	if month, ok := value.(int64); ok && c == monthColumn {
+		return time.Month(month).String()
+	}
+	if b, ok := value.([]byte); ok {
+		return string(b)
+	}
+	return fmt.Sprint(value)
+}
+
+// aggregateValue formats value with unit of aggregated field. Missing values are empty.
+func aggregateValue(a Aggregate, system units.System, value any) string {
+	var number float64
+	switch v := value.(type) {
+	case int64:
+		number = float64(v)
+	case float64:
+		number = v
+	default:
+		return ""
+	}
+	if a.Field == "" {
+		return fmt.Sprintf("%.0f", number)
+	}
+	return present.Value(stats.Measure{Dimension: fields[a.Field].dimension}, system, number)
+}
@@ -0,0 +1,116 @@
+package season
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// Season defines custom year boundary for sports, e.g. skiing season that starts on 1st of November.
+type Season struct {
+	Name   string   `yaml:"name"`
+	Start  string   `yaml:"start"` // MM-DD
+	Sports []string `yaml:"sports"`
+}
+
+// Calendar is the default season from 1st of January to 31st of December
+var Calendar = Season{Name: "calendar", Start: "01-01"}
+
+// MonthDay returns month and day when season starts
+func (s Season) MonthDay() (int, int, error) {
+	if s.Start == "" {
+		return 1, 1, nil
+	}
+	t, err := time.Parse("01-02", s.Start)
+	if err != nil {
+		return 0, 0, fmt.Errorf("season %s has invalid start %s (expected MM-DD): %w", s.Name, s.Start, err)
+	}
+	return int(t.Month()), t.Day(), nil
+}
+
+// IsCalendar tells if season is same as calendar year
+func (s Season) IsCalendar() bool {
+	month, day, err := s.MonthDay()
+	return err == nil && month == 1 && day == 1
+}
+
+// Year returns season year for given date. Season year is the year when season started.
+func (s Season) Year(t time.Time) int {
+	month, day, _ := s.MonthDay()
+	if int(t.Month()) < month || (int(t.Month()) == month && t.Day() < day) {
+		return t.Year() - 1
+	}
+	return t.Year()
+}
+
+// First returns first day of season year
+func (s Season) First(year int, loc *time.Location) time.Time {
+	month, day, _ := s.MonthDay()
+	return time.Date(year, time.Month(month), day, 0, 0, 0, 0, loc)
+}
+
+// Label returns year as label like "2023/24" for seasons that span over two calendar years
+func (s Season) Label(year int) string {
+	if s.IsCalendar() {
+		return strconv.Itoa(year)
+	}
+	return fmt.Sprintf("%d/%02d", year, (year+1)%100)
+}
+
+// Labels returns labels for list of years
+func (s Season) Labels(years []int) []string {
+	labels := make([]string, len(years))
+	for idx, year := range years {
+		labels[idx] = s.Label(year)
+	}
+	return labels
+}
+
+// Order returns period numbers (months 1-12 or weeks 1-53) in the order they appear in season
+func (s Season) Order(period string) []int {
+	month, day, _ := s.MonthDay()
+	first, count := month, 12
+	if period == "week" {
+		_, first = time.Date(2001, time.Month(month), day, 0, 0, 0, 0, time.UTC).ISOWeek()
+		count = 53
+	}
+	order := make([]int, count)
+	for idx := range order {
+		order[idx] = (first-1+idx)%count + 1
+	}
+	return order
+}
+
+// For finds season that covers all given sports. Calendar is returned, if sports don't share season.
+func For(seasons []Season, sports []string) Season {
+	if len(sports) == 0 {
+		return Calendar
+	}
+	for _, s := range seasons {
+		covered := true
+		for _, sport := range sports {
+			if !slices.Contains(s.Sports, sport) {
+				covered = false
+				break
+			}
+		}
+		if covered {
+			return s
+		}
+	}
+	return Calendar
+}
+
+// Find returns season by name. "calendar" is always available.
+func Find(seasons []Season, name string) (Season, error) {
+	if name == Calendar.Name {
+		return Calendar, nil
+	}
+	for _, s := range seasons {
+		if s.Name == name {
+			return s, nil
+		}
+	}
+	return Calendar, fmt.Errorf("unknown season: %s", name)
+}
@@ -0,0 +1,49 @@
+package season //nolint:testpackage
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestYearAndLabel(t *testing.T) {
+	ski := Season{Name: "ski", Start: "11-01", Sports: []string{"NordicSki"}}
+	values := []struct {
+		date     time.Time
+		expected string
+	}{
+		{date: time.Date(2023, time.November, 1, 0, 0, 0, 0, time.UTC), expected: "2023/24"},
+		{date: time.Date(2024, time.March, 15, 0, 0, 0, 0, time.UTC), expected: "2023/24"},
+		{date: time.Date(2024, time.October, 31, 0, 0, 0, 0, time.UTC), expected: "2023/24"},
+		{date: time.Date(1999, time.December, 31, 0, 0, 0, 0, time.UTC), expected: "1999/00"},
+	}
+	for _, value := range values {
+		if got := ski.Label(ski.Year(value.date)); got != value.expected {
+			t.Errorf("label for %s got %s vs. expected %s", value.date, got, value.expected)
+		}
+	}
+	if got := Calendar.Label(Calendar.Year(values[1].date)); got != "2024" {
+		t.Errorf("calendar label got %s vs. expected 2024", got)
+	}
+}
+
+func TestOrder(t *testing.T) {
+	ski := Season{Name: "ski", Start: "11-01"}
+	expected := []int{11, 12, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+	if got := ski.Order("month"); !slices.Equal(got, expected) {
+		t.Errorf("order got %v vs. expected %v", got, expected)
+	}
+	if got := Calendar.Order("week"); got[0] != 1 || got[52] != 53 {
+		t.Errorf("calendar weeks got %v", got)
+	}
+}
+
+func TestFor(t *testing.T) {
+	seasons := []Season{{Name: "ski", Start: "11-01", Sports: []string{"NordicSki", "BackcountrySki"}}}
+	if got := For(seasons, []string{"NordicSki"}); got.Name != "ski" {
+		t.Errorf("expected ski season, got %s", got.Name)
+	}
+	if got := For(seasons, []string{"NordicSki", "Run"}); got.Name != Calendar.Name {
+		t.Errorf("expected calendar, got %s", got.Name)
+	}
+}
@@ -0,0 +1,35 @@
+package sport
+
+import (
+	"slices"
+)
+
+// Group combines Strava's activity types and sport types under one name,
+// e.g. running = Run + TrailRun + VirtualRun.
+type Group struct {
+	Name       string   `yaml:"name"`
+	Types      []string `yaml:"types"`       // matched against activity type (Run, Ride, ...)
+	SportTypes []string `yaml:"sport_types"` // matched against sport type (TrailRun, GravelRide, ...)
+}
+
+// Members returns types and sport types that belong to group
+func (g Group) Members() []string {
+	members := slices.Concat(g.Types, g.SportTypes)
+	slices.Sort(members)
+	return slices.Compact(members)
+}
+
+// Split separates group names from plain activity types
+func Split(groups []Group, names []string) ([]string, []Group) {
+	types := []string{}
+	selected := []Group{}
+	for _, name := range names {
+		idx := slices.IndexFunc(groups, func(g Group) bool { return g.Name == name })
+		if idx < 0 {
+			types = append(types, name)
+			continue
+		}
+		selected = append(selected, groups[idx])
+	}
+	return types, selected
+}
@@ -0,0 +1,42 @@
+package sport //nolint:testpackage
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSplit(t *testing.T) {
+	groups := []Group{
+		{Name: "running", Types: []string{"Run"}, SportTypes: []string{"TrailRun", "VirtualRun"}},
+		{Name: "cycling", Types: []string{"Ride"}},
+	}
+	values := []struct {
+		name   string
+		names  []string
+		types  []string
+		groups []string
+	}{
+		{name: "empty", names: nil, types: []string{}, groups: []string{}},
+		{name: "types", names: []string{"Run", "Hike"}, types: []string{"Run", "Hike"}, groups: []string{}},
+		{name: "mixed", names: []string{"Hike", "running"}, types: []string{"Hike"}, groups: []string{"running"}},
+	}
+	for _, value := range values {
+		t.Run(value.name, func(t *testing.T) {
+			types, selected := Split(groups, value.names)
+			names := []string{}
+			for _, g := range selected {
+				names = append(names, g.Name)
+			}
+			if !slices.Equal(types, value.types) || !slices.Equal(names, value.groups) {
+				t.Errorf("mismatch: %v vs. %v and %v vs. %v", types, value.types, names, value.groups)
+			}
+		})
+	}
+}
+
+func TestMembers(t *testing.T) {
+	g := Group{Name: "running", Types: []string{"Run"}, SportTypes: []string{"TrailRun", "Run"}}
+	if members := g.Members(); !slices.Equal(members, []string{"Run", "TrailRun"}) {
+		t.Errorf("unexpected members: %v", members)
+	}
+}
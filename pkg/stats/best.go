@@ -3,51 +3,123 @@ package stats
 import (
 	"context"
 	"fmt"
+	"math"
+	"time"
 
 	"github.com/jylitalo/mystats/pkg/telemetry"
 	"github.com/jylitalo/mystats/storage"
 )
 
-func Best(ctx context.Context, db Storage, distance string, limit int) ([]string, [][]string, error) {
+// BestEffort is best effort for distance (or duration) within activity
+type BestEffort struct {
+	StravaID         int64
+	Date             time.Time
+	Name             string
+	ElapsedTime      time.Duration
+	ActivityDistance float64 // meters
+	ActivityTime     time.Duration
+}
+
+func Best(
+	ctx context.Context, db Storage, distance string, limit int, filters ...storage.QueryOption,
+) ([]BestEffort, error) {
 	_, span := telemetry.NewSpan(ctx, "stats.Best")
 	defer span.End()
 
-	o := []string{storage.BestEffortTable + ".Movingtime", "Year", "Month", "Day"}
-	rows, err := db.Query(
-		ctx,
-		[]string{
-			"Year", "Month", "Day",
-			storage.SummaryTable + ".Name",
-			storage.SummaryTable + ".Distance",
-			storage.SummaryTable + ".Elapsedtime",
-			storage.BestEffortTable + ".Movingtime",
-			storage.BestEffortTable + ".Elapsedtime",
-			storage.SummaryTable + ".StravaID",
-		},
-		storage.WithName(distance),
-		storage.WithTable(storage.SummaryTable), storage.WithTable(storage.BestEffortTable),
-		storage.WithOrder(storage.OrderConfig{OrderBy: o, Limit: limit}),
-	)
+	// duration based efforts have fixed time, so efforts are ranked by pace instead of time
+	s, be := storage.Summary, storage.BestEffort
+	pace := storage.Ratio(be.MovingTime, be.Distance)
+	rows, err := db.Query(ctx, storage.Select(
+		s.Year, s.Month, s.Day, s.Name, s.Distance, s.ElapsedTime, be.ElapsedTime, s.StravaID,
+	).From(storage.SummaryTable).Join(storage.BestEffortTable, s.StravaID, be.StravaID).
+		Where(append([]storage.QueryOption{storage.WithName(distance)}, filters...)...).
+		OrderBy(storage.Asc(pace), storage.Asc(s.Year), storage.Asc(s.Month), storage.Asc(s.Day)).Limit(limit))
+	if err != nil {
+		return nil, telemetry.Error(span, fmt.Errorf("query caused: %w", err))
+	}
+	defer func() { _ = rows.Close() }()
+	results := []BestEffort{}
+	for rows.Next() {
+		var year, month, day, elapsedTime, totalTime int
+		var e BestEffort
+		err = rows.Scan(&year, &month, &day, &e.Name, &e.ActivityDistance, &totalTime, &elapsedTime, &e.StravaID)
+		if err != nil {
+			return nil, telemetry.Error(span, err)
+		}
+		e.Date = date(year, month, day)
+		e.ElapsedTime = time.Duration(elapsedTime) * time.Second
+		e.ActivityTime = time.Duration(totalTime) * time.Second
+		results = append(results, e)
+	}
+	return results, nil
+}
+
+// ProgressionRecord is effort that improved all-time or season best for the distance
+type ProgressionRecord struct {
+	StravaID    int64
+	Date        time.Time
+	Name        string
+	ElapsedTime time.Duration
+	Improvement time.Duration // compared to previous best, zero for first effort
+	AllTime     bool
+}
+
+// BestProgression lists in chronological order every effort that set new all-time or season best.
+// Seasons are calendar years, unless filters have WithSeason. Efforts without distance are skipped.
+func BestProgression(
+	ctx context.Context, db Storage, distance string, filters ...storage.QueryOption,
+) ([]ProgressionRecord, error) {
+	_, span := telemetry.NewSpan(ctx, "stats.BestProgression")
+	defer span.End()
+
+	s, be := storage.Summary, storage.BestEffort
+	rows, err := db.Query(ctx, storage.Select(
+		s.YearKey(filters...), s.Year, s.Month, s.Day, s.Name, be.ElapsedTime, be.Distance, s.StravaID,
+	).From(storage.SummaryTable).Join(storage.BestEffortTable, s.StravaID, be.StravaID).
+		Where(append([]storage.QueryOption{storage.WithName(distance)}, filters...)...).
+		OrderBy(storage.Asc(s.Year), storage.Asc(s.Month), storage.Asc(s.Day), storage.Asc(s.StravaID)))
 	if err != nil {
-		return nil, nil, telemetry.Error(span, fmt.Errorf("query caused: %w", err))
+		return nil, telemetry.Error(span, fmt.Errorf("query caused: %w", err))
 	}
 	defer func() { _ = rows.Close() }()
-	results := [][]string{}
+	results := []ProgressionRecord{}
+	// efforts are compared by pace, because duration based efforts have fixed time
+	allTime := 0.0
+	seasons := map[int]float64{}
+	improvement := func(previous, pace, distance float64) time.Duration {
+		return time.Duration(math.Round((previous-pace)*distance)) * time.Second
+	}
 	for rows.Next() {
-		var year, month, day, movingTime, elapsedTime, totalTime, stravaID int
+		var seasonYear, year, month, day, elapsedTime int
 		var distance float64
-		var name string
-		err = rows.Scan(&year, &month, &day, &name, &distance, &totalTime, &movingTime, &elapsedTime, &stravaID)
+		r := ProgressionRecord{}
+		err = rows.Scan(&seasonYear, &year, &month, &day, &r.Name, &elapsedTime, &distance, &r.StravaID)
 		if err != nil {
-			return nil, nil, telemetry.Error(span, err)
+			return nil, telemetry.Error(span, err)
+		}
+		if distance <= 0 {
+			continue
+		}
+		r.Date = date(year, month, day)
+		r.ElapsedTime = time.Duration(elapsedTime) * time.Second
+		pace := float64(elapsedTime) / distance
+		season, ok := seasons[seasonYear]
+		switch {
+		case allTime == 0 || pace < allTime:
+			if allTime > 0 {
+				r.Improvement = improvement(allTime, pace, distance)
+			}
+			r.AllTime = true
+			allTime = pace
+		case !ok || pace < season:
+			if ok {
+				r.Improvement = improvement(season, pace, distance)
+			}
+		default:
+			continue
 		}
-		results = append(results, []string{
-			fmt.Sprintf("%2d.%2d.%d", day, month, year), name,
-			fmt.Sprintf("%2d:%02d:%02d", elapsedTime/3600, elapsedTime/60%60, elapsedTime%60),
-			fmt.Sprintf("%.2f", distance/1000),
-			fmt.Sprintf("%2d:%02d:%02d", totalTime/3600, totalTime/60%60, totalTime%60),
-			fmt.Sprintf("https://strava.com/activities/%d", stravaID),
-		})
+		seasons[seasonYear] = pace
+		results = append(results, r)
 	}
-	return []string{"Date", distance, "Time", "Total (km)", "Total (time)", "Link"}, results, nil
+	return results, nil
 }
@@ -0,0 +1,149 @@
+package stats
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/jylitalo/mystats/pkg/telemetry"
+	"github.com/jylitalo/mystats/storage"
+)
+
+// StepsMeasure colours calendar by Garmin's daily steps instead of Strava activities
+const StepsMeasure = "steps"
+
+// CalendarResult has value of measure for every day of year and totals of months and weeks.
+// Weeks are calendar columns (Monday first), so the first week starts before January 1st,
+// unless year starts on Monday. Missing values are NaN.
+type CalendarResult struct {
+	Year    int
+	Measure Measure // only Name, Aggregation and Dimension for steps
+	Days    []float64
+	Weeks   []float64
+	Months  []float64
+}
+
+// FirstMonday is the first day of calendar column that has January 1st
+func FirstMonday(year int) time.Time {
+	first := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+	return first.AddDate(0, 0, -(int(first.Weekday())+6)%7)
+}
+
+func newCalendarResult(year int, m Measure) *CalendarResult {
+	days := time.Date(year, time.December, 31, 0, 0, 0, 0, time.UTC).YearDay()
+	weeks := (int(date(year, 1, 1).Sub(FirstMonday(year)).Hours()/24) + days + 6) / 7
+	result := &CalendarResult{
+		Year: year, Measure: m, Days: make([]float64, days), Weeks: make([]float64, weeks), Months: make([]float64, 12),
+	}
+	for _, values := range [][]float64{result.Days, result.Weeks, result.Months} {
+		for idx := range values {
+			values[idx] = math.NaN()
+		}
+	}
+	return result
+}
+
+// week is calendar column of date
+func (r *CalendarResult) week(t time.Time) int {
+	return int(t.Sub(FirstMonday(r.Year)).Hours()/24) / 7
+}
+
+// Calendar aggregates measure (see Measures) of every day, week and month in year.
+// StepsMeasure reads steps from DailySteps table and ignores sports, workouts and filters.
+func Calendar(
+	ctx context.Context, db Storage, measure string, year int, sports, workouts []string,
+	filters ...storage.QueryOption,
+) (*CalendarResult, error) {
+	_, span := telemetry.NewSpan(ctx, "stats.Calendar")
+	defer span.End()
+
+	if measure == StepsMeasure {
+		result, err := calendarSteps(ctx, db, year)
+		return result, telemetry.Error(span, err)
+	}
+	m, err := MeasureByName(measure)
+	if err != nil {
+		return nil, telemetry.Error(span, err)
+	}
+	result := newCalendarResult(year, m)
+	opts := []storage.QueryOption{
+		storage.WithSports(sports...),
+		storage.WithWorkouts(workouts...),
+		storage.WithYears(year),
+	}
+	opts = append(opts, filters...)
+	s := storage.Summary
+	// averages and ratios can't be summed, so every period has its own query
+	err = calendarQuery(ctx, db, storage.Select(s.Month, s.Day, m.Expression).From(storage.SummaryTable).
+		Where(opts...).GroupBy(s.Month, s.Day), func(keys []int, value float64) {
+		result.Days[date(year, keys[0], keys[1]).YearDay()-1] = value
+	})
+	if err != nil {
+		return nil, telemetry.Error(span, err)
+	}
+	err = calendarQuery(ctx, db, storage.Select(s.WeekYear, s.Week, m.Expression).From(storage.SummaryTable).
+		Where(opts...).GroupBy(s.WeekYear, s.Week), func(keys []int, value float64) {
+		// January 4th is always in ISO week 1, so this is a day within ISO week
+		day := date(keys[0], 1, 4).AddDate(0, 0, (keys[1]-1)*7)
+		if week := result.week(day); week >= 0 && week < len(result.Weeks) {
+			result.Weeks[week] = value
+		}
+	})
+	if err != nil {
+		return nil, telemetry.Error(span, err)
+	}
+	err = calendarQuery(ctx, db, storage.Select(s.Month, m.Expression).From(storage.SummaryTable).
+		Where(opts...).GroupBy(s.Month), func(keys []int, value float64) {
+		result.Months[keys[0]-1] = value
+	})
+	return result, telemetry.Error(span, err)
+}
+
+// calendarQuery scans integer keys and measure value from every row that has value
+func calendarQuery(ctx context.Context, db Storage, q *storage.Query, found func(keys []int, value float64)) error {
+	rows, err := db.Query(ctx, q)
+	if err != nil {
+		return fmt.Errorf("select caused: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	keys := make([]int, len(columns)-1)
+	for rows.Next() {
+		var value sql.NullFloat64
+		dest := make([]any, 0, len(columns))
+		for idx := range keys {
+			dest = append(dest, &keys[idx])
+		}
+		if err = rows.Scan(append(dest, &value)...); err != nil {
+			return err
+		}
+		if value.Valid {
+			found(keys, value.Float64)
+		}
+	}
+	return rows.Err()
+}
+
+// calendarSteps sums daily steps into weeks and months
+func calendarSteps(ctx context.Context, db Storage, year int) (*CalendarResult, error) {
+	result := newCalendarResult(year, Measure{Name: StepsMeasure, Aggregation: AggregationSum, Dimension: DimensionCount})
+	d := storage.DailySteps
+	err := calendarQuery(ctx, db, storage.Select(d.Month, d.Day, storage.Max(d.TotalSteps)).
+		From(storage.DailyStepsTable).Where(storage.WithYears(year)).GroupBy(d.Month, d.Day),
+		func(keys []int, value float64) {
+			day := date(year, keys[0], keys[1])
+			result.Days[day.YearDay()-1] = value
+			for _, total := range []*float64{&result.Weeks[result.week(day)], &result.Months[keys[0]-1]} {
+				if math.IsNaN(*total) {
+					*total = 0
+				}
+				*total += value
+			}
+		})
+	return result, err
+}
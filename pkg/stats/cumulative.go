@@ -0,0 +1,122 @@
+package stats
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jylitalo/mystats/pkg/season"
+	"github.com/jylitalo/mystats/pkg/telemetry"
+	"github.com/jylitalo/mystats/storage"
+)
+
+// CumulativeScan expects rows with (season) year, month, day and value.
+// Result has running sum for each day of (season) year.
+func CumulativeScan(rows *sql.Rows, years []int, s season.Season) (map[int][]float64, error) {
+	tz, _ := time.LoadLocation("Europe/Helsinki")
+	day1 := map[int]time.Time{}
+	// ys is map, where key is year and array has entry for each day of the year
+	ys := map[int][]float64{}
+	previous_y := map[int]float64{}
+	for _, year := range years {
+		day1[year] = s.First(year, tz).Add(6 * time.Hour)
+		ys[year] = []float64{}
+		previous_y[year] = 0
+	}
+	max_acts := 0
+	if rows == nil {
+		return ys, nil
+	}
+	for rows.Next() { // scan through database rows
+		var year, month, day int
+		var value float64
+		if err := rows.Scan(&year, &month, &day, &value); err != nil {
+			return ys, err
+		}
+		now := time.Date(year, time.Month(month), day, 6, 0, 0, 0, tz) // time when activity happened
+		// season continues on next calendar year
+		if now.Before(day1[year]) {
+			now = now.AddDate(1, 0, 0)
+		}
+		days := int(now.Sub(day1[year]).Hours()/24) + 1 // day within a year (1-365)
+		if days > 366 {
+			log.Fatalf(
+				"days got impossible number %d (year=%d, month=%d, day=%d, now=%#v, day1=%#v)",
+				days, year, month, day, now, day1[year],
+			)
+		}
+		yslen := len(ys[year])
+		for x := yslen; x < days-1; x++ { // fill the gaps on days that didn't have activities
+			ys[year] = append(ys[year], previous_y[year])
+		}
+		max_acts = max(max_acts, days)
+		previous_y[year] += value
+		ys[year] = append(ys[year], previous_y[year])
+	}
+	for _, year := range years { // fill the end of year
+		yslen := len(ys[year])
+		for x := yslen; x < max_acts; x++ {
+			ys[year] = append(ys[year], previous_y[year])
+		}
+	}
+	return ys, nil
+}
+
+// Cumulative calculates running sum of measure for each day of (season) year in measure's base units.
+// Only measures that can be summed (see CumulativeMeasures) are supported.
+func Cumulative(
+	ctx context.Context, db Storage, s season.Season, measure string, sports, workouts []string,
+	month, day int, years []int, filters ...storage.QueryOption,
+) (map[int][]float64, error) {
+	_, span := telemetry.NewSpan(ctx, "stats.Cumulative")
+	defer span.End()
+	summary := storage.Summary
+	var year storage.Expr = summary.Year
+	group := []storage.Expr{summary.Year, summary.Month, summary.Day}
+	if !s.IsCalendar() {
+		sm, sd, err := s.MonthDay()
+		if err != nil {
+			return nil, telemetry.Error(span, err)
+		}
+		year = summary.SeasonYear(sm, sd)
+		group = append([]storage.Expr{year}, group...)
+	}
+	opts := []storage.QueryOption{
+		storage.WithDayOfYear(day, month),
+		storage.WithSports(sports...),
+		storage.WithWorkouts(workouts...),
+		storage.WithYears(years...),
+	}
+	opts = append(opts, filters...)
+	m, err := MeasureByName(measure)
+	if err != nil {
+		return nil, telemetry.Error(span, err)
+	}
+	if m.Aggregation != AggregationSum {
+		return nil, telemetry.Error(span, fmt.Errorf("measure %s can't be plotted cumulatively", measure))
+	}
+	order := []storage.Order{}
+	for _, e := range group {
+		order = append(order, storage.Asc(e))
+	}
+	rows, err := db.Query(ctx, storage.Select(year, summary.Month, summary.Day, m.Expression).
+		From(storage.SummaryTable).Where(opts...).GroupBy(group...).OrderBy(order...))
+	if err != nil {
+		return nil, telemetry.Error(span, fmt.Errorf("select caused: %w", err))
+	}
+	defer func() {
+		if rows != nil {
+			if err := rows.Close(); err != nil {
+				_ = telemetry.Error(span, err)
+			}
+		}
+	}()
+	foundYears, err := db.QueryYears(ctx, storage.SummaryTable, opts...)
+	if err != nil {
+		return nil, telemetry.Error(span, err)
+	}
+	measured, err := CumulativeScan(rows, foundYears, s)
+	return measured, telemetry.Error(span, err)
+}
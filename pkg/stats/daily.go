@@ -0,0 +1,105 @@
+package stats
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jylitalo/mystats/pkg/season"
+	"github.com/jylitalo/mystats/pkg/telemetry"
+	"github.com/jylitalo/mystats/storage"
+)
+
+// Steps returns cumulative step counts for each day of year
+func Steps(ctx context.Context, db Storage, month, day int, years []int) (map[int][]float64, error) {
+	ctx, span := telemetry.NewSpan(ctx, "stats.Steps")
+	defer span.End()
+
+	years, rows, err := yearToDateQuery(ctx, db, day, month, years, storage.DailyStepsTable, storage.DailySteps.TotalSteps)
+	if err != nil {
+		return nil, telemetry.Error(span, err)
+	}
+	return CumulativeScan(rows, years, season.Calendar)
+}
+
+// HeartRate returns resting heart rate for each day of year
+func HeartRate(ctx context.Context, db Storage, month, day int, years []int) (map[int][]float64, error) {
+	ctx, span := telemetry.NewSpan(ctx, "stats.HeartRate")
+	defer span.End()
+
+	years, rows, err := yearToDateQuery(ctx, db, day, month, years, storage.HeartRateTable, storage.HeartRate.RestingHR)
+	if err != nil {
+		return nil, telemetry.Error(span, err)
+	}
+	return absoluteScan(rows, years)
+}
+
+func yearToDateQuery(
+	ctx context.Context, db Storage, day, month int, years []int, table storage.Table, column storage.Column,
+) ([]int, *sql.Rows, error) {
+	keys, err := table.DateKeys()
+	if err != nil {
+		return nil, nil, err
+	}
+	opts := []storage.QueryOption{
+		storage.WithDayOfYear(day, month),
+		storage.WithYears(years...),
+	}
+	rows, err := db.Query(ctx, storage.Select(keys.Year, keys.Month, keys.Day, storage.Max(column)).From(table).
+		Where(opts...).GroupBy(keys.Year, keys.Month, keys.Day).
+		OrderBy(storage.Asc(keys.Year), storage.Asc(keys.Month), storage.Asc(keys.Day)))
+	if err != nil {
+		return nil, nil, fmt.Errorf("select caused: %w", err)
+	}
+	foundYears, err := db.QueryYears(ctx, table, opts...)
+	return foundYears, rows, err
+}
+
+// absoluteScan tries to ensure that we have some value for each year-to-day for all years.
+func absoluteScan(rows *sql.Rows, years []int) (map[int][]float64, error) {
+	tz, _ := time.LoadLocation("Europe/Helsinki")
+	day1 := map[int]time.Time{}
+	// ys is map, where key is year and array has entry for each day of the year
+	ys := map[int][]float64{}
+	previous_y := map[int]float64{}
+	for _, year := range years {
+		day1[year] = time.Date(year, time.January, 1, 6, 0, 0, 0, tz)
+		ys[year] = []float64{}
+		previous_y[year] = 0
+	}
+	max_acts := 0
+	if rows == nil {
+		return ys, nil
+	}
+	for rows.Next() { // scan through database rows
+		var year, month, day int
+		var value float64
+		if err := rows.Scan(&year, &month, &day, &value); err != nil {
+			return ys, err
+		}
+		now := time.Date(year, time.Month(month), day, 6, 0, 0, 0, tz) // time when activity happened
+		days := int(now.Sub(day1[year]).Hours()/24) + 1                // day within a year (1-365)
+		if days > 366 {
+			log.Fatalf(
+				"days got impossible number %d (year=%d, month=%d, day=%d, now=%#v, day1=%#v)",
+				days, year, month, day, now, day1[year],
+			)
+		}
+		yslen := len(ys[year])
+		for x := yslen; x < days-1; x++ { // fill the gaps on days that didn't have activities
+			ys[year] = append(ys[year], previous_y[year])
+		}
+		ys[year] = append(ys[year], value)
+		max_acts = max(max_acts, len(ys[year]))
+		previous_y[year] = value
+	}
+	for _, year := range years { // fill the end of year
+		yslen := len(ys[year])
+		for x := yslen; x < max_acts; x++ {
+			ys[year] = append(ys[year], previous_y[year])
+		}
+	}
+	return ys, nil
+}
@@ -2,103 +2,303 @@ package stats
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"strconv"
+	"time"
+
+	strava "github.com/strava/go.strava"
 
 	"github.com/jylitalo/mystats/pkg/telemetry"
+	"github.com/jylitalo/mystats/pkg/units"
 	"github.com/jylitalo/mystats/storage"
 )
 
+// ErrNotFound is returned, when activity doesn't exist
+var ErrNotFound = errors.New("not found")
+
+// Activity is Strava activity as listed by List
+type Activity struct {
+	StravaID    int64
+	Date        time.Time
+	Name        string
+	Distance    float64 // meters
+	Elevation   float64 // meters
+	ElapsedTime time.Duration
+	Type        string
+	WorkoutType string
+}
+
+// ActivityDetails has everything that Summary table knows about activity
+type ActivityDetails struct {
+	Activity
+	SportType        string
+	MovingTime       time.Duration
+	AverageHeartrate float64 // zero, when activity doesn't have heart rate
+	Kilojoules       float64
+	Route            [][2]float64 // latitude and longitude, empty for indoor activities
+}
+
+// ActivitySplit is kilometer (or mile) split of activity
+type ActivitySplit struct {
+	Split         int
+	Distance      float64 // meters, last split is usually shorter
+	ElapsedTime   time.Duration
+	MovingTime    time.Duration
+	ElevationDiff float64 // meters
+	// AverageHeartrate is zero, when activity doesn't have heart rate
+	AverageHeartrate float64
+}
+
+// ActivityLap is lap of activity, e.g. interval or auto lap of device
+type ActivityLap struct {
+	Lap              int
+	Name             string
+	Distance         float64 // meters
+	ElapsedTime      time.Duration
+	MovingTime       time.Duration
+	Elevation        float64 // meters
+	AverageHeartrate float64
+}
+
+// ProfilePoint is altitude at distance from the start of activity (both in meters)
+type ProfilePoint struct {
+	Distance float64
+	Altitude float64
+}
+
+func date(year, month, day int) time.Time {
+	return time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+}
+
+// List lists activities in chronological order
 func List(
 	ctx context.Context, db Storage, sports, workouts []string,
-	years []int, limit int, name string,
-) ([]string, [][]string, error) {
-	_, span := telemetry.NewSpan(ctx, "stats.List")
-	defer span.End()
-
+	years []int, limit int, name string, filters ...storage.QueryOption,
+) ([]Activity, error) {
 	opts := []storage.QueryOption{
-		storage.WithTable(storage.SummaryTable),
 		storage.WithName(name),
+		storage.WithSports(sports...),
+		storage.WithWorkouts(workouts...),
+		storage.WithYears(years...),
 	}
-	opts = append(opts, storage.WithSports(sports...))
-	opts = append(opts, storage.WithWorkouts(workouts...))
-	opts = append(opts, storage.WithYears(years...))
-	o := []string{"Year", "Month", "Day", "StravaID"}
-	opts = append(opts, storage.WithOrder(storage.OrderConfig{GroupBy: o, OrderBy: o, Limit: limit}))
-	rows, err := db.Query(
-		ctx,
-		[]string{
-			"Year", "Month", "Day", "Name", "Distance", "Elevation", "Elapsedtime",
-			"Type", "Workouttype", "StravaID",
-		}, opts...,
-	)
+	return Activities(ctx, db, Chronological(), limit, 0, append(opts, filters...)...)
+}
+
+// Chronological orders activities from oldest to newest
+func Chronological() []storage.Order {
+	return []storage.Order{
+		storage.Asc(storage.Summary.Year), storage.Asc(storage.Summary.Month), storage.Asc(storage.Summary.Day),
+		storage.Asc(storage.Summary.StravaID),
+	}
+}
+
+// Activities lists activities in given order. Zero limit lists all activities after offset.
+func Activities(
+	ctx context.Context, db Storage, order []storage.Order, limit, offset int, filters ...storage.QueryOption,
+) ([]Activity, error) {
+	_, span := telemetry.NewSpan(ctx, "stats.Activities")
+	defer span.End()
+
+	s := storage.Summary
+	rows, err := db.Query(ctx, storage.Select(
+		s.Year, s.Month, s.Day, s.Name, s.Distance, s.Elevation, s.ElapsedTime, s.Type, s.WorkoutType, s.StravaID,
+	).From(storage.SummaryTable).Where(filters...).OrderBy(order...).Limit(limit).Offset(offset))
 	if rows == nil || err != nil {
-		return nil, nil, telemetry.Error(span, fmt.Errorf("query caused: %w", err))
+		return nil, telemetry.Error(span, fmt.Errorf("query caused: %w", err))
 	}
 	defer func() { _ = rows.Close() }()
-	results := [][]string{}
+	results := []Activity{}
 	for rows.Next() {
-		var year, month, day, elapsedTime, stravaID int
-		var distance, elevation float64
-		var name, typeName, workoutType string
-		err = rows.Scan(&year, &month, &day, &name, &distance, &elevation, &elapsedTime, &typeName, &workoutType, &stravaID)
+		var year, month, day, elapsedTime int
+		var a Activity
+		err = rows.Scan(
+			&year, &month, &day, &a.Name, &a.Distance, &a.Elevation, &elapsedTime, &a.Type, &a.WorkoutType, &a.StravaID,
+		)
 		if err != nil {
-			return nil, nil, err
+			return nil, err
 		}
-		results = append(results, []string{
-			strconv.Itoa(stravaID),
-			fmt.Sprintf("%2d.%2d.%d", day, month, year), name,
-			fmt.Sprintf("%.1f", distance/1000), fmt.Sprintf("%.0f", elevation),
-			fmt.Sprintf("%2d:%02d:%02d", elapsedTime/3600, elapsedTime/60%60, elapsedTime%60),
-			typeName, workoutType, fmt.Sprintf("https://strava.com/activities/%d", stravaID),
-		})
-	}
-	return []string{
-			"ID", "Date", "Name", "Distance (km)", "Elevation (m)", "Time",
-			"Type", "Workout Type", "Link",
-		},
-		results, nil
+		a.Date = date(year, month, day)
+		a.ElapsedTime = time.Duration(elapsedTime) * time.Second
+		results = append(results, a)
+	}
+	return results, nil
 }
 
-func Split(ctx context.Context, db Storage, id int64) ([]string, [][]string, error) {
-	var totalTime int
-	var ascent, descent float64
+// Details finds activity by Strava ID
+func Details(ctx context.Context, db Storage, id int64) (*ActivityDetails, error) {
+	_, span := telemetry.NewSpan(ctx, "stats.Details")
+	defer span.End()
 
+	s := storage.Summary
+	rows, err := db.Query(ctx, storage.Select(
+		s.Year, s.Month, s.Day, s.Name, s.Distance, s.Elevation, s.ElapsedTime, s.MovingTime,
+		s.Type, s.SportType, s.WorkoutType, s.AverageHeartrate, s.Kilojoules, s.SummaryPolyline,
+	).From(storage.SummaryTable).Where(storage.WithStravaID(id)))
+	if err != nil {
+		return nil, telemetry.Error(span, fmt.Errorf("query caused: %w", err))
+	}
+	defer func() { _ = rows.Close() }()
+	if !rows.Next() {
+		return nil, telemetry.Error(span, fmt.Errorf("%w: activity %d", ErrNotFound, id))
+	}
+	var year, month, day, elapsedTime, movingTime int
+	var polyline string
+	d := &ActivityDetails{Activity: Activity{StravaID: id}}
+	err = rows.Scan(
+		&year, &month, &day, &d.Name, &d.Distance, &d.Elevation, &elapsedTime, &movingTime,
+		&d.Type, &d.SportType, &d.WorkoutType, &d.AverageHeartrate, &d.Kilojoules, &polyline,
+	)
+	if err != nil {
+		return nil, telemetry.Error(span, err)
+	}
+	d.Date = date(year, month, day)
+	d.ElapsedTime = time.Duration(elapsedTime) * time.Second
+	d.MovingTime = time.Duration(movingTime) * time.Second
+	d.Route = strava.Polyline(polyline).Decode()
+	return d, nil
+}
+
+// Split lists splits of activity. Splits are per kilometer or per mile depending on unit system.
+func Split(ctx context.Context, db Storage, id int64) ([]ActivitySplit, error) {
 	_, span := telemetry.NewSpan(ctx, "stats.Split")
 	defer span.End()
 
-	rows, err := db.Query(
-		ctx,
-		[]string{"split", "elapsedtime", "elevationdiff"},
-		storage.WithTable(storage.SplitTable), storage.WithStravaID(id),
-	)
+	u := units.FromContext(ctx)
+	s := storage.Split
+	rows, err := db.Query(ctx, storage.Select(
+		s.Split, s.Distance, s.ElapsedTime, s.MovingTime, s.ElevationDiff, s.AverageHeartrate,
+	).From(storage.SplitTable).Where(storage.WithStravaID(id), storage.WithSplitUnits(string(u.System))).
+		OrderBy(storage.Asc(s.Split)))
+	if err != nil {
+		return nil, telemetry.Error(span, fmt.Errorf("query caused: %w", err))
+	}
+	defer func() { _ = rows.Close() }()
+	results := []ActivitySplit{}
+	for rows.Next() {
+		var elapsedTime, movingTime int
+		var s ActivitySplit
+		err = rows.Scan(&s.Split, &s.Distance, &elapsedTime, &movingTime, &s.ElevationDiff, &s.AverageHeartrate)
+		if err != nil {
+			return nil, err
+		}
+		s.ElapsedTime = time.Duration(elapsedTime) * time.Second
+		s.MovingTime = time.Duration(movingTime) * time.Second
+		results = append(results, s)
+	}
+	return results, nil
+}
+
+// Laps lists laps of activity
+func Laps(ctx context.Context, db Storage, id int64) ([]ActivityLap, error) {
+	_, span := telemetry.NewSpan(ctx, "stats.Laps")
+	defer span.End()
+
+	l := storage.Lap
+	rows, err := db.Query(ctx, storage.Select(
+		l.Lap, l.Name, l.Distance, l.ElapsedTime, l.MovingTime, l.Elevation, l.AverageHeartrate,
+	).From(storage.LapTable).Where(storage.WithStravaID(id)).OrderBy(storage.Asc(l.Lap)))
 	if err != nil {
-		return nil, nil, telemetry.Error(span, fmt.Errorf("query caused: %w", err))
+		return nil, telemetry.Error(span, fmt.Errorf("query caused: %w", err))
 	}
 	defer func() { _ = rows.Close() }()
-	results := [][]string{}
+	results := []ActivityLap{}
 	for rows.Next() {
-		var split, elapsedTime int
-		var elevationDiff float64
-		err = rows.Scan(&split, &elapsedTime, &elevationDiff)
+		var elapsedTime, movingTime int
+		var l ActivityLap
+		err = rows.Scan(&l.Lap, &l.Name, &l.Distance, &elapsedTime, &movingTime, &l.Elevation, &l.AverageHeartrate)
 		if err != nil {
-			return nil, nil, err
+			return nil, telemetry.Error(span, err)
 		}
-		totalTime += elapsedTime
-		if elevationDiff < 0 {
-			descent += -elevationDiff
-		} else {
-			ascent += elevationDiff
+		l.ElapsedTime = time.Duration(elapsedTime) * time.Second
+		l.MovingTime = time.Duration(movingTime) * time.Second
+		results = append(results, l)
+	}
+	return results, nil
+}
+
+// ElevationProfile returns altitude along the route of activity. Profile comes from altitude stream or,
+// if that hasn't been fetched, from elevation differences of kilometer splits. Latter starts from zero.
+func ElevationProfile(ctx context.Context, db Storage, id int64) ([]ProfilePoint, error) {
+	_, span := telemetry.NewSpan(ctx, "stats.ElevationProfile")
+	defer span.End()
+
+	p := storage.ElevationProfile
+	rows, err := db.Query(ctx, storage.Select(p.Distance, p.Altitude).From(storage.ElevationProfileTable).
+		Where(storage.WithStravaID(id)).OrderBy(storage.Asc(p.Distance)))
+	if err != nil {
+		return nil, telemetry.Error(span, fmt.Errorf("query caused: %w", err))
+	}
+	defer func() { _ = rows.Close() }()
+	points := []ProfilePoint{}
+	for rows.Next() {
+		var point ProfilePoint
+		if err = rows.Scan(&point.Distance, &point.Altitude); err != nil {
+			return nil, telemetry.Error(span, err)
 		}
-		results = append(results, []string{
-			strconv.Itoa(split),
-			fmt.Sprintf("%02d:%02d", elapsedTime/60%60, elapsedTime%60),
-			fmt.Sprintf("%.0f", elevationDiff),
-			fmt.Sprintf("%2d:%02d:%02d", totalTime/3600, totalTime/60%60, totalTime%60),
-			fmt.Sprintf("%.0f", ascent), fmt.Sprintf("%.0f", descent),
-		})
-	}
-	return []string{
-		"Split", "Time", "Elevation (m)", "Total Time", "Ascent (m)", "Descent (m)",
-	}, results, nil
+		points = append(points, point)
+	}
+	if len(points) > 0 {
+		return points, nil
+	}
+	s := storage.Split
+	rows, err = db.Query(ctx, storage.Select(s.Distance, s.ElevationDiff).From(storage.SplitTable).
+		Where(storage.WithStravaID(id), storage.WithSplitUnits(string(units.Metric))).OrderBy(storage.Asc(s.Split)))
+	if err != nil {
+		return nil, telemetry.Error(span, fmt.Errorf("query caused: %w", err))
+	}
+	defer func() { _ = rows.Close() }()
+	point := ProfilePoint{}
+	for rows.Next() {
+		if len(points) == 0 {
+			points = append(points, point)
+		}
+		var distance, diff float64
+		if err = rows.Scan(&distance, &diff); err != nil {
+			return nil, telemetry.Error(span, err)
+		}
+		point = ProfilePoint{Distance: point.Distance + distance, Altitude: point.Altitude + diff}
+		points = append(points, point)
+	}
+	return points, nil
+}
+
+// Adjacent finds the previous and the next activity of the same type. Zero means that there isn't one.
+func Adjacent(ctx context.Context, db Storage, id int64) (int64, int64, error) {
+	_, span := telemetry.NewSpan(ctx, "stats.Adjacent")
+	defer span.End()
+
+	s := storage.Summary
+	rows, err := db.Query(ctx, storage.Select(s.Type, s.StartDate).From(storage.SummaryTable).
+		Where(storage.WithStravaID(id)))
+	if err != nil {
+		return 0, 0, telemetry.Error(span, fmt.Errorf("query caused: %w", err))
+	}
+	defer func() { _ = rows.Close() }()
+	if !rows.Next() {
+		return 0, 0, telemetry.Error(span, fmt.Errorf("%w: activity %d", ErrNotFound, id))
+	}
+	var sportType, startDate string
+	if err = rows.Scan(&sportType, &startDate); err != nil {
+		return 0, 0, telemetry.Error(span, err)
+	}
+	adjacent := func(operator string, order storage.Order) (int64, error) {
+		rows, err := db.Query(ctx, storage.Select(s.StravaID).From(storage.SummaryTable).Where(
+			storage.WithConditions(
+				storage.Condition{Column: s.Type, Operator: "=", Values: []any{sportType}},
+				storage.Condition{Column: s.StartDate, Operator: operator, Values: []any{startDate}},
+			),
+		).OrderBy(order).Limit(1))
+		if err != nil {
+			return 0, fmt.Errorf("query caused: %w", err)
+		}
+		defer func() { _ = rows.Close() }()
+		var found int64
+		if rows.Next() {
+			err = rows.Scan(&found)
+		}
+		return found, err
+	}
+	previous, errP := adjacent("<", storage.Desc(s.StartDate))
+	next, errN := adjacent(">", storage.Asc(s.StartDate))
+	return previous, next, telemetry.Error(span, errors.Join(errP, errN))
 }
@@ -0,0 +1,112 @@
+package stats
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/jylitalo/mystats/storage"
+)
+
+// Aggregations tell how measure behaves when periods are combined
+const (
+	AggregationSum   = "sum"   // values can be added together (e.g. distance)
+	AggregationAvg   = "avg"   // average over activities (e.g. heart rate)
+	AggregationRatio = "ratio" // ratio of two sums (e.g. pace)
+)
+
+// Dimensions tell what measure's values are. Values are in base units (meters, seconds).
+const (
+	DimensionCount     = "count"
+	DimensionDistance  = "distance"  // meters
+	DimensionElevation = "elevation" // meters
+	DimensionDuration  = "duration"  // seconds
+	DimensionPace      = "pace"      // seconds per meter
+	DimensionSpeed     = "speed"     // meters per second
+	DimensionGradient  = "gradient"  // elevation meters per distance meter
+	DimensionHeartRate = "heartrate" // beats per minute
+	DimensionEnergy    = "energy"    // kilojoules
+)
+
+// Measure describes how value is calculated from Summary table
+type Measure struct {
+	Name       string
+	Expression storage.Expr // aggregate over group of activities. NULL means no value.
+	// Numerator and Denominator are sums of ratio, so that ratio of longer period can be combined from days
+	Numerator   storage.Expr
+	Denominator storage.Expr
+	Aggregation string
+	Dimension   string
+	Ascending   bool // smaller values are better (e.g. pace)
+}
+
+// ratio is measure, which divides sum of numerator with sum of denominator
+func ratio(numerator, denominator storage.Column, dimension string, ascending bool) Measure {
+	sumN, sumD := storage.Sum(numerator), storage.Sum(denominator)
+	return Measure{
+		Expression: storage.Ratio(sumN, sumD), Numerator: sumN, Denominator: sumD,
+		Aggregation: AggregationRatio, Dimension: dimension, Ascending: ascending,
+	}
+}
+
+var measures = map[string]Measure{
+	"count": {Expression: storage.Count(), Aggregation: AggregationSum, Dimension: DimensionCount},
+	"distance": {
+		Expression: storage.Sum(storage.Summary.Distance), Aggregation: AggregationSum, Dimension: DimensionDistance,
+	},
+	"elevation": {
+		Expression: storage.Sum(storage.Summary.Elevation), Aggregation: AggregationSum, Dimension: DimensionElevation,
+	},
+	"moving_time": {
+		Expression: storage.Sum(storage.Summary.MovingTime), Aggregation: AggregationSum, Dimension: DimensionDuration,
+	},
+	"elapsed_time": {
+		Expression: storage.Sum(storage.Summary.ElapsedTime), Aggregation: AggregationSum, Dimension: DimensionDuration,
+	},
+	"kilojoules": {
+		Expression:  storage.Sum(storage.NullIfZero(storage.Summary.Kilojoules)),
+		Aggregation: AggregationSum, Dimension: DimensionEnergy,
+	},
+	"pace":             ratio(storage.Summary.MovingTime, storage.Summary.Distance, DimensionPace, true),
+	"speed":            ratio(storage.Summary.Distance, storage.Summary.MovingTime, DimensionSpeed, false),
+	"elevation_per_km": ratio(storage.Summary.Elevation, storage.Summary.Distance, DimensionGradient, false),
+	"heartrate": {
+		// activities without heart rate have zero in database
+		Expression:  storage.Avg(storage.NullIfZero(storage.Summary.AverageHeartrate)),
+		Aggregation: AggregationAvg, Dimension: DimensionHeartRate,
+	},
+}
+
+// measureAliases keeps older measure names working
+var measureAliases = map[string]string{
+	"time": "elapsed_time",
+}
+
+// Measures lists names of all measures
+func Measures() []string {
+	return slices.Sorted(maps.Keys(measures))
+}
+
+// CumulativeMeasures lists measures that can be summed over days and periods
+func CumulativeMeasures() []string {
+	names := []string{}
+	for _, name := range Measures() {
+		if measures[name].Aggregation == AggregationSum {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// MeasureByName finds measure from registry
+func MeasureByName(name string) (Measure, error) {
+	if alias, ok := measureAliases[name]; ok {
+		name = alias
+	}
+	m, ok := measures[name]
+	if !ok {
+		return Measure{}, fmt.Errorf("unknown measure: %s (valid measures are %v)", name, Measures())
+	}
+	m.Name = name
+	return m, nil
+}
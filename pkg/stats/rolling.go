@@ -0,0 +1,188 @@
+package stats
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/jylitalo/mystats/pkg/telemetry"
+	"github.com/jylitalo/mystats/storage"
+)
+
+// garminMeasures are daily values from Garmin's tables. Strava's measures come from registry (see Measures).
+var garminMeasures = map[string]struct {
+	table   storage.Table
+	measure Measure
+}{
+	StepsMeasure: {table: storage.DailyStepsTable, measure: Measure{
+		Expression: storage.Sum(storage.DailySteps.TotalSteps), Aggregation: AggregationSum, Dimension: DimensionCount,
+	}},
+	"resting_hr": {table: storage.HeartRateTable, measure: Measure{
+		Expression: storage.Avg(storage.HeartRate.RestingHR), Aggregation: AggregationAvg, Dimension: DimensionHeartRate,
+	}},
+}
+
+// RollingMeasures lists measures that Rolling supports
+func RollingMeasures() []string {
+	return slices.Sorted(slices.Values(append(Measures(), slices.Collect(maps.Keys(garminMeasures))...)))
+}
+
+// RollingMeasure finds measure and its table for Rolling
+func RollingMeasure(name string) (Measure, storage.Table, error) {
+	if g, ok := garminMeasures[name]; ok {
+		g.measure.Name = name
+		return g.measure, g.table, nil
+	}
+	m, err := MeasureByName(name)
+	return m, storage.SummaryTable, err
+}
+
+// RollingWindows lists default window lengths in days
+func RollingWindows() []int {
+	return []int{7, 28, 90, 365}
+}
+
+// Rolling calculates rolling sum (or average) of measure over window days for each day.
+// Ratios (e.g. pace) divide sums of the whole window and averages (e.g. heart rate) are averaged over days
+// that have value, since summing them would be meaningless. Windows continue over year boundaries and run
+// until the end of years, date range or today. Result has value for each day of year (index 0 is 1st of
+// January), so that years can be compared with each other. Values are in base units (see RollingMeasure).
+// Sports, workouts and filters apply only to Strava measures.
+func Rolling(
+	ctx context.Context, db Storage, measure string, window int, average bool,
+	sports, workouts []string, years []int, filters ...storage.QueryOption,
+) ([]int, map[int][]float64, error) {
+	_, span := telemetry.NewSpan(ctx, "stats.Rolling")
+	defer span.End()
+
+	m, table, err := RollingMeasure(measure)
+	if err != nil {
+		return nil, nil, telemetry.Error(span, err)
+	}
+	if window < 1 {
+		return nil, nil, telemetry.Error(span, fmt.Errorf("window must be at least one day (not %d)", window))
+	}
+	daily, first, last, err := rollingDays(ctx, db, m, table, sports, workouts, filters...)
+	if err != nil {
+		return nil, nil, telemetry.Error(span, err)
+	}
+	results := map[int][]float64{}
+	if first.IsZero() {
+		return []int{}, results, nil
+	}
+	if end := rollingEnd(years, filters...); end.After(last) {
+		last = end
+	}
+	total := rollingDay{}
+	for t := first; !t.After(last); t = t.AddDate(0, 0, 1) {
+		if day, ok := daily[t]; ok {
+			total.value += day.value
+			total.weight += day.weight
+		}
+		if day, ok := daily[t.AddDate(0, 0, -window)]; ok {
+			total.value -= day.value
+			total.weight -= day.weight
+		}
+		value := total.value
+		switch {
+		case m.Aggregation != AggregationSum && total.weight > 0:
+			value = total.value / total.weight
+		case m.Aggregation != AggregationSum:
+			value = 0
+		case average:
+			value = total.value / float64(window)
+		}
+		results[t.Year()] = append(results[t.Year()], value)
+	}
+	found := []int{}
+	for year, values := range results {
+		if len(years) > 0 && !slices.Contains(years, year) {
+			delete(results, year)
+			continue
+		}
+		// pad the first year, so that index is always day of year
+		start := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+		if start.Before(first) {
+			padding := make([]float64, int(first.Sub(start).Hours()/24))
+			results[year] = append(padding, values...)
+		}
+		found = append(found, year)
+	}
+	slices.Sort(found)
+	return found, results, nil
+}
+
+// rollingDay is value of day and its weight. Weight is denominator of ratio or one for day with average.
+type rollingDay struct {
+	value  float64
+	weight float64
+}
+
+// rollingDays queries value of every day that has one and the first and the last of those days
+func rollingDays(
+	ctx context.Context, db Storage, m Measure, table storage.Table, sports, workouts []string,
+	filters ...storage.QueryOption,
+) (map[time.Time]rollingDay, time.Time, time.Time, error) {
+	var first, last time.Time
+	keys, err := table.DateKeys()
+	if err != nil {
+		return nil, first, last, err
+	}
+	fields := []storage.Expr{keys.Year, keys.Month, keys.Day, m.Expression}
+	if m.Aggregation == AggregationRatio {
+		fields = []storage.Expr{keys.Year, keys.Month, keys.Day, m.Numerator, m.Denominator}
+	}
+	q := storage.Select(fields...).From(table).
+		GroupBy(keys.Year, keys.Month, keys.Day).
+		OrderBy(storage.Asc(keys.Year), storage.Asc(keys.Month), storage.Asc(keys.Day))
+	if table == storage.SummaryTable {
+		q.Where(storage.WithSports(sports...), storage.WithWorkouts(workouts...)).Where(filters...)
+	}
+	rows, err := db.Query(ctx, q)
+	if err != nil {
+		return nil, first, last, fmt.Errorf("select caused: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+	daily := map[time.Time]rollingDay{}
+	for rows.Next() {
+		var year, month, day int
+		var value, weight sql.NullFloat64
+		dest := []any{&year, &month, &day, &value}
+		if m.Aggregation == AggregationRatio {
+			dest = append(dest, &weight)
+		} else {
+			weight = sql.NullFloat64{Float64: 1, Valid: true}
+		}
+		if err = rows.Scan(dest...); err != nil {
+			return nil, first, last, err
+		}
+		if !value.Valid || !weight.Valid || weight.Float64 == 0 {
+			continue
+		}
+		t := time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+		if first.IsZero() {
+			first = t
+		}
+		last = t
+		daily[t] = rollingDay{value: value.Float64, weight: weight.Float64}
+	}
+	return daily, first, last, rows.Err()
+}
+
+// rollingEnd is the last day of years or date range, but not after today
+func rollingEnd(years []int, filters ...storage.QueryOption) time.Time {
+	now := time.Now().UTC()
+	end := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	if len(years) > 0 {
+		if last := time.Date(slices.Max(years), time.December, 31, 0, 0, 0, 0, time.UTC); last.Before(end) {
+			end = last
+		}
+	}
+	if to := storage.NewQueryConfig(filters...).To; !to.IsZero() && to.Before(end) {
+		end = time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, time.UTC)
+	}
+	return end
+}
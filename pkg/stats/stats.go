@@ -4,29 +4,49 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
-	"slices"
-	"strings"
 
 	"github.com/jylitalo/mystats/pkg/telemetry"
 	"github.com/jylitalo/mystats/storage"
 )
 
 type Storage interface {
-	QueryYears(ctx context.Context, opts ...storage.QueryOption) ([]int, error)
-	Query(ctx context.Context, fields []string, opts ...storage.QueryOption) (*sql.Rows, error)
+	QueryYears(ctx context.Context, t storage.Table, opts ...storage.QueryOption) ([]int, error)
+	Query(ctx context.Context, q *storage.Query) (*sql.Rows, error)
 }
 
+// periods are columns of periods in Summary table
+var periods = map[string]storage.Column{
+	"month": storage.Summary.Month,
+	"week":  storage.Summary.Week,
+	"day":   storage.Summary.Day,
+}
+
+// StatsResult has measure for each period (row) and year (column) in measure's base units.
+// Values are nil, when period doesn't have any activities.
+type StatsResult struct {
+	Measure Measure
+	Period  string
+	Years   []int
+	Values  [][]*float64
+	Totals  []*float64
+}
+
+// Stats aggregates measure (see Measures) by period (row) and year (column).
+// Rows are indexed by period number, so WithSeason filter only changes which year row belongs to.
 func Stats(
 	ctx context.Context, db Storage, measure, period string, sports, workouts []string,
-	month, day int, years []int,
-) ([]int, [][]string, []string, error) {
+	month, day int, years []int, filters ...storage.QueryOption,
+) (*StatsResult, error) {
 	_, span := telemetry.NewSpan(ctx, "stats.Stats")
 	defer span.End()
 
+	m, err := MeasureByName(measure)
+	if err != nil {
+		return nil, telemetry.Error(span, err)
+	}
 	if years == nil {
-		var err error
-		if years, err = db.QueryYears(ctx); err != nil {
-			return nil, nil, nil, telemetry.Error(span, err)
+		if years, err = db.QueryYears(ctx, storage.SummaryTable, filters...); err != nil {
+			return nil, telemetry.Error(span, err)
 		}
 	}
 	yearIndex := map[int]int{}
@@ -38,61 +58,80 @@ func Stats(
 		"week":  53,
 	}
 	if _, ok := inYear[period]; !ok {
-		return nil, nil, nil, telemetry.Error(span, fmt.Errorf("unknown period: %s", period))
+		return nil, telemetry.Error(span, fmt.Errorf("unknown period: %s", period))
 	}
-	results := make([][]string, inYear[period])
-	columns := len(years)
-	for idx := range results {
-		results[idx] = slices.Repeat([]string{"    "}, columns) // helps CSV formatting
+	result := &StatsResult{
+		Measure: m,
+		Period:  period,
+		Years:   years,
+		Values:  make([][]*float64, inYear[period]),
+		Totals:  make([]*float64, len(years)),
+	}
+	for idx := range result.Values {
+		result.Values[idx] = make([]*float64, len(years))
 	}
-	measure = strings.ReplaceAll(measure, "(time)", "(elapsedtime)")
-	o := []string{period, "Year"}
 	opts := []storage.QueryOption{
-		storage.WithTable(storage.SummaryTable),
 		storage.WithDayOfYear(day, month),
-		storage.WithOrder(storage.OrderConfig{GroupBy: o, OrderBy: o}),
 		storage.WithSports(sports...),
 		storage.WithWorkouts(workouts...),
 		storage.WithYears(years...),
 	}
-	yearField := "Year"
-	if period == "week" {
-		yearField = "WeekYear as Year"
+	opts = append(opts, filters...)
+	summary := storage.Summary
+	column := periods[period]
+	year := summary.YearKey(filters...)
+	if period == "week" && !storage.NewQueryConfig(filters...).HasSeason() {
+		year = summary.WeekYear
 	}
-	rows, err := db.Query(ctx, []string{yearField, period, measure}, opts...)
+	rows, err := db.Query(ctx, storage.Select(year, column, m.Expression).From(storage.SummaryTable).
+		Where(opts...).GroupBy(column, year).OrderBy(storage.Asc(column), storage.Asc(year)))
 	if err != nil {
-		return nil, nil, nil, telemetry.Error(span, fmt.Errorf("select caused: %w", err))
+		return nil, telemetry.Error(span, fmt.Errorf("select caused: %w", err))
 	}
 	defer func() { _ = rows.Close() }()
-	totalsAbs := make([]float64, len(years))
-	modifier, unit := getModifier(measure)
 	for rows.Next() {
 		var year, periodValue int
-		var measureValue float64
+		var measureValue sql.NullFloat64
 		if err = rows.Scan(&year, &periodValue, &measureValue); err != nil {
-			return nil, nil, nil, err
+			return nil, telemetry.Error(span, err)
+		}
+		if !measureValue.Valid {
+			continue
 		}
-		totalsAbs[yearIndex[year]] += measureValue / modifier
-		results[periodValue-1][yearIndex[year]] = fmt.Sprintf(unit, measureValue/modifier)
+		idx := yearIndex[year]
+		value := measureValue.Float64
+		result.Values[periodValue-1][idx] = &value
+		if result.Totals[idx] == nil {
+			result.Totals[idx] = new(float64)
+		}
+		*result.Totals[idx] += value
 	}
-	totals := make([]string, len(years))
-	for idx := range totalsAbs {
-		totals[idx] = fmt.Sprintf(unit, totalsAbs[idx])
+	if m.Aggregation != AggregationSum {
+		// averages and ratios can't be summed, so totals need their own query
+		q := storage.Select(year, m.Expression).From(storage.SummaryTable).Where(opts...).GroupBy(year)
+		if result.Totals, err = queryTotals(ctx, db, q, yearIndex); err != nil {
+			return nil, telemetry.Error(span, err)
+		}
 	}
-	return years, results, totals, nil
+	return result, nil
 }
 
-func getModifier(measure string) (float64, string) {
-	modifier := float64(1)
-	unit := "%4.0fm"
-	switch {
-	case strings.Contains(measure, "count"):
-	case strings.Contains(measure, "distance"):
-		modifier = 1000
-		unit = "%4.1fkm"
-	case strings.Contains(measure, "time"):
-		modifier = 3600
-		unit = "%4.1fh"
+func queryTotals(ctx context.Context, db Storage, q *storage.Query, yearIndex map[int]int) ([]*float64, error) {
+	rows, err := db.Query(ctx, q)
+	if err != nil {
+		return nil, fmt.Errorf("select caused: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+	totals := make([]*float64, len(yearIndex))
+	for rows.Next() {
+		var year int
+		var value sql.NullFloat64
+		if err = rows.Scan(&year, &value); err != nil {
+			return nil, err
+		}
+		if idx, ok := yearIndex[year]; ok && value.Valid {
+			totals[idx] = &value.Float64
+		}
 	}
-	return modifier, unit
+	return totals, rows.Err()
 }
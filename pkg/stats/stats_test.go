@@ -0,0 +1,306 @@
+package stats //nolint:testpackage
+
+import (
+	"context"
+	"math"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jylitalo/mystats/pkg/telemetry"
+	"github.com/jylitalo/mystats/storage"
+)
+
+// testContext has tracer, which writes spans into temporary directory
+func testContext(t *testing.T) context.Context {
+	t.Helper()
+	t.Chdir(t.TempDir())
+	ctx, _, err := telemetry.Setup(context.TODO(), "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ctx
+}
+
+// activity is Summary record of date (YYYY-MM-DD) with distance in meters and times in seconds
+func activity(
+	date string, id int64, sport string, distance float64, moving, elapsed int, hr float64,
+) storage.SummaryRecord {
+	t, _ := time.Parse(time.DateOnly, date)
+	weekYear, week := t.ISOWeek()
+	return storage.SummaryRecord{
+		StartDate: t, Year: t.Year(), Month: int(t.Month()), Day: t.Day(), WeekYear: weekYear, Week: week,
+		StravaID: id, Name: sport + " " + date, Type: sport, SportType: sport,
+		Distance: distance, MovingTime: moving, ElapsedTime: elapsed, AverageHeartrate: hr,
+	}
+}
+
+// newTestDB is in-memory database with activities
+func newTestDB(ctx context.Context, t *testing.T, activities ...storage.SummaryRecord) *storage.Sqlite3 {
+	t.Helper()
+	db, err := storage.NewMemory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	if err = db.InsertSummary(ctx, activities); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func equalFloats(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestMeasureByName(t *testing.T) {
+	values := []struct {
+		name        string
+		input       string
+		measure     string
+		aggregation string
+		dimension   string
+		err         string
+	}{
+		{
+			name: "sum", input: "distance", measure: "distance",
+			aggregation: AggregationSum, dimension: DimensionDistance,
+		},
+		{
+			name: "alias", input: "time", measure: "elapsed_time",
+			aggregation: AggregationSum, dimension: DimensionDuration,
+		},
+		{name: "ratio", input: "pace", measure: "pace", aggregation: AggregationRatio, dimension: DimensionPace},
+		{
+			name: "avg", input: "heartrate", measure: "heartrate",
+			aggregation: AggregationAvg, dimension: DimensionHeartRate,
+		},
+		{name: "unknown", input: "unknown", err: "unknown measure: unknown (valid measures are [count distance"},
+	}
+	for _, value := range values {
+		t.Run(value.name, func(t *testing.T) {
+			m, err := MeasureByName(value.input)
+			if value.err != "" {
+				if err == nil || !strings.Contains(err.Error(), value.err) {
+					t.Errorf("error mismatch: %v vs. %s", err, value.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if m.Name != value.measure || m.Aggregation != value.aggregation || m.Dimension != value.dimension {
+				t.Errorf("measure mismatch: %#v", m)
+			}
+		})
+	}
+	if cumulative := CumulativeMeasures(); slices.Contains(cumulative, "pace") ||
+		slices.Contains(cumulative, "heartrate") || !slices.Contains(cumulative, "distance") {
+		t.Errorf("cumulative measures can only be sums: %v", cumulative)
+	}
+}
+
+func TestStats(t *testing.T) {
+	ctx := testContext(t)
+	db := newTestDB(ctx, t,
+		activity("2023-03-10", 1, "Run", 10000, 3000, 3100, 150),
+		activity("2023-03-20", 2, "Run", 5000, 1800, 1800, 0), // without heart rate
+		activity("2024-01-15", 3, "Run", 20000, 7200, 7500, 140),
+		activity("2024-02-01", 4, "Ride", 30000, 3600, 3600, 120),
+	)
+	values := []struct {
+		name    string
+		measure string
+		jan2024 float64
+		feb2024 float64
+		totals  []float64 // 2023 and 2024
+	}{
+		{name: "count", measure: "count", jan2024: 1, feb2024: 1, totals: []float64{2, 2}},
+		{name: "sum", measure: "distance", jan2024: 20000, feb2024: 30000, totals: []float64{15000, 50000}},
+		{name: "alias", measure: "time", jan2024: 7500, feb2024: 3600, totals: []float64{4900, 11100}},
+		// totals are ratios of yearly sums, not sums of monthly ratios
+		{name: "ratio", measure: "pace", jan2024: 0.36, feb2024: 0.12, totals: []float64{0.32, 0.216}},
+		// zero heart rate is missing value
+		{name: "avg", measure: "heartrate", jan2024: 140, feb2024: 120, totals: []float64{150, 130}},
+	}
+	for _, value := range values {
+		t.Run(value.name, func(t *testing.T) {
+			result, err := Stats(ctx, db, value.measure, "month", nil, nil, 12, 31, []int{2023, 2024})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := *result.Values[0][1]; !equalFloats(got, value.jan2024) {
+				t.Errorf("January 2024 mismatch: %f vs. %f", got, value.jan2024)
+			}
+			if got := *result.Values[1][1]; !equalFloats(got, value.feb2024) {
+				t.Errorf("February 2024 mismatch: %f vs. %f", got, value.feb2024)
+			}
+			if result.Values[0][0] != nil {
+				t.Errorf("January 2023 should be missing, not %f", *result.Values[0][0])
+			}
+			for idx, total := range value.totals {
+				if result.Totals[idx] == nil || !equalFloats(*result.Totals[idx], total) {
+					t.Errorf("total of %d mismatch: %v vs. %f", result.Years[idx], result.Totals[idx], total)
+				}
+			}
+		})
+	}
+	if _, err := Stats(ctx, db, "unknown", "month", nil, nil, 12, 31, nil); err == nil {
+		t.Error("unknown measure should fail")
+	}
+}
+
+func TestRolling(t *testing.T) { //nolint:funlen
+	ctx := testContext(t)
+	db := newTestDB(ctx, t,
+		activity("2023-01-01", 1, "Run", 10000, 3000, 3000, 150),
+		activity("2023-01-03", 2, "Run", 30000, 12000, 12000, 130),
+		activity("2023-01-05", 3, "Ride", 5000, 1000, 1000, 0), // without heart rate
+	)
+	values := []struct {
+		name     string
+		measure  string
+		window   int
+		average  bool
+		filters  []storage.QueryOption
+		days     int             // length of 2023
+		expected map[int]float64 // by day of year (0 is 1st of January)
+	}{
+		{
+			// gap days keep values of window until they drop out
+			name: "sum", measure: "distance", window: 7, days: 365,
+			expected: map[int]float64{0: 10000, 1: 10000, 2: 40000, 6: 45000, 7: 35000, 9: 5000, 11: 0},
+		},
+		{
+			name: "average", measure: "distance", window: 7, average: true, days: 365,
+			expected: map[int]float64{0: 10000.0 / 7, 2: 40000.0 / 7},
+		},
+		{
+			// ratio of window's sums, daily ratios 0.3 and 0.4 would average to 0.35
+			name: "ratio", measure: "pace", window: 7, days: 365,
+			expected: map[int]float64{0: 0.3, 1: 0.3, 2: 15000.0 / 40000, 4: 16000.0 / 45000, 11: 0},
+		},
+		{
+			// average ignores days without value (even when they have activities)
+			name: "avg", measure: "heartrate", window: 7, average: true, days: 365,
+			expected: map[int]float64{0: 150, 1: 150, 2: 140, 4: 140, 9: 0},
+		},
+		{
+			name: "date_range", measure: "count", window: 3, days: 20,
+			filters:  []storage.QueryOption{storage.WithDateRange(time.Time{}, date(2023, 1, 20))},
+			expected: map[int]float64{0: 1, 2: 2, 4: 2, 5: 1, 19: 0},
+		},
+	}
+	for _, value := range values {
+		t.Run(value.name, func(t *testing.T) {
+			years, results, err := Rolling(
+				ctx, db, value.measure, value.window, value.average, nil, nil, []int{2023}, value.filters...,
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if slices.Compare(years, []int{2023}) != 0 || len(results[2023]) != value.days {
+				t.Fatalf("expected %d days of 2023, got %d days of %v", value.days, len(results[2023]), years)
+			}
+			for day, expected := range value.expected {
+				if got := results[2023][day]; !equalFloats(got, expected) {
+					t.Errorf("day %d mismatch: %f vs. %f", day, got, expected)
+				}
+			}
+		})
+	}
+	if _, _, err := Rolling(ctx, db, "unknown", 7, false, nil, nil, nil); err == nil {
+		t.Error("unknown measure should fail")
+	}
+	if _, _, err := Rolling(ctx, db, "distance", 0, false, nil, nil, nil); err == nil {
+		t.Error("empty window should fail")
+	}
+}
+
+func TestRollingToday(t *testing.T) {
+	ctx := testContext(t)
+	now := time.Now().UTC()
+	recent := now.AddDate(0, 0, -3)
+	db := newTestDB(ctx, t, activity(recent.Format(time.DateOnly), 1, "Run", 10000, 3000, 3000, 150))
+	years, results, err := Rolling(ctx, db, "distance", 7, false, nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// windows continue after the last activity until today
+	if !slices.Contains(years, now.Year()) || len(results[now.Year()]) != now.YearDay() {
+		t.Fatalf("expected %d days of %d, got %d days of %v",
+			now.YearDay(), now.Year(), len(results[now.Year()]), years)
+	}
+	if got := results[now.Year()][now.YearDay()-1]; got != 10000 {
+		t.Errorf("today should have the recent activity in window, not %f", got)
+	}
+}
+
+func TestBestProgression(t *testing.T) {
+	ctx := testContext(t)
+	dates := []string{
+		"2022-05-01", "2022-06-01", "2022-07-01", "2022-08-01", "2023-03-01", "2023-04-01", "2023-05-01", "2023-12-01",
+	}
+	activities := []storage.SummaryRecord{}
+	for idx, date := range dates {
+		activities = append(activities, activity(date, int64(idx+1), "Run", 10000, 3000, 3000, 0))
+	}
+	db := newTestDB(ctx, t, activities...)
+	efforts := []storage.BestEffortRecord{}
+	for idx, seconds := range []int{1500, 1450, 1500, 1450, 1480, 1470, 100, 1475} {
+		distance := 5000
+		if idx == 6 {
+			distance = 0 // pace can't be compared
+		}
+		efforts = append(efforts, storage.BestEffortRecord{
+			StravaID: int64(idx + 1), Name: "5k", ElapsedTime: seconds, MovingTime: seconds, Distance: distance,
+		})
+	}
+	if err := db.InsertBestEffort(ctx, efforts); err != nil {
+		t.Fatal(err)
+	}
+	type record struct {
+		id          int64
+		allTime     bool
+		improvement time.Duration
+	}
+	values := []struct {
+		name     string
+		filters  []storage.QueryOption
+		expected []record
+	}{
+		{
+			// 3 is slower and 4 ties, 5 is season best after all-time best of earlier year
+			name: "calendar",
+			expected: []record{
+				{id: 1, allTime: true}, {id: 2, allTime: true, improvement: 50 * time.Second},
+				{id: 5}, {id: 6, improvement: 10 * time.Second},
+			},
+		},
+		{
+			// December starts new season
+			name:    "season",
+			filters: []storage.QueryOption{storage.WithSeason(11, 1)},
+			expected: []record{
+				{id: 1, allTime: true}, {id: 2, allTime: true, improvement: 50 * time.Second},
+				{id: 5}, {id: 6, improvement: 10 * time.Second}, {id: 8},
+			},
+		},
+	}
+	for _, value := range values {
+		t.Run(value.name, func(t *testing.T) {
+			records, err := BestProgression(ctx, db, "5k", value.filters...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := []record{}
+			for _, r := range records {
+				got = append(got, record{id: r.StravaID, allTime: r.AllTime, improvement: r.Improvement})
+			}
+			if !slices.Equal(got, value.expected) {
+				t.Errorf("records mismatch: %v vs. %v", got, value.expected)
+			}
+		})
+	}
+}
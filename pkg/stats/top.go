@@ -2,67 +2,73 @@ package stats
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
-	"slices"
-	"strconv"
-	"time"
 
 	"github.com/jylitalo/mystats/pkg/telemetry"
 	"github.com/jylitalo/mystats/storage"
 )
 
+// TopPeriod is week, month or day with its measured value in measure's base units
+type TopPeriod struct {
+	Year   int
+	Period int
+	Value  float64
+}
+
+// TopResult has periods in order from best to worst
+type TopResult struct {
+	Measure Measure
+	Period  string
+	Periods []TopPeriod
+}
+
 func Top(
 	ctx context.Context, db Storage, measure, period string, sports, workouts []string, limit int, years []int,
-) ([]string, [][]string, error) {
+	filters ...storage.QueryOption,
+) (*TopResult, error) {
 	_, span := telemetry.NewSpan(ctx, "stats.Top")
 	defer span.End()
 
-	var m, unit string
-	switch measure {
-	case "time":
-		m = "sum(elapsedtime)/3600"
-		unit = "%4.1fh"
-	case "distance":
-		m = "sum(distance)/1000"
-		unit = "%4.1fkm"
-	case "elevation":
-		m = "sum(elevation)"
-		unit = "%4.0fm"
+	m, err := MeasureByName(measure)
+	if err != nil {
+		return nil, telemetry.Error(span, err)
+	}
+	if _, ok := periods[period]; !ok {
+		return nil, fmt.Errorf("valid values for top query are month, week and day (not %s)", period)
 	}
-	if !slices.Contains([]string{"month", "week", "day"}, period) {
-		return nil, nil, fmt.Errorf("valid values for top query are month, week and day (not %s)", period)
+	summary := storage.Summary
+	column := periods[period]
+	// periods without value (e.g. pace without distance) are last
+	order := storage.Desc(m.Expression).NullsLast()
+	if m.Ascending {
+		order = storage.Asc(m.Expression).NullsLast()
 	}
-	results := [][]string{}
 	opts := []storage.QueryOption{
-		storage.WithOrder(storage.OrderConfig{
-			GroupBy: []string{"year", period},
-			OrderBy: []string{"total desc", "year desc", period + " desc"},
-			Limit:   limit,
-		}),
-		storage.WithTable(storage.SummaryTable),
 		storage.WithSports(sports...),
 		storage.WithWorkouts(workouts...),
 		storage.WithYears(years...),
 	}
-	rows, err := db.Query(ctx, []string{m + " as total", "year", period}, opts...)
+	opts = append(opts, filters...)
+	rows, err := db.Query(ctx, storage.Select(m.Expression, summary.Year, column).From(storage.SummaryTable).
+		Where(opts...).GroupBy(summary.Year, column).
+		OrderBy(order, storage.Desc(summary.Year), storage.Desc(column)).Limit(limit))
 	if err != nil {
-		return nil, nil, telemetry.Error(span, fmt.Errorf("select caused: %w", err))
+		return nil, telemetry.Error(span, fmt.Errorf("select caused: %w", err))
 	}
 	defer func() { _ = rows.Close() }()
+	result := &TopResult{Measure: m, Period: period, Periods: []TopPeriod{}}
 	for rows.Next() {
-		var year, periodValue int
-		var measureValue float64
-		if err = rows.Scan(&measureValue, &year, &periodValue); err != nil {
-			return nil, nil, telemetry.Error(span, err)
+		var tp TopPeriod
+		var measureValue sql.NullFloat64
+		if err = rows.Scan(&measureValue, &tp.Year, &tp.Period); err != nil {
+			return nil, telemetry.Error(span, err)
 		}
-		value := fmt.Sprintf(unit, measureValue)
-		periodStr := strconv.FormatInt(int64(periodValue), 10)
-		if period == "month" {
-			periodStr = time.Month(periodValue).String()
+		if !measureValue.Valid {
+			continue
 		}
-		results = append(
-			results, []string{value, strconv.FormatInt(int64(year), 10), periodStr},
-		)
+		tp.Value = measureValue.Float64
+		result.Periods = append(result.Periods, tp)
 	}
-	return []string{measure, "year", period}, results, nil
+	return result, nil
 }
@@ -0,0 +1,74 @@
+package telemetry
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Handler starts server span for every request with tracer from parent. Handlers start their spans
+// from context of request, so that spans end up under request and trace from client's traceparent continues.
+func Handler(parent context.Context, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := otel.GetTextMapPropagator().Extract(Inherit(r.Context(), parent), propagation.HeaderCarrier(r.Header))
+		scheme := "http"
+		if r.TLS != nil {
+			scheme = "https"
+		}
+		client, _, _ := net.SplitHostPort(r.RemoteAddr)
+		ctx, span := tracer(ctx).Start(ctx, r.Method, trace.WithSpanKind(trace.SpanKindServer), trace.WithAttributes(
+			semconv.HTTPRequestMethodKey.String(r.Method),
+			semconv.URLPath(r.URL.Path),
+			semconv.URLScheme(scheme),
+			semconv.ServerAddress(r.Host),
+			semconv.ClientAddress(client),
+			semconv.UserAgentOriginal(r.UserAgent()),
+		))
+		defer span.End()
+
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		r = r.WithContext(ctx)
+		h.ServeHTTP(rec, r)
+		// ServeMux sets pattern of matching route (e.g. "GET /plot") into request
+		if r.Pattern != "" {
+			span.SetName(r.Pattern)
+			_, route, _ := strings.Cut(r.Pattern, " ")
+			span.SetAttributes(semconv.HTTPRoute(route))
+		}
+		span.SetAttributes(semconv.HTTPResponseStatusCode(rec.status))
+		if rec.status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(rec.status))
+		}
+	})
+}
+
+// statusRecorder keeps status code for span. Unwrap lets http.ResponseController flush event streams.
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (s *statusRecorder) WriteHeader(status int) {
+	if !s.wroteHeader {
+		s.status = status
+		s.wroteHeader = true
+	}
+	s.ResponseWriter.WriteHeader(status)
+}
+
+func (s *statusRecorder) Write(b []byte) (int, error) {
+	s.wroteHeader = true
+	return s.ResponseWriter.Write(b)
+}
+
+func (s *statusRecorder) Unwrap() http.ResponseWriter {
+	return s.ResponseWriter
+}
@@ -12,6 +12,7 @@ import (
 	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
 	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
 	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
 	"go.opentelemetry.io/otel/sdk/resource"
 	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
@@ -80,12 +81,22 @@ func Setup(ctx context.Context, name string) (context.Context, *sdktrace.TracerP
 	}
 	tp := newTraceProvider(exp)
 	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
 	tracer := tp.Tracer(name)
 	ctx = context.WithValue(ctx, otelCtxKey, tracer)
 	return ctx, tp, err
 }
 
+// Inherit copies tracer from parent into ctx, e.g. into context of HTTP request
+func Inherit(ctx, parent context.Context) context.Context {
+	return context.WithValue(ctx, otelCtxKey, parent.Value(otelCtxKey))
+}
+
 func NewSpan(ctx context.Context, name string) (context.Context, trace.Span) {
+	return tracer(ctx).Start(ctx, name)
+}
+
+func tracer(ctx context.Context) trace.Tracer {
 	value := ctx.Value(otelCtxKey)
 	if value == nil {
 		log.Fatal("Telemetry has not been setup")
@@ -94,7 +105,7 @@ func NewSpan(ctx context.Context, name string) (context.Context, trace.Span) {
 	if !ok {
 		log.Fatal("telemetry type conversion failed")
 	}
-	return v.Start(ctx, name)
+	return v
 }
 
 func Error(span trace.Span, err error) error {
@@ -0,0 +1,138 @@
+package units
+
+import (
+	"context"
+	"fmt"
+)
+
+// System is unit system used in output. Database always stores meters and seconds.
+type System string
+
+const (
+	Metric   System = "metric"
+	Imperial System = "imperial"
+)
+
+const (
+	metersInKilometer = 1000
+	metersInMile      = 1609.344
+	metersInFoot      = 0.3048
+)
+
+// ParseSystem validates unit system name. Empty name defaults to metric.
+func ParseSystem(name string) (System, error) {
+	switch System(name) {
+	case "", Metric:
+		return Metric, nil
+	case Imperial:
+		return Imperial, nil
+	}
+	return Metric, fmt.Errorf("unknown unit system: %s (valid systems are %s and %s)", name, Metric, Imperial)
+}
+
+// DistanceUnit is unit for long distances (km or mi)
+func (s System) DistanceUnit() string {
+	if s == Imperial {
+		return "mi"
+	}
+	return "km"
+}
+
+// ElevationUnit is unit for elevation (m or ft)
+func (s System) ElevationUnit() string {
+	if s == Imperial {
+		return "ft"
+	}
+	return "m"
+}
+
+// Distance converts meters into kilometers or miles
+func (s System) Distance(meters float64) float64 {
+	if s == Imperial {
+		return meters / metersInMile
+	}
+	return meters / metersInKilometer
+}
+
+// Elevation converts meters into meters or feet
+func (s System) Elevation(meters float64) float64 {
+	if s == Imperial {
+		return meters / metersInFoot
+	}
+	return meters
+}
+
+// Locale defines how dates are shown
+type Locale string
+
+const (
+	Finnish Locale = "fi"
+	British Locale = "en-GB"
+	English Locale = "en-US"
+	ISO     Locale = "iso"
+)
+
+// ParseLocale validates locale name. Empty name defaults to Finnish style dates.
+func ParseLocale(name string) (Locale, error) {
+	switch l := Locale(name); l {
+	case "":
+		return Finnish, nil
+	case Finnish, British, English, ISO:
+		return l, nil
+	}
+	return Finnish, fmt.Errorf(
+		"unknown locale: %s (valid locales are %s, %s, %s and %s)", name, Finnish, British, English, ISO,
+	)
+}
+
+// Date formats date, e.g. " 5. 3.2024" (fi), "05/03/2024" (en-GB), " 3/ 5/2024" (en-US) or "2024-03-05" (iso)
+func (l Locale) Date(year, month, day int) string {
+	switch l {
+	case British:
+		return fmt.Sprintf("%02d/%02d/%d", day, month, year)
+	case English:
+		return fmt.Sprintf("%2d/%2d/%d", month, day, year)
+	case ISO:
+		return fmt.Sprintf("%d-%02d-%02d", year, month, day)
+	}
+	return fmt.Sprintf("%2d.%2d.%d", day, month, year)
+}
+
+// Preferences combine unit system and locale
+type Preferences struct {
+	System System
+	Locale Locale
+}
+
+// Default preferences are used, when context doesn't have any
+var Default = Preferences{System: Metric, Locale: Finnish}
+
+// Parse validates unit system and locale names
+func Parse(system, locale string) (Preferences, error) {
+	s, err := ParseSystem(system)
+	if err != nil {
+		return Default, err
+	}
+	l, err := ParseLocale(locale)
+	if err != nil {
+		return Default, err
+	}
+	return Preferences{System: s, Locale: l}, nil
+}
+
+type unitsCtxKey string
+
+const preferencesKey unitsCtxKey = "mystats.units"
+
+// WithContext stores preferences into context
+func WithContext(ctx context.Context, p Preferences) context.Context {
+	return context.WithValue(ctx, preferencesKey, p)
+}
+
+// FromContext returns preferences from context or Default
+func FromContext(ctx context.Context) Preferences {
+	if p, ok := ctx.Value(preferencesKey).(Preferences); ok {
+		return p
+	}
+	return Default
+}
@@ -0,0 +1,61 @@
+package units //nolint:testpackage
+
+import (
+	"context"
+	"math"
+	"testing"
+)
+
+func TestSystem(t *testing.T) {
+	values := []struct {
+		name      string
+		system    string
+		distance  float64
+		elevation float64
+	}{
+		{name: "default", system: "", distance: 10, elevation: 100},
+		{name: "metric", system: "metric", distance: 10, elevation: 100},
+		{name: "imperial", system: "imperial", distance: 6.2137, elevation: 328.084},
+	}
+	for _, value := range values {
+		t.Run(value.name, func(t *testing.T) {
+			s, err := ParseSystem(value.system)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if d := s.Distance(10000); math.Abs(d-value.distance) > 0.001 {
+				t.Errorf("distance mismatch: %f vs. %f", d, value.distance)
+			}
+			if e := s.Elevation(100); math.Abs(e-value.elevation) > 0.001 {
+				t.Errorf("elevation mismatch: %f vs. %f", e, value.elevation)
+			}
+		})
+	}
+	if _, err := ParseSystem("nautical"); err == nil {
+		t.Error("unknown unit system didn't cause error")
+	}
+}
+
+func TestLocale(t *testing.T) {
+	values := map[Locale]string{
+		Finnish: " 5. 3.2024",
+		British: "05/03/2024",
+		English: " 3/ 5/2024",
+		ISO:     "2024-03-05",
+	}
+	for l, expected := range values {
+		if date := l.Date(2024, 3, 5); date != expected {
+			t.Errorf("%s mismatch: %s vs. %s", l, date, expected)
+		}
+	}
+}
+
+func TestContext(t *testing.T) {
+	if p := FromContext(context.TODO()); p != Default {
+		t.Errorf("expected default preferences, got %v", p)
+	}
+	p := Preferences{System: Imperial, Locale: ISO}
+	if got := FromContext(WithContext(context.TODO(), p)); got != p {
+		t.Errorf("preferences mismatch: %v vs. %v", got, p)
+	}
+}
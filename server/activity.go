@@ -0,0 +1,188 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/jylitalo/mystats/pkg/chart"
+	"github.com/jylitalo/mystats/pkg/present"
+	"github.com/jylitalo/mystats/pkg/stats"
+	"github.com/jylitalo/mystats/pkg/telemetry"
+	"github.com/jylitalo/mystats/pkg/units"
+)
+
+type lapStatsFn func(ctx context.Context, db stats.Storage, id int64) ([]stats.ActivityLap, error)
+
+type profileStatsFn func(ctx context.Context, db stats.Storage, id int64) ([]stats.ProfilePoint, error)
+
+type adjacentStatsFn func(ctx context.Context, db stats.Storage, id int64) (int64, int64, error)
+
+// ActivityPage has summary, charts, splits and laps of one activity.
+// Previous and Next are activities of the same type (zero, when there isn't one).
+type ActivityPage struct {
+	ID       int64
+	Name     string
+	Date     string
+	Previous int64
+	Next     int64
+	HasRoute bool
+	HasHR    bool
+	Summary  TableData
+	Splits   TableData
+	Laps     TableData
+}
+
+// activityID reads Strava ID of activity from query string
+func activityID(values url.Values) (int64, error) {
+	id, err := strconv.ParseInt(values.Get("id"), 10, 64)
+	if err != nil || id <= 0 {
+		return 0, fmt.Errorf("%w: activity id %q", errInvalidForm, values.Get("id"))
+	}
+	return id, nil
+}
+
+func newActivityPage(ctx context.Context, db Storage, cfg *pageConfig, values url.Values) (*ActivityPage, error) {
+	id, err := activityID(values)
+	if err != nil {
+		return nil, err
+	}
+	details, err := cfg.detailStats(ctx, db, id)
+	if err != nil {
+		return nil, err
+	}
+	splits, errS := cfg.splitStats(ctx, db, id)
+	laps, errL := cfg.lapStats(ctx, db, id)
+	previous, next, errA := cfg.adjacentStats(ctx, db, id)
+	if err = errors.Join(errS, errL, errA); err != nil {
+		return nil, err
+	}
+	prefs := units.FromContext(ctx)
+	page := &ActivityPage{
+		ID:       id,
+		Name:     details.Name,
+		Date:     strings.TrimSpace(present.Date(details.Date, prefs.Locale)),
+		Previous: previous,
+		Next:     next,
+		HasRoute: len(details.Route) > 0,
+		HasHR:    details.AverageHeartrate > 0,
+		Summary:  newTableData(),
+		Splits:   newTableData(),
+		Laps:     newTableData(),
+	}
+	page.Summary.Headers, page.Summary.Rows = present.Details(details, splits, prefs)
+	page.Splits.Headers, page.Splits.Rows = present.Splits(splits, prefs)
+	if len(laps) > 1 { // single lap is the whole activity
+		page.Laps.Headers, page.Laps.Rows = present.Laps(laps, prefs)
+	}
+	return page, nil
+}
+
+func activityGet(renderer *Template, cfg *pageConfig, db Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, span := telemetry.NewSpan(r.Context(), "activityGET")
+		defer span.End()
+
+		page, err := newActivityPage(ctx, db, cfg, r.URL.Query())
+		if err != nil {
+			status := errorStatus(err)
+			http.Error(w, http.StatusText(status), status)
+			_ = telemetry.Error(span, err)
+			return
+		}
+		if err := render(w, r, renderer, &Page{Tab: "list", Activity: page}, "activity-data", page); err != nil {
+			http.Error(w, "Template rendering failed", http.StatusInternalServerError)
+			_ = telemetry.Error(span, err)
+		}
+	}
+}
+
+// sizes of activity charts
+var (
+	routeSize   = chart.Size{Width: 500, Height: 500}
+	profileSize = chart.Size{Width: 1000, Height: 250}
+)
+
+// routeSVG draws route of activity from its summary polyline
+func routeSVG(cfg *pageConfig, db Storage) drawFn {
+	return func(ctx context.Context, w io.Writer, values url.Values) error {
+		id, err := activityID(values)
+		if err != nil {
+			return err
+		}
+		details, err := cfg.detailStats(ctx, db, id)
+		if err != nil {
+			return err
+		}
+		return chart.RouteSVG(w, routeSize, details.Route)
+	}
+}
+
+// profileSVG draws elevation profile of activity
+func profileSVG(cfg *pageConfig, db Storage) drawFn {
+	return func(ctx context.Context, w io.Writer, values url.Values) error {
+		id, err := activityID(values)
+		if err != nil {
+			return err
+		}
+		points, err := cfg.profileStats(ctx, db, id)
+		if err != nil {
+			return err
+		}
+		system := units.FromContext(ctx).System
+		distances, altitudes := make([]float64, len(points)), make([]float64, len(points))
+		for idx, point := range points {
+			distances[idx], altitudes[idx] = system.Distance(point.Distance), system.Elevation(point.Altitude)
+		}
+		xFormat := func(value float64) string {
+			return fmt.Sprintf("%.1f%s", value, system.DistanceUnit())
+		}
+		yFormat := func(value float64) string {
+			return fmt.Sprintf("%.0f%s", value, system.ElevationUnit())
+		}
+		return chart.ProfileSVG(w, profileSize, "Elevation", distances, altitudes, xFormat, yFormat)
+	}
+}
+
+// splitsSVG draws pace or heart rate of every split as bars
+func splitsSVG(cfg *pageConfig, db Storage) drawFn {
+	return func(ctx context.Context, w io.Writer, values url.Values) error {
+		id, err := activityID(values)
+		if err != nil {
+			return err
+		}
+		measure, err := optionValue(values.Get("measure"), "pace", []string{"pace", "heartrate"})
+		if err != nil {
+			return err
+		}
+		splits, err := cfg.splitStats(ctx, db, id)
+		if err != nil {
+			return err
+		}
+		system := units.FromContext(ctx).System
+		labels := make([]string, len(splits))
+		series := []chart.Series{{Name: "Pace (min/" + system.DistanceUnit() + ")"}}
+		format := present.Pace
+		if measure == "heartrate" {
+			series[0].Name = "Heart Rate"
+			format = func(value float64) string { return fmt.Sprintf("%.0f", value) }
+		}
+		for idx, split := range splits {
+			labels[idx] = strconv.Itoa(split.Split)
+			value := split.AverageHeartrate
+			if measure == "pace" {
+				value = 0
+				if split.Distance > 0 {
+					value = split.ElapsedTime.Seconds() / system.Distance(split.Distance)
+				}
+			}
+			series[0].Values = append(series[0].Values, value)
+		}
+		return chart.BarsSVG(w, barSize, series[0].Name, labels, series, format)
+	}
+}
@@ -0,0 +1,370 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/ogen-go/ogen/ogenerrors"
+
+	"github.com/jylitalo/mystats/api/mystats"
+	"github.com/jylitalo/mystats/pkg/season"
+	"github.com/jylitalo/mystats/pkg/stats"
+	"github.com/jylitalo/mystats/pkg/telemetry"
+	"github.com/jylitalo/mystats/storage"
+)
+
+type activityStatsFn func(
+	ctx context.Context, db stats.Storage, order []storage.Order, limit, offset int, filters ...storage.QueryOption,
+) ([]stats.Activity, error)
+
+type detailStatsFn func(ctx context.Context, db stats.Storage, id int64) (*stats.ActivityDetails, error)
+
+type splitStatsFn func(ctx context.Context, db stats.Storage, id int64) ([]stats.ActivitySplit, error)
+
+// apiHandler implements JSON API from api/mystats/openapi.yaml. Spec validates parameters.
+type apiHandler struct {
+	cfg *pageConfig
+	db  Storage
+}
+
+// newAPIServer serves JSON API under /api/v1/
+func newAPIServer(cfg *pageConfig, db Storage) (*mystats.Server, error) {
+	return mystats.NewServer(
+		&apiHandler{cfg: cfg, db: db}, mystats.WithPathPrefix("/api/v1"), mystats.WithErrorHandler(apiErrorHandler),
+	)
+}
+
+// apiErrorHandler writes errors from decoding request in the same format as errors from handler
+func apiErrorHandler(ctx context.Context, w http.ResponseWriter, r *http.Request, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(ogenerrors.ErrorCode(err))
+	_ = json.NewEncoder(w).Encode(&mystats.Error{Error: err.Error()})
+}
+
+// NewError tells whether client or server caused error. Details are shown only for errors caused by client.
+func (h *apiHandler) NewError(ctx context.Context, err error) *mystats.ErrorStatusCode {
+	status := errorStatus(err)
+	msg := err.Error()
+	if status == http.StatusInternalServerError {
+		msg = http.StatusText(status)
+	}
+	return &mystats.ErrorStatusCode{StatusCode: status, Response: mystats.Error{Error: msg}}
+}
+
+// apiFilter has activity filters from query string. Missing filter matches all activities.
+type apiFilter struct {
+	sports   []string
+	groups   []string
+	workouts []string
+	years    []int
+	filters  []storage.QueryOption
+}
+
+func newAPIFilter(
+	cfg *pageConfig, sports, groups, workouts []string, years []int, from, to mystats.OptDate,
+) (apiFilter, error) {
+	options := newSportGroups(cfg.groups, groups)
+	for _, name := range groups {
+		if !slices.ContainsFunc(options, func(g SportGroup) bool { return g.Name == name }) {
+			return apiFilter{}, fmt.Errorf("%w: group %s", errInvalidForm, name)
+		}
+	}
+	filters := []storage.QueryOption{}
+	if from.Set || to.Set {
+		filters = append(filters, storage.WithDateRange(from.Or(time.Time{}), to.Or(time.Time{})))
+	}
+	names, groupFilters := selectedGroups(options)
+	return apiFilter{
+		sports:   sports,
+		groups:   names,
+		workouts: workouts,
+		years:    years,
+		filters:  append(filters, groupFilters...),
+	}, nil
+}
+
+// options has all filters for Summary table
+func (f apiFilter) options() []storage.QueryOption {
+	opts := []storage.QueryOption{
+		storage.WithSports(f.sports...),
+		storage.WithWorkouts(f.workouts...),
+		storage.WithYears(f.years...),
+	}
+	return append(opts, f.filters...)
+}
+
+func newAPIActivity(a stats.Activity) mystats.Activity {
+	return mystats.Activity{
+		ID:          a.StravaID,
+		Date:        a.Date,
+		Name:        a.Name,
+		Type:        a.Type,
+		WorkoutType: a.WorkoutType,
+		Distance:    a.Distance,
+		Elevation:   a.Elevation,
+		ElapsedTime: a.ElapsedTime.Seconds(),
+	}
+}
+
+// ListActivities lists activities in chronological order
+func (h *apiHandler) ListActivities(
+	ctx context.Context, params mystats.ListActivitiesParams,
+) (*mystats.Activities, error) {
+	ctx, span := telemetry.NewSpan(ctx, "api.ListActivities")
+	defer span.End()
+
+	filter, err := newAPIFilter(h.cfg, params.Sport, params.Group, params.Workout, params.Year, params.From, params.To)
+	if err != nil {
+		return nil, telemetry.Error(span, err)
+	}
+	limit, offset := params.Limit.Value, params.Offset.Value
+	opts := append(filter.options(), storage.WithName(params.Search.Value))
+	// one extra activity tells whether there is next page
+	activities, err := h.cfg.activityStats(ctx, h.db, stats.Chronological(), limit+1, offset, opts...)
+	if err != nil {
+		return nil, telemetry.Error(span, err)
+	}
+	result := &mystats.Activities{Offset: offset, Limit: limit, Activities: []mystats.Activity{}}
+	if len(activities) > limit {
+		activities = activities[:limit]
+		result.NextOffset = mystats.NewOptInt(offset + limit)
+	}
+	for _, a := range activities {
+		result.Activities = append(result.Activities, newAPIActivity(a))
+	}
+	return result, nil
+}
+
+// GetActivity has details and splits of activity
+func (h *apiHandler) GetActivity(
+	ctx context.Context, params mystats.GetActivityParams,
+) (*mystats.ActivityDetails, error) {
+	ctx, span := telemetry.NewSpan(ctx, "api.GetActivity")
+	defer span.End()
+
+	details, err := h.cfg.detailStats(ctx, h.db, params.ID)
+	if err != nil {
+		return nil, telemetry.Error(span, err)
+	}
+	splits, err := h.cfg.splitStats(ctx, h.db, params.ID)
+	if err != nil {
+		return nil, telemetry.Error(span, err)
+	}
+	a := newAPIActivity(details.Activity)
+	result := &mystats.ActivityDetails{
+		ID:          a.ID,
+		Date:        a.Date,
+		Name:        a.Name,
+		Type:        a.Type,
+		WorkoutType: a.WorkoutType,
+		Distance:    a.Distance,
+		Elevation:   a.Elevation,
+		ElapsedTime: a.ElapsedTime,
+		SportType:   details.SportType,
+		MovingTime:  details.MovingTime.Seconds(),
+		Splits:      []mystats.Split{},
+	}
+	if details.AverageHeartrate > 0 {
+		result.AverageHeartrate = mystats.NewOptFloat64(details.AverageHeartrate)
+	}
+	if details.Kilojoules > 0 {
+		result.Kilojoules = mystats.NewOptFloat64(details.Kilojoules)
+	}
+	for _, s := range splits {
+		result.Splits = append(result.Splits, mystats.Split{
+			Split:         s.Split,
+			Distance:      s.Distance,
+			ElapsedTime:   s.ElapsedTime.Seconds(),
+			MovingTime:    s.MovingTime.Seconds(),
+			ElevationDiff: s.ElevationDiff,
+		})
+	}
+	return result, nil
+}
+
+// nilFloats turns missing values into nulls
+func nilFloats(values []*float64) []mystats.NilFloat64 {
+	result := make([]mystats.NilFloat64, len(values))
+	for idx, v := range values {
+		if v == nil {
+			result[idx].SetToNull()
+		} else {
+			result[idx].SetTo(*v)
+		}
+	}
+	return result
+}
+
+// GetStats aggregates measure by period and year. Season of sports decides which year activity belongs to.
+func (h *apiHandler) GetStats(ctx context.Context, params mystats.GetStatsParams) (*mystats.Stats, error) {
+	ctx, span := telemetry.NewSpan(ctx, "api.GetStats")
+	defer span.End()
+
+	filter, err := newAPIFilter(h.cfg, params.Sport, params.Group, params.Workout, params.Year, params.From, params.To)
+	if err != nil {
+		return nil, telemetry.Error(span, err)
+	}
+	filters := filter.filters
+	s := season.For(h.cfg.seasons, slices.Concat(filter.sports, filter.groups))
+	if !s.IsCalendar() {
+		sm, sd, err := s.MonthDay()
+		if err != nil {
+			return nil, telemetry.Error(span, err)
+		}
+		filters = append(filters, storage.WithSeason(sm, sd))
+	}
+	var years []int // nil means all years
+	if len(filter.years) > 0 {
+		years = filter.years
+	}
+	measure, period := string(params.Measure.Value), string(params.Period.Value)
+	result, err := h.cfg.plotStats(
+		ctx, h.db, measure, period, filter.sports, filter.workouts,
+		params.Month.Value, params.Day.Value, years, filters...,
+	)
+	if err != nil {
+		return nil, telemetry.Error(span, err)
+	}
+	response := &mystats.Stats{
+		Measure: measure,
+		Period:  period,
+		Years:   []int{},
+		Values:  [][]mystats.NilFloat64{},
+		Totals:  []mystats.NilFloat64{},
+	}
+	if result != nil {
+		response.Dimension = result.Measure.Dimension
+		response.Years = result.Years
+		for _, row := range result.Values {
+			response.Values = append(response.Values, nilFloats(row))
+		}
+		response.Totals = nilFloats(result.Totals)
+	}
+	return response, nil
+}
+
+// GetTop lists periods with the highest (or for pace the lowest) values
+func (h *apiHandler) GetTop(ctx context.Context, params mystats.GetTopParams) (*mystats.Top, error) {
+	ctx, span := telemetry.NewSpan(ctx, "api.GetTop")
+	defer span.End()
+
+	filter, err := newAPIFilter(h.cfg, params.Sport, params.Group, params.Workout, params.Year, params.From, params.To)
+	if err != nil {
+		return nil, telemetry.Error(span, err)
+	}
+	measure, period := string(params.Measure.Value), string(params.Period.Value)
+	result, err := h.cfg.topStats(
+		ctx, h.db, measure, period, filter.sports, filter.workouts, params.Limit.Value, filter.years, filter.filters...,
+	)
+	if err != nil {
+		return nil, telemetry.Error(span, err)
+	}
+	response := &mystats.Top{Measure: measure, Period: period, Periods: []mystats.TopPeriod{}}
+	if result != nil {
+		response.Dimension = result.Measure.Dimension
+		for _, p := range result.Periods {
+			response.Periods = append(response.Periods, mystats.TopPeriod{Year: p.Year, Period: p.Period, Value: p.Value})
+		}
+	}
+	return response, nil
+}
+
+// GetBest lists best efforts for distances (default all) and optionally their progression
+func (h *apiHandler) GetBest(ctx context.Context, params mystats.GetBestParams) ([]mystats.Best, error) {
+	ctx, span := telemetry.NewSpan(ctx, "api.GetBest")
+	defer span.End()
+
+	distances, err := h.db.QueryBestEffortDistances(ctx)
+	if err != nil {
+		return nil, telemetry.Error(span, err)
+	}
+	for _, d := range params.Distance {
+		if !slices.Contains(distances, d) {
+			return nil, telemetry.Error(span, fmt.Errorf("%w: distance %s", errInvalidForm, d))
+		}
+	}
+	if len(params.Distance) > 0 {
+		distances = params.Distance
+	}
+	filters := []storage.QueryOption{}
+	if params.From.Set || params.To.Set {
+		filters = append(filters, storage.WithDateRange(params.From.Or(time.Time{}), params.To.Or(time.Time{})))
+	}
+	response := []mystats.Best{}
+	for _, distance := range distances {
+		efforts, err := h.cfg.bestStats(ctx, h.db, distance, params.Limit.Value, filters...)
+		if err != nil {
+			return nil, telemetry.Error(span, err)
+		}
+		best := mystats.Best{Distance: distance, Efforts: []mystats.BestEffort{}}
+		for _, e := range efforts {
+			best.Efforts = append(best.Efforts, mystats.BestEffort{
+				ID:               e.StravaID,
+				Date:             e.Date,
+				Name:             e.Name,
+				ElapsedTime:      e.ElapsedTime.Seconds(),
+				ActivityDistance: e.ActivityDistance,
+				ActivityTime:     e.ActivityTime.Seconds(),
+			})
+		}
+		if params.Progression.Value {
+			records, err := h.cfg.bestProgressions(ctx, h.db, distance, filters...)
+			if err != nil {
+				return nil, telemetry.Error(span, err)
+			}
+			for _, p := range records {
+				best.Progression = append(best.Progression, mystats.ProgressionRecord{
+					ID:          p.StravaID,
+					Date:        p.Date,
+					Name:        p.Name,
+					ElapsedTime: p.ElapsedTime.Seconds(),
+					Improvement: p.Improvement.Seconds(),
+					AllTime:     p.AllTime,
+				})
+			}
+		}
+		response = append(response, best)
+	}
+	return response, nil
+}
+
+type dailyStatsFn func(ctx context.Context, db stats.Storage, month, day int, years []int) (map[int][]float64, error)
+
+// daily has daily values (steps or heart rate) of years
+func (h *apiHandler) daily(
+	ctx context.Context, values dailyStatsFn, month, day int, years []int,
+) (*mystats.Daily, error) {
+	numbers, err := values(ctx, h.db, month, day, years)
+	if err != nil {
+		return nil, err
+	}
+	response := &mystats.Daily{Years: []int{}, Values: [][]float64{}}
+	for _, year := range slices.Sorted(maps.Keys(numbers)) {
+		response.Years = append(response.Years, year)
+		response.Values = append(response.Values, numbers[year])
+	}
+	return response, nil
+}
+
+// GetSteps has cumulative steps for each day of year
+func (h *apiHandler) GetSteps(ctx context.Context, params mystats.GetStepsParams) (*mystats.Daily, error) {
+	ctx, span := telemetry.NewSpan(ctx, "api.GetSteps")
+	defer span.End()
+
+	response, err := h.daily(ctx, stats.Steps, params.Month.Value, params.Day.Value, params.Year)
+	return response, telemetry.Error(span, err)
+}
+
+// GetHeartRate has resting heart rate for each day of year
+func (h *apiHandler) GetHeartRate(ctx context.Context, params mystats.GetHeartRateParams) (*mystats.Daily, error) {
+	ctx, span := telemetry.NewSpan(ctx, "api.GetHeartRate")
+	defer span.End()
+
+	response, err := h.daily(ctx, stats.HeartRate, params.Month.Value, params.Day.Value, params.Year)
+	return response, telemetry.Error(span, err)
+}
@@ -0,0 +1,48 @@
+package server
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// htmxURL is the release of htmx that js/htmx.min.js vendors
+const htmxURL = "https://unpkg.com/htmx.org@2.0.4/dist/htmx.min.js"
+
+//go:generate curl -sSfL -o js/htmx.min.js https://unpkg.com/htmx.org@2.0.4/dist/htmx.min.js
+
+// embedded has templates, css and vendored JavaScript, so that server works from any directory and offline.
+//
+//go:embed css js views
+var embedded embed.FS
+
+// assets returns embedded assets or, when dir is given, assets from dir (e.g. server directory of repository).
+// Dir is useful for template development, since changes don't need new build.
+func assets(dir string) (fs.FS, error) {
+	if dir == "" {
+		return embedded, nil
+	}
+	if _, err := os.Stat(filepath.Join(dir, "views")); err != nil {
+		return nil, fmt.Errorf("assets dir %s: %w", dir, err)
+	}
+	return os.DirFS(dir), nil
+}
+
+// staticHandler serves css and js from assets. Build without vendored htmx redirects it to the same release,
+// so that pages never lose htmx.
+func staticHandler(static fs.FS) http.Handler {
+	files := http.FileServerFS(static)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/js/htmx.min.js" {
+			if _, err := fs.Stat(static, "js/htmx.min.js"); errors.Is(err, fs.ErrNotExist) {
+				http.Redirect(w, r, htmxURL, http.StatusFound)
+				return
+			}
+		}
+		files.ServeHTTP(w, r)
+	})
+}
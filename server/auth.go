@@ -0,0 +1,513 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/jylitalo/mystats/pkg/telemetry"
+)
+
+const (
+	sessionCookie = "mystats_session"
+	stateCookie   = "mystats_state"
+	sessionTTL    = 24 * time.Hour
+	loginTTL      = 10 * time.Minute // time to log in at OIDC provider
+)
+
+// Users has bcrypt hashes of local users' passwords by user name
+type Users map[string][]byte
+
+// ReadUsers reads users file, which has "name:bcrypt hash" on every line (e.g. from `htpasswd -nB name`).
+// Empty lines and lines starting with # are skipped.
+func ReadUsers(r io.Reader) (Users, error) {
+	users := Users{}
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		name, hash, ok := strings.Cut(text, ":")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("line %d: expected name:hash", line)
+		}
+		if _, err := bcrypt.Cost([]byte(hash)); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		users[name] = []byte(hash)
+	}
+	return users, scanner.Err()
+}
+
+// HashPassword returns line of users file
+func HashPassword(name, password string) (string, error) {
+	if name == "" || strings.Contains(name, ":") {
+		return "", fmt.Errorf("invalid user name %q", name)
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	return name + ":" + string(hash), err
+}
+
+// OIDCConfig has client registered at OpenID Connect provider.
+// Redirect URL of client is /login/callback of this server.
+type OIDCConfig struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	// Allow has subjects (sub), emails or @domain of emails that may log in. Providers like Google let anyone in.
+	Allow []string
+}
+
+// WithUsers lets local users log in with password
+func WithUsers(users Users) StartOption {
+	return func(o *startOptions) {
+		o.users = users
+	}
+}
+
+// WithOIDC lets users log in at OpenID Connect provider
+func WithOIDC(cfg OIDCConfig) StartOption {
+	return func(o *startOptions) {
+		o.oidc = &cfg
+	}
+}
+
+// oidcProvider has endpoints from provider's discovery document
+type oidcProvider struct {
+	OIDCConfig
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	UserinfoEndpoint      string `json:"userinfo_endpoint"`
+}
+
+// discover reads endpoints of provider from /.well-known/openid-configuration
+func discover(ctx context.Context, client *http.Client, cfg OIDCConfig) (*oidcProvider, error) {
+	issuer := strings.TrimSuffix(cfg.Issuer, "/")
+	var doc struct {
+		Issuer string `json:"issuer"`
+		oidcProvider
+	}
+	if err := getJSON(ctx, client, issuer+"/.well-known/openid-configuration", "", &doc); err != nil {
+		return nil, fmt.Errorf("OIDC discovery failed: %w", err)
+	}
+	if strings.TrimSuffix(doc.Issuer, "/") != issuer {
+		return nil, fmt.Errorf("OIDC discovery returned issuer %s (not %s)", doc.Issuer, cfg.Issuer)
+	}
+	if doc.AuthorizationEndpoint == "" || doc.TokenEndpoint == "" || doc.UserinfoEndpoint == "" {
+		return nil, errors.New("OIDC provider doesn't have authorization, token and userinfo endpoints")
+	}
+	doc.OIDCConfig = cfg
+	return &doc.oidcProvider, nil
+}
+
+// getJSON decodes response of GET. Token is sent as bearer token, when it is set.
+func getJSON(ctx context.Context, client *http.Client, endpoint, token string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return doJSON(client, req, v)
+}
+
+func doJSON(client *http.Client, req *http.Request, v any) error {
+	req.Header.Set("Accept", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned %s", req.URL.Redacted(), resp.Status)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(v)
+}
+
+// session is logged in user
+type session struct {
+	user    string
+	expires time.Time
+}
+
+// pendingLogin waits for user to come back from OIDC provider
+type pendingLogin struct {
+	verifier string // PKCE code verifier
+	next     string
+	expires  time.Time
+}
+
+// auth keeps sessions in memory, so restart logs everyone out
+type auth struct {
+	users    Users
+	dummy    []byte // compared with unknown users, so that response time doesn't tell which users exist
+	oidc     *oidcProvider
+	client   *http.Client
+	mu       sync.Mutex
+	sessions map[string]session
+	pending  map[string]pendingLogin // by state parameter
+}
+
+// newAuth returns nil, when neither local users nor OIDC has been configured
+func newAuth(ctx context.Context, users Users, cfg *OIDCConfig) (*auth, error) {
+	if len(users) == 0 && cfg == nil {
+		return nil, nil //nolint:nilnil // server without authentication
+	}
+	a := &auth{
+		users:    users,
+		client:   &http.Client{Timeout: 10 * time.Second},
+		sessions: map[string]session{},
+		pending:  map[string]pendingLogin{},
+	}
+	var err error
+	if len(users) > 0 {
+		if a.dummy, err = bcrypt.GenerateFromPassword([]byte("dummy"), bcrypt.DefaultCost); err != nil {
+			return nil, err
+		}
+	}
+	if cfg != nil {
+		if len(cfg.Allow) == 0 {
+			return nil, errors.New("OIDC needs users, emails or domains that are allowed to log in")
+		}
+		if a.oidc, err = discover(ctx, a.client, *cfg); err != nil {
+			return nil, err
+		}
+	}
+	return a, nil
+}
+
+// randomString is for session tokens, OIDC state and PKCE verifier
+func randomString() string {
+	b := make([]byte, 32)
+	_, _ = rand.Read(b) // never returns error
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// setCookie has secure defaults. Browsers accept secure cookies from http://localhost too.
+func setCookie(w http.ResponseWriter, name, value, path string, maxAge time.Duration) {
+	http.SetCookie(w, &http.Cookie{
+		Name: name, Value: value, Path: path, MaxAge: int(maxAge.Seconds()),
+		HttpOnly: true, Secure: true, SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// login starts session and redirects user to page where login started
+func (a *auth) login(w http.ResponseWriter, r *http.Request, user, next string) {
+	token := randomString()
+	now := time.Now()
+	a.mu.Lock()
+	for t, s := range a.sessions {
+		if now.After(s.expires) {
+			delete(a.sessions, t)
+		}
+	}
+	a.sessions[token] = session{user: user, expires: now.Add(sessionTTL)}
+	a.mu.Unlock()
+	slog.Info("login", "user", user)
+	setCookie(w, sessionCookie, token, "/", sessionTTL)
+	http.Redirect(w, r, safeNext(next), http.StatusSeeOther)
+}
+
+// user of request from session cookie or, in JSON API, from basic auth of local user
+func (a *auth) user(r *http.Request) (string, bool) {
+	if cookie, err := r.Cookie(sessionCookie); err == nil {
+		a.mu.Lock()
+		s, ok := a.sessions[cookie.Value]
+		a.mu.Unlock()
+		if ok && time.Now().Before(s.expires) {
+			return s.user, true
+		}
+	}
+	if name, password, ok := r.BasicAuth(); ok && strings.HasPrefix(r.URL.Path, "/api/") {
+		return name, a.checkPassword(name, password)
+	}
+	return "", false
+}
+
+func (a *auth) checkPassword(name, password string) bool {
+	hash, ok := a.users[name]
+	if !ok {
+		_ = bcrypt.CompareHashAndPassword(a.dummy, []byte(password))
+		return false
+	}
+	return bcrypt.CompareHashAndPassword(hash, []byte(password)) == nil
+}
+
+// safeNext allows redirects only within this server
+func safeNext(next string) string {
+	if !strings.HasPrefix(next, "/") || strings.HasPrefix(next, "//") || strings.HasPrefix(next, "/\\") {
+		return "/"
+	}
+	return next
+}
+
+type userKey struct{}
+
+// currentUser is name of logged in user. It is empty, when server doesn't have authentication.
+func currentUser(ctx context.Context) string {
+	user, _ := ctx.Value(userKey{}).(string)
+	return user
+}
+
+// require lets only logged in users through. Pages redirect to login page, while JSON API,
+// htmx and other requests get 401.
+func (a *auth) require(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, ok := a.user(r); ok {
+			req := r.WithContext(context.WithValue(r.Context(), userKey{}, user))
+			next.ServeHTTP(w, req)
+			r.Pattern = req.Pattern // route of page for telemetry
+			return
+		}
+		login := "/login?" + url.Values{"next": {r.URL.RequestURI()}}.Encode()
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/api/"):
+			if len(a.users) > 0 {
+				w.Header().Set("WWW-Authenticate", `Basic realm="mystats"`)
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = io.WriteString(w, `{"error":"unauthorized"}`)
+		case r.Header.Get("HX-Request") == "true":
+			w.Header().Set("HX-Redirect", login)
+			w.WriteHeader(http.StatusUnauthorized)
+		case r.Method == http.MethodGet:
+			http.Redirect(w, r, login, http.StatusSeeOther)
+		default:
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		}
+	})
+}
+
+// LoginPage has login methods that server has
+type LoginPage struct {
+	Next   string
+	Error  string
+	Local  bool
+	Issuer string // OIDC provider
+}
+
+func (a *auth) loginPage(next, errMsg string) LoginPage {
+	page := LoginPage{Next: safeNext(next), Error: errMsg, Local: len(a.users) > 0}
+	if a.oidc != nil {
+		page.Issuer = a.oidc.Issuer
+	}
+	return page
+}
+
+func loginGet(renderer *Template, a *auth) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := renderer.tmpl.ExecuteTemplate(w, "login", a.loginPage(r.URL.Query().Get("next"), "")); err != nil {
+			http.Error(w, "Template rendering failed", http.StatusInternalServerError)
+		}
+	}
+}
+
+func loginPost(renderer *Template, a *auth) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		_, span := telemetry.NewSpan(r.Context(), "loginPOST")
+		defer span.End()
+
+		name, password, next := r.PostFormValue("user"), r.PostFormValue("password"), r.PostFormValue("next")
+		if len(a.users) == 0 || !a.checkPassword(name, password) {
+			slog.Warn("login failed", "user", name)
+			w.WriteHeader(http.StatusUnauthorized)
+			_ = renderer.tmpl.ExecuteTemplate(w, "login", a.loginPage(next, "Invalid user name or password"))
+			return
+		}
+		a.login(w, r, name, next)
+	}
+}
+
+func logoutPost(a *auth) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if cookie, err := r.Cookie(sessionCookie); err == nil {
+			a.mu.Lock()
+			delete(a.sessions, cookie.Value)
+			a.mu.Unlock()
+		}
+		setCookie(w, sessionCookie, "", "/", -time.Second)
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+	}
+}
+
+// callbackURL is redirect URL of OIDC client
+func callbackURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host + "/login/callback"
+}
+
+// oidcGet redirects user to provider. State cookie ties callback to the same browser.
+func oidcGet(a *auth) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if a.oidc == nil {
+			http.NotFound(w, r)
+			return
+		}
+		state, verifier := randomString(), randomString()
+		now := time.Now()
+		a.mu.Lock()
+		for s, p := range a.pending {
+			if now.After(p.expires) {
+				delete(a.pending, s)
+			}
+		}
+		a.pending[state] = pendingLogin{verifier: verifier, next: r.URL.Query().Get("next"), expires: now.Add(loginTTL)}
+		a.mu.Unlock()
+		setCookie(w, stateCookie, state, "/login", loginTTL)
+		challenge := sha256.Sum256([]byte(verifier))
+		query := url.Values{
+			"response_type":         {"code"},
+			"client_id":             {a.oidc.ClientID},
+			"redirect_uri":          {callbackURL(r)},
+			"scope":                 {"openid profile email"},
+			"state":                 {state},
+			"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+			"code_challenge_method": {"S256"},
+		}
+		http.Redirect(w, r, a.oidc.AuthorizationEndpoint+"?"+query.Encode(), http.StatusFound)
+	}
+}
+
+// callbackGet exchanges code to access token and asks name of user from userinfo endpoint.
+// Both calls go directly to provider, so ID token doesn't need to be verified.
+func callbackGet(renderer *Template, a *auth) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		_, span := telemetry.NewSpan(r.Context(), "loginCallback")
+		defer span.End()
+
+		fail := func(err error) {
+			slog.Warn("OIDC login failed", "err", telemetry.Error(span, err))
+			w.WriteHeader(http.StatusUnauthorized)
+			_ = renderer.tmpl.ExecuteTemplate(w, "login", a.loginPage("", "Login failed"))
+		}
+		if a.oidc == nil {
+			http.NotFound(w, r)
+			return
+		}
+		query := r.URL.Query()
+		state := query.Get("state")
+		cookie, err := r.Cookie(stateCookie)
+		if err != nil || state == "" || cookie.Value != state {
+			fail(errors.New("state doesn't match"))
+			return
+		}
+		setCookie(w, stateCookie, "", "/login", -time.Second)
+		a.mu.Lock()
+		pending, ok := a.pending[state]
+		delete(a.pending, state)
+		a.mu.Unlock()
+		if !ok || time.Now().After(pending.expires) {
+			fail(errors.New("login has expired"))
+			return
+		}
+		if msg := query.Get("error"); msg != "" {
+			fail(fmt.Errorf("provider returned %s: %s", msg, query.Get("error_description")))
+			return
+		}
+		user, err := a.oidc.user(r.Context(), a.client, query.Get("code"), pending.verifier, callbackURL(r))
+		if err != nil {
+			fail(err)
+			return
+		}
+		a.login(w, r, user, pending.next)
+	}
+}
+
+// userinfo has claims of user from provider's userinfo endpoint
+// Claims like preferred_username aren't unique or verified, so they are never used.
+type userinfo struct {
+	Subject       string `json:"sub"`
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+}
+
+// allowed tells if Allow has user's subject, verified email or domain of verified email
+func (p *oidcProvider) allowed(info userinfo) bool {
+	for _, allow := range p.Allow {
+		if info.Subject != "" && allow == info.Subject {
+			return true
+		}
+		if info.Email == "" || !info.EmailVerified {
+			continue
+		}
+		_, domain, _ := strings.Cut(info.Email, "@")
+		if strings.EqualFold(allow, info.Email) || (domain != "" && strings.EqualFold(allow, "@"+domain)) {
+			return true
+		}
+	}
+	return false
+}
+
+// user exchanges authorization code to access token and returns name from userinfo.
+// Users with unverified email or without match in Allow are rejected.
+func (p *oidcProvider) user(ctx context.Context, client *http.Client, code, verifier, redirect string) (string, error) {
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {redirect},
+		"code_verifier": {verifier},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(url.QueryEscape(p.ClientID), url.QueryEscape(p.ClientSecret))
+	var token struct {
+		AccessToken string `json:"access_token"`
+	}
+	if err = doJSON(client, req, &token); err != nil {
+		return "", err
+	}
+	var info userinfo
+	if err = getJSON(ctx, client, p.UserinfoEndpoint, token.AccessToken, &info); err != nil {
+		return "", err
+	}
+	if info.Email != "" && !info.EmailVerified {
+		return "", fmt.Errorf("email %s hasn't been verified", info.Email)
+	}
+	if !p.allowed(info) {
+		return "", fmt.Errorf("user %s (%s) isn't allowed", info.Subject, info.Email)
+	}
+	if info.Email != "" {
+		return info.Email, nil // verified above
+	}
+	if info.Subject != "" {
+		return info.Subject, nil
+	}
+	return "", errors.New("userinfo doesn't have user")
+}
+
+// routes adds login pages in front of handler, which then requires login
+func (a *auth) routes(static http.Handler, renderer *Template, h http.Handler) http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("GET /css/", static)
+	mux.Handle("GET /js/", static)
+	mux.HandleFunc("GET /login", loginGet(renderer, a))
+	mux.HandleFunc("POST /login", loginPost(renderer, a))
+	mux.HandleFunc("GET /login/oidc", oidcGet(a))
+	mux.HandleFunc("GET /login/callback", callbackGet(renderer, a))
+	mux.HandleFunc("POST /logout", logoutPost(a))
+	mux.Handle("/", a.require(h))
+	return mux
+}
@@ -2,150 +2,188 @@ package server
 
 import (
 	"context"
-	"errors"
-	"log/slog"
+	"encoding/json"
+	"fmt"
+	"html/template"
 	"net/http"
 	"net/url"
 	"slices"
-	"strconv"
 	"strings"
+	"time"
 
+	"github.com/jylitalo/mystats/pkg/present"
+	"github.com/jylitalo/mystats/pkg/season"
 	"github.com/jylitalo/mystats/pkg/stats"
 	"github.com/jylitalo/mystats/pkg/telemetry"
+	"github.com/jylitalo/mystats/pkg/units"
+	"github.com/jylitalo/mystats/storage"
 )
 
 type BestFormData struct {
-	Distances map[string]bool
-	InOrder   []string
-	Limit     int
+	Name        string
+	From        string
+	To          string
+	Distances   map[string]bool
+	InOrder     []string
+	Limit       int
+	Progression bool
 }
 
-func newBestFormData(ctx context.Context, db Storage) (*BestFormData, error) {
-	_, span := telemetry.NewSpan(ctx, "server.newBestFormData")
+// newBestFormData reads form from query string. Bare URL shows the shortest distance.
+func newBestFormData(ctx context.Context, db Storage, values url.Values) (BestFormData, error) {
+	ctx, span := telemetry.NewSpan(ctx, "server.newBestFormData")
 	defer span.End()
 	inOrder, err := db.QueryBestEffortDistances(ctx)
 	if err != nil {
-		return nil, telemetry.Error(span, err)
+		return BestFormData{}, telemetry.Error(span, err)
 	}
-	distances := map[string]bool{}
-	for _, d := range inOrder {
-		distances[d] = false
+	checked := values["distance"]
+	if len(values) == 0 && len(inOrder) > 0 {
+		checked = inOrder[:1]
 	}
-	if len(inOrder) > 0 {
-		distances[inOrder[0]] = true
+	limit, err := intValue(values, "limit", 10)
+	if err != nil {
+		return BestFormData{}, err
 	}
-	return &BestFormData{
-		Distances: distances,
-		InOrder:   inOrder,
-		Limit:     10,
-	}, nil
+	limit, err = optionValue(limit, 10, []int{3, 5, 10, 100})
+	return BestFormData{
+		Name:        "best",
+		From:        values.Get("from"),
+		To:          values.Get("to"),
+		Distances:   checkboxes(inOrder, checked),
+		InOrder:     inOrder,
+		Limit:       limit,
+		Progression: values.Get("progression") == "on",
+	}, err
 }
 
-type bestStatsFn func(ctx context.Context, db stats.Storage, distance string, limit int) ([]string, [][]string, error)
+type bestStatsFn func(
+	ctx context.Context, db stats.Storage, distance string, limit int, filters ...storage.QueryOption,
+) ([]stats.BestEffort, error)
+
+type progressionStatsFn func(
+	ctx context.Context, db stats.Storage, distance string, filters ...storage.QueryOption,
+) ([]stats.ProgressionRecord, error)
+
+// BestProgressionData has progression table and step chart for single distance
+type BestProgressionData struct {
+	Distance   string
+	ScriptRows template.JS
+	TableData
+}
 
 type BestData struct {
-	Data  []TableData
-	stats bestStatsFn
+	Data        []TableData
+	Progression []BestProgressionData
 }
 
-func newBestData(ctx context.Context, db Storage, selected []string, limit int, stats bestStatsFn) (*BestData, error) {
+// newBestData has tables for checked distances in order of distance
+func newBestData(ctx context.Context, db Storage, cfg *pageConfig, form BestFormData) (BestData, error) {
 	ctx, span := telemetry.NewSpan(ctx, "server.newBestData")
 	defer span.End()
-	data := []TableData{}
-	if stats == nil {
-		panic("stats is nil in server.newBestData()")
+	data := BestData{Data: []TableData{}, Progression: []BestProgressionData{}}
+	filters, err := dateRangeValues(form.From, form.To)
+	if err != nil {
+		return data, err
+	}
+	// best efforts are runs, so season records follow season of runs
+	progressionFilters := filters
+	if s := season.For(cfg.seasons, []string{"Run"}); !s.IsCalendar() {
+		sm, sd, err := s.MonthDay()
+		if err != nil {
+			return data, telemetry.Error(span, err)
+		}
+		progressionFilters = append(slices.Clone(filters), storage.WithSeason(sm, sd))
 	}
-	for _, distance := range selected {
-		if headers, rows, err := stats(ctx, db, distance, limit); err != nil {
-			return nil, telemetry.Error(span, err)
-		} else {
-			data = append(data, TableData{Headers: headers, Rows: rows})
+	prefs := units.FromContext(ctx)
+	for _, distance := range form.InOrder {
+		if !form.Distances[distance] {
+			continue
+		}
+		efforts, err := cfg.bestStats(ctx, db, distance, form.Limit, filters...)
+		if err != nil {
+			return data, telemetry.Error(span, err)
 		}
+		headers, rows := present.Best(distance, efforts, prefs)
+		data.Data = append(data.Data, TableData{Headers: headers, Rows: rows})
+		if !form.Progression {
+			continue
+		}
+		records, err := cfg.bestProgressions(ctx, db, distance, progressionFilters...)
+		if err != nil {
+			return data, telemetry.Error(span, err)
+		}
+		data.Progression = append(data.Progression, newBestProgressionData(distance, records, prefs))
 	}
-	return &BestData{Data: data, stats: stats}, nil
+	return data, nil
 }
 
 type BestPage struct {
-	Form *BestFormData
-	Data *BestData
+	Form BestFormData
+	Data BestData
 }
 
-func newBestPage(ctx context.Context, db Storage, stats bestStatsFn) (*BestPage, error) {
-	form, err := newBestFormData(ctx, db)
+func newBestPage(ctx context.Context, db Storage, cfg *pageConfig, values url.Values) (*BestPage, error) {
+	form, err := newBestFormData(ctx, db, values)
 	if err != nil {
 		return nil, err
 	}
-	data, err := newBestData(ctx, db, selectedBestEfforts(form.Distances), form.Limit, stats)
+	data, err := newBestData(ctx, db, cfg, form)
 	if err != nil {
 		return nil, err
 	}
-	return &BestPage{
-		Form: form,
-		Data: data,
-	}, nil
-}
-
-func selectedBestEfforts(distances map[string]bool) []string {
-	checked := []string{}
-	for k, v := range distances {
-		if v {
-			checked = append(checked, k)
-		}
-	}
-	return checked
+	return &BestPage{Form: form, Data: data}, nil
 }
 
-func bestEffortValues(values url.Values) (map[string]bool, error) {
-	if values == nil {
-		return nil, errors.New("no bename values given")
-	}
-	bestEfforts := map[string]bool{}
-	for k, v := range values {
-		if strings.HasPrefix(k, "be_") {
-			tv := strings.ReplaceAll(strings.ReplaceAll(k[3:], "_", " "), "X", "/")
-			bestEfforts[tv] = (len(tv) > 0 && v[0] == "on")
-		}
-	}
-	return bestEfforts, nil
-}
-
-func bestPost(ctx context.Context, renderer *Template, page *BestPage, db Storage) http.HandlerFunc {
+func bestGet(renderer *Template, cfg *pageConfig, db Storage) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		var err, errB, errL error
-
-		ctx, span := telemetry.NewSpan(ctx, "bestPOST")
+		ctx, span := telemetry.NewSpan(r.Context(), "bestGET")
 		defer span.End()
-		if err := r.ParseForm(); err != nil {
-			http.Error(w, "Invalid form data", http.StatusBadRequest)
+
+		page, err := newBestPage(ctx, db, cfg, r.URL.Query())
+		if err != nil {
+			status := errorStatus(err)
+			http.Error(w, http.StatusText(status), status)
 			_ = telemetry.Error(span, err)
 			return
 		}
-		page.Form.Distances, errB = bestEffortValues(r.Form)
-		page.Form.Limit, errL = strconv.Atoi(r.FormValue("limit"))
-		if err := errors.Join(err, errB, errL); err != nil {
+		if err := render(w, r, renderer, &Page{Tab: "best", Best: page}, "best-data", page.Data); err != nil {
+			http.Error(w, "Template rendering failed", http.StatusInternalServerError)
 			_ = telemetry.Error(span, err)
-			return
 		}
-		slog.Info("POST /best", "values", r.Form)
-		selected := selectedBestEfforts(page.Form.Distances)
-		page.Data.Data = []TableData{}
-		for _, distance := range page.Form.InOrder {
-			if !slices.Contains(selected, distance) {
-				continue
-			}
-			if headers, rows, err := page.Data.stats(ctx, db, distance, page.Form.Limit); err != nil {
-				_ = telemetry.Error(span, err)
-				return
-			} else {
-				page.Data.Data = append(page.Data.Data, TableData{
-					Headers: headers,
-					Rows:    rows,
-				})
-			}
+	}
+}
+
+// newBestProgressionData draws all-time best as steps, so that it stays flat until next improvement
+func newBestProgressionData(
+	distance string, records []stats.ProgressionRecord, prefs units.Preferences,
+) BestProgressionData {
+	headers, rows := present.Progression(distance, records, prefs)
+	scriptRows := [][]interface{}{}
+	newDate := func(t time.Time) template.JS {
+		// Month in JavaScript's Date is 0-indexed
+		return template.JS(fmt.Sprintf("new Date(%d, %d, %d)", t.Year(), t.Month()-1, t.Day())) // #nosec G203
+	}
+	best := 0.0
+	for _, r := range records {
+		if !r.AllTime {
+			continue
 		}
-		if err := renderer.tmpl.ExecuteTemplate(w, "best-data", page.Data); err != nil {
-			_ = telemetry.Error(span, err)
-			http.Error(w, "Template rendering failed", http.StatusInternalServerError)
+		date := newDate(r.Date)
+		minutes := r.ElapsedTime.Minutes()
+		if best > 0 {
+			scriptRows = append(scriptRows, []interface{}{date, best})
 		}
+		scriptRows = append(scriptRows, []interface{}{date, minutes})
+		best = minutes
+	}
+	if best > 0 {
+		scriptRows = append(scriptRows, []interface{}{newDate(time.Now()), best})
+	}
+	byteRows, _ := json.Marshal(scriptRows)
+	return BestProgressionData{
+		Distance:   distance,
+		ScriptRows: template.JS(strings.ReplaceAll(string(byteRows), `"`, ``)), // #nosec G203
+		TableData:  TableData{Headers: headers, Rows: rows},
 	}
 }
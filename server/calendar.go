@@ -0,0 +1,177 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jylitalo/mystats/pkg/chart"
+	"github.com/jylitalo/mystats/pkg/present"
+	"github.com/jylitalo/mystats/pkg/stats"
+	"github.com/jylitalo/mystats/pkg/telemetry"
+	"github.com/jylitalo/mystats/pkg/units"
+	"github.com/jylitalo/mystats/storage"
+)
+
+type calendarStatsFn func(
+	ctx context.Context, db stats.Storage, measure string, year int, sports, workouts []string,
+	filters ...storage.QueryOption,
+) (*stats.CalendarResult, error)
+
+type CalendarFormData struct {
+	Name           string
+	Year           int
+	YearOptions    []int
+	Measure        string
+	MeasureOptions []string
+	FilterFormData
+}
+
+// newCalendarFormData reads form from query string. Bare URL has distance of current year.
+func newCalendarFormData(
+	ctx context.Context, db Storage, cfg *pageConfig, values url.Values,
+) (CalendarFormData, error) {
+	filters, err := newFilterFormData(ctx, db, cfg, values, currentYear)
+	if err != nil {
+		return CalendarFormData{}, err
+	}
+	years, err := db.QueryYears(ctx, storage.SummaryTable)
+	if err != nil {
+		return CalendarFormData{}, err
+	}
+	now := time.Now().Year()
+	if !slices.Contains(years, now) {
+		years = append(years, now)
+	}
+	slices.Sort(years)
+	form := CalendarFormData{
+		Name:           "calendar",
+		YearOptions:    years,
+		MeasureOptions: append(stats.Measures(), stats.StepsMeasure),
+		FilterFormData: filters,
+	}
+	year, err := intValue(values, "year", now)
+	if err != nil {
+		return form, err
+	}
+	var errY, errM error
+	form.Year, errY = optionValue(year, now, years)
+	form.Measure, errM = optionValue(values.Get("measure"), "distance", form.MeasureOptions)
+	return form, errors.Join(errY, errM)
+}
+
+type CalendarData struct {
+	Date  string        // selected day, YYYY-MM-DD
+	Chart template.HTML // inline, so that days have links and tooltips
+	Day   TableData     // activities of selected day
+}
+
+type CalendarPage struct {
+	Form CalendarFormData
+	Data CalendarData
+}
+
+func newCalendarPage(ctx context.Context, db Storage, cfg *pageConfig, values url.Values) (*CalendarPage, error) {
+	form, err := newCalendarFormData(ctx, db, cfg, values)
+	if err != nil {
+		return nil, err
+	}
+	page := &CalendarPage{Form: form, Data: CalendarData{Date: values.Get("date"), Day: newTableData()}}
+	var selected time.Time
+	if page.Data.Date != "" {
+		if selected, err = time.Parse(time.DateOnly, page.Data.Date); err != nil || selected.Year() != form.Year {
+			return nil, fmt.Errorf("%w: date %s", errInvalidForm, page.Data.Date)
+		}
+	}
+	sports, workouts, _, filters, err := form.filters()
+	if err != nil {
+		return nil, err
+	}
+	result, err := cfg.calendarStats(ctx, db, form.Measure, form.Year, sports, workouts, filters...)
+	if err != nil {
+		return nil, err
+	}
+	activities, err := cfg.listStats(ctx, db, sports, workouts, []int{form.Year}, 0, "", filters...)
+	if err != nil {
+		return nil, err
+	}
+	var b bytes.Buffer
+	if err = writeCalendar(ctx, &b, result, activities, values); err != nil {
+		return nil, err
+	}
+	page.Data.Chart = template.HTML(b.String()) //nolint:gosec // SVG is escaped by chart package
+	if !selected.IsZero() {
+		day := []stats.Activity{}
+		for _, a := range activities {
+			if a.Date.Equal(selected) {
+				day = append(day, a)
+			}
+		}
+		page.Data.Day.Headers, page.Data.Day.Rows = present.List(day, units.FromContext(ctx))
+	}
+	return page, nil
+}
+
+// writeCalendar draws heatmap, where every day links to the same page with the day selected
+func writeCalendar(
+	ctx context.Context, w io.Writer, result *stats.CalendarResult, activities []stats.Activity, values url.Values,
+) error {
+	system := units.FromContext(ctx).System
+	format := func(value float64) string {
+		return strings.TrimSpace(present.Value(result.Measure, system, value))
+	}
+	first := time.Date(result.Year, time.January, 1, 0, 0, 0, 0, time.UTC)
+	link := func(day int) string {
+		query := url.Values{}
+		for k, v := range values {
+			query[k] = v
+		}
+		query.Set("year", fmt.Sprint(result.Year))
+		query.Set("date", first.AddDate(0, 0, day).Format(time.DateOnly))
+		return "/calendar?" + query.Encode()
+	}
+	extras := chart.CalendarExtras{
+		Months: result.Months, Weeks: result.Weeks, Notes: present.CalendarNotes(result.Year, activities), Link: link,
+	}
+	title := fmt.Sprintf("%s %d", present.Header(result.Measure, system), result.Year)
+	return chart.CalendarSVG(w, title, result.Year, result.Days, format, extras)
+}
+
+// calendarSVG draws heatmap of calendar page
+func calendarSVG(cfg *pageConfig, db Storage) drawFn {
+	return func(ctx context.Context, w io.Writer, values url.Values) error {
+		page, err := newCalendarPage(ctx, db, cfg, values)
+		if err != nil {
+			return err
+		}
+		_, err = io.WriteString(w, string(page.Data.Chart))
+		return err
+	}
+}
+
+func calendarGet(renderer *Template, cfg *pageConfig, db Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, span := telemetry.NewSpan(r.Context(), "calendarGET")
+		defer span.End()
+
+		page, err := newCalendarPage(ctx, db, cfg, r.URL.Query())
+		if err != nil {
+			status := errorStatus(err)
+			http.Error(w, http.StatusText(status), status)
+			_ = telemetry.Error(span, err)
+			return
+		}
+		if err := render(w, r, renderer, &Page{Tab: "calendar", Calendar: page}, "calendar-data", page.Data); err != nil {
+			http.Error(w, "Template rendering failed", http.StatusInternalServerError)
+			_ = telemetry.Error(span, err)
+		}
+	}
+}
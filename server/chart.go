@@ -0,0 +1,152 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/jylitalo/mystats/pkg/chart"
+	"github.com/jylitalo/mystats/pkg/present"
+	"github.com/jylitalo/mystats/pkg/stats"
+	"github.com/jylitalo/mystats/pkg/telemetry"
+	"github.com/jylitalo/mystats/pkg/units"
+)
+
+// sizes of SVG charts
+var (
+	lineSize = chart.Size{Width: 1000, Height: 500}
+	barSize  = chart.Size{Width: 1000, Height: 350}
+)
+
+// drawFn draws chart from the same query string as page has
+type drawFn func(ctx context.Context, w io.Writer, values url.Values) error
+
+// chartGet serves chart as SVG. Chart is drawn into buffer, so that errors can still change status code.
+func chartGet(name string, draw drawFn) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, span := telemetry.NewSpan(r.Context(), name)
+		defer span.End()
+
+		var b bytes.Buffer
+		if err := draw(ctx, &b, r.URL.Query()); err != nil {
+			status := errorStatus(err)
+			http.Error(w, http.StatusText(status), status)
+			_ = telemetry.Error(span, err)
+			return
+		}
+		w.Header().Set("Content-Type", "image/svg+xml")
+		_, _ = b.WriteTo(w)
+	}
+}
+
+func dayLabel(first time.Time) func(int) string {
+	return func(day int) string {
+		return first.AddDate(0, 0, day).Format("Jan 02")
+	}
+}
+
+// plotSVG draws cumulative values of plot page
+func plotSVG(cfg *pageConfig, db Storage) drawFn {
+	return func(ctx context.Context, w io.Writer, values url.Values) error {
+		page, err := newPlotPage(ctx, db, cfg, values)
+		if err != nil {
+			return err
+		}
+		m, err := stats.MeasureByName(page.Data.Measure)
+		if err != nil {
+			return err
+		}
+		system := units.FromContext(ctx).System
+		unit := present.Unit(m, system)
+		format := func(value float64) string {
+			return fmt.Sprintf("%.0f%s", value, unit)
+		}
+		d := page.Data
+		return chart.LinesSVG(w, lineSize, present.Header(m, system), d.lines, dayLabel(d.first), format)
+	}
+}
+
+// statsSVG draws table of plot page as bars
+func statsSVG(cfg *pageConfig, db Storage) drawFn {
+	return func(ctx context.Context, w io.Writer, values url.Values) error {
+		page, err := newPlotPage(ctx, db, cfg, values)
+		if err != nil {
+			return err
+		}
+		result := page.Data.result
+		if result == nil {
+			result = &stats.StatsResult{Period: page.Data.Period}
+			if result.Measure, err = stats.MeasureByName(page.Data.Measure); err != nil {
+				return err
+			}
+		}
+		system := units.FromContext(ctx).System
+		format := func(value float64) string {
+			return strings.TrimSpace(present.Value(result.Measure, system, value))
+		}
+		labels, series := present.StatsSeries(result, page.Data.season)
+		return chart.BarsSVG(w, barSize, present.Header(result.Measure, system), labels, series, format)
+	}
+}
+
+// stepsSVG draws cumulative steps of steps page
+func stepsSVG(cfg *pageConfig, db Storage) drawFn {
+	return func(ctx context.Context, w io.Writer, values url.Values) error {
+		page, err := newStepsPage(ctx, db, cfg, values)
+		if err != nil {
+			return err
+		}
+		format := func(value float64) string {
+			return fmt.Sprintf("%.0fk", value/1000)
+		}
+		d := page.Data
+		return chart.LinesSVG(w, lineSize, "Steps", d.lines, dayLabel(d.first), format)
+	}
+}
+
+// stepsCalendarSVG draws daily steps of the latest year in steps page as heatmap
+func stepsCalendarSVG(cfg *pageConfig, db Storage) drawFn {
+	return func(ctx context.Context, w io.Writer, values url.Values) error {
+		page, err := newStepsPage(ctx, db, cfg, values)
+		if err != nil {
+			return err
+		}
+		year, daily := time.Now().Year(), []float64{}
+		if d := page.Data; len(d.lines) > 0 {
+			// lines are cumulative and sorted by year
+			cumulative := d.lines[len(d.lines)-1].Values
+			year = d.first.Year()
+			daily = make([]float64, len(cumulative))
+			for day, value := range cumulative {
+				daily[day] = value
+				if day > 0 {
+					daily[day] -= cumulative[day-1]
+				}
+			}
+		}
+		format := func(value float64) string {
+			return fmt.Sprintf("%.0f steps", value)
+		}
+		return chart.CalendarSVG(w, fmt.Sprintf("Daily steps %d", year), year, daily, format, chart.CalendarExtras{})
+	}
+}
+
+// heartrateSVG draws resting heart rate of heartrate page
+func heartrateSVG(db Storage) drawFn {
+	return func(ctx context.Context, w io.Writer, values url.Values) error {
+		page, err := newHeartRatePage(ctx, db, values)
+		if err != nil {
+			return err
+		}
+		d := page.Data
+		format := func(value float64) string {
+			return fmt.Sprintf("%.0f", value)
+		}
+		return chart.LinesSVG(w, lineSize, "Resting heart rate (bpm)", d.lines, dayLabel(d.first), format)
+	}
+}
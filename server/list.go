@@ -2,172 +2,82 @@ package server
 
 import (
 	"context"
-	"errors"
-	"fmt"
-	"log/slog"
 	"net/http"
-	"strconv"
-	"time"
+	"net/url"
 
+	"github.com/jylitalo/mystats/pkg/present"
 	"github.com/jylitalo/mystats/pkg/stats"
 	"github.com/jylitalo/mystats/pkg/telemetry"
+	"github.com/jylitalo/mystats/pkg/units"
 	"github.com/jylitalo/mystats/storage"
 )
 
 type ListFormData struct {
-	Name     string
-	Sports   map[string]bool
-	Workouts map[string]bool
-	Years    map[int]bool
-	Limit    int
+	Name   string
+	Search string // activity name (SQL LIKE pattern)
+	Limit  int
+	FilterFormData
 }
 
-func newListFormData(years []int, sports, workouts map[string]bool) ListFormData {
-	yearSelection := map[int]bool{}
-	currentYear := time.Now().Year()
-	for _, y := range years {
-		yearSelection[y] = false
+// newListFormData reads form from query string. Bare URL lists activities of current year.
+func newListFormData(ctx context.Context, db Storage, cfg *pageConfig, values url.Values) (ListFormData, error) {
+	filters, err := newFilterFormData(ctx, db, cfg, values, currentYear)
+	if err != nil {
+		return ListFormData{}, err
 	}
-	if _, ok := yearSelection[currentYear]; ok {
-		yearSelection[currentYear] = true
+	limit, err := intValue(values, "limit", 1000)
+	if err != nil {
+		return ListFormData{}, err
 	}
+	limit, err = optionValue(limit, 1000, []int{10, 20, 100, 1000})
 	return ListFormData{
-		Name:     "list",
-		Sports:   sports,
-		Workouts: workouts,
-		Years:    yearSelection,
-		Limit:    1000,
-	}
-}
-
-type ListEventData struct {
-	Name string
-	Date string
-	TableData
+		Name:           "list",
+		Search:         values.Get("search"),
+		Limit:          limit,
+		FilterFormData: filters,
+	}, err
 }
 
 type listStatsFn func(
 	ctx context.Context, db stats.Storage, sports, workouts []string,
-	years []int, limit int, name string) ([]string, [][]string, error)
+	years []int, limit int, name string, filters ...storage.QueryOption) ([]stats.Activity, error)
 
 type ListPage struct {
-	Form  ListFormData
-	Data  TableData
-	Event ListEventData
-	stats listStatsFn
+	Form ListFormData
+	Data TableData
 }
 
-func newListPage(
-	ctx context.Context, db Storage, years []int,
-	sports, workouts map[string]bool, stats listStatsFn,
-) (*ListPage, error) {
-	var err error
-
-	form := newListFormData(years, sports, workouts)
-	data := newTableData()
-	data.Headers, data.Rows, err = stats(
-		ctx, db, selectedSports(sports), selectedWorkouts(workouts),
-		selectedYears(form.Years), form.Limit, "",
-	)
+func newListPage(ctx context.Context, db Storage, cfg *pageConfig, values url.Values) (*ListPage, error) {
+	form, err := newListFormData(ctx, db, cfg, values)
 	if err != nil {
 		return nil, err
 	}
-	return &ListPage{
-		Form: form,
-		Data: data,
-		Event: ListEventData{
-			Name:      "",
-			TableData: newTableData(),
-		},
-		stats: stats,
-	}, err
-}
-
-func listPost(ctx context.Context, renderer *Template, page *ListPage, db Storage) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		var err error
-
-		ctx, span := telemetry.NewSpan(ctx, "listPOST")
-		defer span.End()
-
-		if err := r.ParseForm(); err != nil {
-			http.Error(w, "Invalid form data", http.StatusBadRequest)
-			_ = telemetry.Error(span, err)
-			return
-		}
-		values := r.Form
-		sports, errT := sportsValues(values)
-		workouts, errW := workoutsValues(values)
-		years, errY := yearValues(values)
-		limit, errL := strconv.Atoi(r.FormValue("limit"))
-		name := r.FormValue("name")
-		if err = errors.Join(errT, errW, errY, errL); err != nil {
-			http.Error(w, "Error with arguments", http.StatusBadRequest)
-			slog.Error("server.listPost()", "err", err)
-			_ = telemetry.Error(span, err)
-		}
-		slog.Info("POST /list", "values", values)
-		page.Form.Years = years
-		page.Data.Headers, page.Data.Rows, err = page.stats(
-			ctx, db, selectedSports(sports), selectedWorkouts(workouts),
-			selectedYears(years), limit, name,
-		)
-		if err != nil {
-			http.Error(w, "Failed to build page", http.StatusInternalServerError)
-			_ = telemetry.Error(span, err)
-		}
-		if err := renderer.tmpl.ExecuteTemplate(w, "list-data", page.Data); err != nil {
-			http.Error(w, "Template rendering failed", http.StatusInternalServerError)
-			_ = telemetry.Error(span, err)
-		}
+	sports, workouts, years, filters, err := form.filters()
+	if err != nil {
+		return nil, err
+	}
+	activities, err := cfg.listStats(ctx, db, sports, workouts, years, form.Limit, form.Search, filters...)
+	if err != nil {
+		return nil, err
 	}
+	data := newTableData()
+	data.Headers, data.Rows = present.List(activities, units.FromContext(ctx))
+	return &ListPage{Form: form, Data: data}, nil
 }
 
-func listEvent(ctx context.Context, renderer *Template, page *ListPage, db Storage) http.HandlerFunc {
+func listGet(renderer *Template, cfg *pageConfig, db Storage) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		ctx, span := telemetry.NewSpan(ctx, "eventPOST")
+		ctx, span := telemetry.NewSpan(r.Context(), "listGET")
 		defer span.End()
 
-		if err := r.ParseForm(); err != nil {
-			http.Error(w, "Invalid form data", http.StatusBadRequest)
-			_ = telemetry.Error(span, err)
-			return
-		}
-
-		id, err := strconv.Atoi(r.FormValue("id"))
+		page, err := newListPage(ctx, db, cfg, r.URL.Query())
 		if err != nil {
+			status := errorStatus(err)
+			http.Error(w, http.StatusText(status), status)
 			_ = telemetry.Error(span, err)
 			return
 		}
-		rows, err := db.Query(
-			ctx,
-			[]string{"name", "year", "month", "day"},
-			storage.WithTable(storage.SummaryTable),
-			storage.WithStravaID(int64(id)),
-		)
-		if err != nil {
-			_ = telemetry.Error(span, err)
-			return
-		}
-		defer func() { _ = rows.Close() }()
-		if !rows.Next() {
-			http.Error(w, "Unable to find activity", http.StatusBadRequest)
-			_ = telemetry.Error(span, fmt.Errorf("listEvent was unable to find activity %d", id))
-			return
-		}
-		var year, month, day int
-		if err = rows.Scan(&page.Event.Name, &year, &month, &day); err != nil {
-			http.Error(w, "Unable to find activity", http.StatusBadRequest)
-			_ = telemetry.Error(span, err)
-			return
-		}
-		page.Event.Date = fmt.Sprintf("%d.%d.%d", day, month, year)
-		page.Event.Headers, page.Event.Rows, err = stats.Split(ctx, db, int64(id))
-		if err != nil {
-			http.Error(w, "Failed to build page", http.StatusInternalServerError)
-			_ = telemetry.Error(span, err)
-		}
-		if err := renderer.tmpl.ExecuteTemplate(w, "list-event", page.Event); err != nil {
+		if err := render(w, r, renderer, &Page{Tab: "list", List: page}, "list-data", page.Data); err != nil {
 			http.Error(w, "Template rendering failed", http.StatusInternalServerError)
 			_ = telemetry.Error(span, err)
 		}
@@ -2,23 +2,21 @@ package server
 
 import (
 	"context"
-	"database/sql"
-	"encoding/json"
 	"errors"
-	"fmt"
-	"html/template"
-	"log"
 	"log/slog"
 	"maps"
 	"net/http"
+	"net/url"
 	"slices"
-	"strconv"
-	"strings"
 	"time"
 
+	"github.com/jylitalo/mystats/pkg/chart"
 	"github.com/jylitalo/mystats/pkg/data"
+	"github.com/jylitalo/mystats/pkg/present"
+	"github.com/jylitalo/mystats/pkg/season"
 	"github.com/jylitalo/mystats/pkg/stats"
 	"github.com/jylitalo/mystats/pkg/telemetry"
+	"github.com/jylitalo/mystats/pkg/units"
 	"github.com/jylitalo/mystats/storage"
 )
 
@@ -30,54 +28,47 @@ type PlotFormData struct {
 	MeasureOptions []string
 	Period         string
 	PeriodOptions  []string
-	Sports         map[string]bool
-	Workouts       map[string]bool
-	Years          map[int]bool
+	FilterFormData
 }
 
-func newPlotFormData(years []int, sports, workouts map[string]bool) PlotFormData {
-	yearSelection := map[int]bool{}
-	for _, y := range years {
-		yearSelection[y] = true
+// newPlotFormData reads form from query string. Bare URL plots distance of all years until today.
+func newPlotFormData(ctx context.Context, db Storage, cfg *pageConfig, values url.Values) (PlotFormData, error) {
+	filters, err := newFilterFormData(ctx, db, cfg, values, allYears)
+	if err != nil {
+		return PlotFormData{}, err
 	}
-	t := time.Now()
-	return PlotFormData{
+	form := PlotFormData{
 		Name:           "plot",
-		EndMonth:       int(t.Month()),
-		EndDay:         t.Day(),
-		Measure:        "distance",
-		MeasureOptions: []string{"distance", "elevation", "time"},
-		Period:         "month",
+		MeasureOptions: stats.CumulativeMeasures(),
 		PeriodOptions:  []string{"month", "week"},
-		Sports:         sports,
-		Workouts:       workouts,
-		Years:          yearSelection,
+		FilterFormData: filters,
 	}
+	var errD, errM, errP error
+	form.EndMonth, form.EndDay, errD = monthDayValues(values)
+	form.Measure, errM = optionValue(values.Get("measure"), "distance", form.MeasureOptions)
+	form.Period, errP = optionValue(values.Get("period"), "month", form.PeriodOptions)
+	return form, errors.Join(errD, errM, errP)
 }
 
 type plotStatsFn func(
 	ctx context.Context, db stats.Storage, measure, period string, sports, workouts []string,
-	month, day int, years []int,
-) ([]int, [][]string, []string, error)
+	month, day int, years []int, filters ...storage.QueryOption,
+) (*stats.StatsResult, error)
 
 type PlotData struct {
-	Years         []int
-	Measure       string
-	Stats         [][]string
-	Totals        []string
-	ScriptColumns []int
-	ScriptRows    template.JS
-	ScriptColors  template.JS
-	Period        string
-	stats         plotStatsFn
-}
-
-func newPlotData(stats plotStatsFn, period string) PlotData {
-	return PlotData{
-		Measure: "distance",
-		Period:  period,
-		stats:   stats,
-	}
+	Years   []int
+	Labels  []string
+	Order   []int
+	Measure string
+	Unit    string
+	Stats   [][]string
+	Totals  []string
+	Period  string
+	Query   string
+	lines   []chart.Series
+	first   time.Time
+	result  *stats.StatsResult
+	season  season.Season
 }
 
 type PlotPage struct {
@@ -85,47 +76,39 @@ type PlotPage struct {
 	Form PlotFormData
 }
 
-func newPlotPage(
-	ctx context.Context, db Storage, years []int,
-	sports, workouts map[string]bool, stats plotStatsFn,
-) (*PlotPage, error) {
-	form := newPlotFormData(years, sports, workouts)
+func newPlotPage(ctx context.Context, db Storage, cfg *pageConfig, values url.Values) (*PlotPage, error) {
+	form, err := newPlotFormData(ctx, db, cfg, values)
+	if err != nil {
+		return nil, err
+	}
 	page := &PlotPage{
 		Form: form,
-		Data: newPlotData(stats, form.Period),
+		Data: PlotData{Measure: form.Measure, Period: form.Period, Query: values.Encode()},
 	}
-	return page, page.render(
-		ctx, db, selectedSports(sports), selectedWorkouts(workouts),
-		form.EndMonth, form.EndDay, form.Years, form.Period,
-	)
+	return page, page.render(ctx, db, cfg)
 }
 
-func (p *PlotPage) render(
-	ctx context.Context, db Storage, sports, workouts []string, month, day int,
-	years map[int]bool, period string,
-) error {
+func (p *PlotPage) render(ctx context.Context, db Storage, cfg *pageConfig) error {
 	ctx, span := telemetry.NewSpan(ctx, "plot.render")
 	defer span.End()
 
-	colors := []string{
-		"#0000ff", // 1
-		"#00ff00", // 2
-		"#ff0000", // 3
-		"#00ffff", // 4
-		"#ffff00", // 5
-		"#ff00ff", // 6
-		"#000088", // 7
-		"#008800", // 8
-		"#880000", // 9
-		"#00f000",
-		"#0000f0",
+	sports, workouts, checkedYears, filters, err := p.Form.filters()
+	if err != nil {
+		return err
 	}
-	p.Form.EndMonth = month
-	p.Form.EndDay = day
-	p.Form.Years = years
-	checkedYears := selectedYears(years)
+	month, day, period := p.Form.EndMonth, p.Form.EndDay, p.Form.Period
 	d := &p.Data
-	measured, err := getNumbers(ctx, db, sports, workouts, d.Measure, month, day, checkedYears)
+	groupNames, _ := selectedGroups(p.Form.Groups)
+	s := season.For(cfg.seasons, slices.Concat(sports, groupNames))
+	d.season = s
+	if !s.IsCalendar() {
+		sm, sd, err := s.MonthDay()
+		if err != nil {
+			return err
+		}
+		filters = append(filters, storage.WithSeason(sm, sd))
+	}
+	measured, err := getNumbers(ctx, db, s, sports, workouts, d.Measure, month, day, checkedYears, filters...)
 	if err != nil {
 		slog.Error("failed to plot", "err", err)
 		return err
@@ -136,165 +119,76 @@ func (p *PlotPage) render(
 		return nil
 	}
 	slices.Sort(foundYears)
-	refTime, err := time.Parse(time.DateOnly, fmt.Sprintf("%d-01-01", slices.Max(foundYears)))
+	labels := s.Labels(foundYears)
+	d.lines = make([]chart.Series, len(foundYears))
+	for idx, year := range foundYears {
+		d.lines[idx] = chart.Series{Name: labels[idx], Values: measured[year]}
+	}
+	d.first = s.First(slices.Max(foundYears), time.UTC)
+	result, err := cfg.plotStats(ctx, db, d.Measure, period, sports, workouts, month, day, foundYears, filters...)
 	if err != nil {
+		slog.Error("failed to calculate stats", "err", err)
 		return err
 	}
-	scriptRows := [][]interface{}{}
-	for day := range measured[foundYears[0]] {
-		scriptRows = append(scriptRows, make([]interface{}, len(foundYears)+1))
-		index0 := refTime.Add(24 * time.Duration(day) * time.Hour)
-		// Month in JavaScript's Date is 0-indexed
-		newDate := fmt.Sprintf("new Date(%d, %d, %d)", index0.Year(), index0.Month()-1, index0.Day())
-		scriptRows[day][0] = template.JS(newDate) // #nosec G203
-		for idx, year := range foundYears {
-			scriptRows[day][idx+1] = measured[year][day]
+	d.Years, d.Stats, d.Totals, d.Unit = nil, nil, nil, ""
+	if result != nil {
+		prefs := units.FromContext(ctx)
+		d.Years = result.Years
+		d.Stats, d.Totals = present.Stats(result, prefs)
+		d.Unit = present.Unit(result.Measure, prefs.System)
+		d.result = result
+	}
+	d.Labels = s.Labels(d.Years)
+	d.Order = []int{}
+	for _, period := range s.Order(period) {
+		if period <= len(d.Stats) {
+			d.Order = append(d.Order, period)
 		}
 	}
-	byteRows, _ := json.Marshal(scriptRows)
-	byteColors, _ := json.Marshal(colors[0:len(foundYears)])
-	p.Data.ScriptColumns = foundYears
-	p.Data.ScriptRows = template.JS(strings.ReplaceAll(string(byteRows), `"`, ``)) // #nosec G203
-	p.Data.ScriptColors = template.JS(byteColors)                                  // #nosec G203
-	measure := d.Measure
-	if measure == "time" {
-		measure = "elapsedtime"
-	}
-	d.Years, d.Stats, d.Totals, err = d.stats(
-		ctx, db, "sum("+measure+")", period, sports, workouts, month, day, foundYears,
-	)
-	if err != nil {
-		slog.Error("failed to calculate stats", "err", err)
-	}
-	return err
+	return nil
 }
 
 type numbers map[int][]float64
 
-func plotPost(ctx context.Context, renderer *Template, page *PlotPage, db Storage) http.HandlerFunc {
+func plotGet(renderer *Template, cfg *pageConfig, db Storage) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		_, span := telemetry.NewSpan(ctx, "plotPOST")
+		ctx, span := telemetry.NewSpan(r.Context(), "plotGET")
 		defer span.End()
-		if err := r.ParseForm(); err != nil {
-			http.Error(w, "Invalid form data", http.StatusBadRequest)
-			_ = telemetry.Error(span, err)
-			return
-		}
-		month, errM := strconv.Atoi(r.FormValue("EndMonth"))
-		day, errD := strconv.Atoi(r.FormValue("EndDay"))
-		page.Form.Measure = r.FormValue("Measure")
-		page.Data.Measure = page.Form.Measure
-		page.Data.Period = r.FormValue("Period")
-		page.Form.Period = page.Data.Period
-		values := r.Form
-		sports, errS := sportsValues(values)
-		workouts, errW := workoutsValues(values)
-		years, errY := yearValues(values)
-		if err := errors.Join(errM, errD, errS, errW, errY); err != nil {
-			_ = telemetry.Error(span, err)
-			return
-		}
-		slog.Info("POST /plot", "values", values)
-		err := page.render(
-			ctx, db, selectedSports(sports), selectedWorkouts(workouts),
-			month, day, years, page.Data.Period,
-		)
+
+		page, err := newPlotPage(ctx, db, cfg, r.URL.Query())
 		if err != nil {
+			status := errorStatus(err)
+			http.Error(w, http.StatusText(status), status)
 			_ = telemetry.Error(span, err)
 			return
 		}
-		if err := renderer.tmpl.ExecuteTemplate(w, "plot-data", page.Data); err != nil {
-			_ = telemetry.Error(span, err)
+		if err := render(w, r, renderer, &Page{Tab: "plot", Plot: page}, "plot-data", page.Data); err != nil {
 			http.Error(w, "Template rendering failed", http.StatusInternalServerError)
-			return
-		}
-	}
-}
-
-func cumulativeScan(rows *sql.Rows, years []int) (numbers, error) {
-	tz, _ := time.LoadLocation("Europe/Helsinki")
-	day1 := map[int]time.Time{}
-	// ys is map, where key is year and array has entry for each day of the year
-	ys := map[int][]float64{}
-	previous_y := map[int]float64{}
-	for _, year := range years {
-		day1[year] = time.Date(year, time.January, 1, 6, 0, 0, 0, tz)
-		ys[year] = []float64{}
-		previous_y[year] = 0
-	}
-	max_acts := 0
-	if rows == nil {
-		return ys, nil
-	}
-	for rows.Next() { // scan through database rows
-		var year, month, day int
-		var value float64
-		if err := rows.Scan(&year, &month, &day, &value); err != nil {
-			return ys, err
-		}
-		now := time.Date(year, time.Month(month), day, 6, 0, 0, 0, tz) // time when activity happened
-		days := int(now.Sub(day1[year]).Hours()/24) + 1                // day within a year (1-365)
-		if days > 366 {
-			log.Fatalf(
-				"days got impossible number %d (year=%d, month=%d, day=%d, now=%#v, day1=%#v)",
-				days, year, month, day, now, day1[year],
-			)
-		}
-		yslen := len(ys[year])
-		for x := yslen; x < days-1; x++ { // fill the gaps on days that didn't have activities
-			ys[year] = append(ys[year], previous_y[year])
-		}
-		max_acts = max(max_acts, days)
-		previous_y[year] += value
-		ys[year] = append(ys[year], previous_y[year])
-	}
-	for _, year := range years { // fill the end of year
-		yslen := len(ys[year])
-		for x := yslen; x < max_acts; x++ {
-			ys[year] = append(ys[year], previous_y[year])
+			_ = telemetry.Error(span, err)
 		}
 	}
-	return ys, nil
 }
 
 func getNumbers(
-	ctx context.Context, db Storage, sports, workouts []string, measure string,
-	month, day int, years []int,
+	ctx context.Context, db Storage, s season.Season, sports, workouts []string, measure string,
+	month, day int, years []int, filters ...storage.QueryOption,
 ) (numbers, error) {
-	_, span := telemetry.NewSpan(ctx, "server.getNumbers")
+	ctx, span := telemetry.NewSpan(ctx, "server.getNumbers")
 	defer span.End()
-	o := []string{"year", "month", "day"}
-	opts := []storage.QueryOption{
-		storage.WithTable(storage.SummaryTable),
-		storage.WithDayOfYear(day, month),
-		storage.WithOrder(storage.OrderConfig{GroupBy: o, OrderBy: o}),
-	}
-	opts = append(opts, storage.WithSports(sports...))
-	opts = append(opts, storage.WithWorkouts(workouts...))
-	opts = append(opts, storage.WithYears(years...))
-	var m string
-	switch measure {
-	case "time":
-		m = "sum(elapsedtime)/3600"
-	case "distance":
-		m = "sum(distance)/1000"
-	case "elevation":
-		m = "sum(elevation)"
+	m, err := stats.MeasureByName(measure)
+	if err != nil {
+		return nil, telemetry.Error(span, err)
 	}
-	rows, err := db.Query(ctx, append(o, m), opts...)
+	measured, err := stats.Cumulative(ctx, db, s, measure, sports, workouts, month, day, years, filters...)
 	if err != nil {
-		return nil, fmt.Errorf("select caused: %w", err)
+		return nil, telemetry.Error(span, err)
 	}
-	defer func() {
-		if rows != nil {
-			if err := rows.Close(); err != nil {
-				_ = telemetry.Error(span, err)
-			}
+	// sums are in base units, conversion is linear so cumulative sums can be converted afterwards
+	system := units.FromContext(ctx).System
+	for _, values := range measured {
+		for idx := range values {
+			values[idx] = present.Convert(m, system, values[idx])
 		}
-	}()
-	foundYears, err := db.QueryYears(ctx, opts...)
-	if err != nil {
-		return nil, err
 	}
-	return cumulativeScan(rows, foundYears)
+	return measured, nil
 }
@@ -0,0 +1,150 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jylitalo/mystats/pkg/present"
+	"github.com/jylitalo/mystats/pkg/stats"
+	"github.com/jylitalo/mystats/pkg/telemetry"
+	"github.com/jylitalo/mystats/pkg/units"
+	"github.com/jylitalo/mystats/storage"
+)
+
+type RollingFormData struct {
+	Name           string
+	Average        bool
+	Measure        string
+	MeasureOptions []string
+	Window         int
+	WindowOptions  []int
+	FilterFormData
+}
+
+// newRollingFormData reads form from query string. Bare URL has 28 day distance of all years.
+func newRollingFormData(
+	ctx context.Context, db Storage, cfg *pageConfig, values url.Values,
+) (RollingFormData, error) {
+	filters, err := newFilterFormData(ctx, db, cfg, values, allYears)
+	if err != nil {
+		return RollingFormData{}, err
+	}
+	form := RollingFormData{
+		Name:           "rolling",
+		Average:        values.Get("average") == "on",
+		MeasureOptions: stats.RollingMeasures(),
+		WindowOptions:  stats.RollingWindows(),
+		FilterFormData: filters,
+	}
+	window, errW := intValue(values, "window", 28)
+	form.Window, err = optionValue(window, 28, form.WindowOptions)
+	var errM error
+	form.Measure, errM = optionValue(values.Get("measure"), "distance", form.MeasureOptions)
+	return form, errors.Join(errW, err, errM)
+}
+
+type rollingStatsFn func(
+	ctx context.Context, db stats.Storage, measure string, window int, average bool,
+	sports, workouts []string, years []int, filters ...storage.QueryOption,
+) ([]int, map[int][]float64, error)
+
+type RollingData struct {
+	Measure       string
+	ScriptColumns []int
+	ScriptRows    template.JS
+}
+
+type RollingPage struct {
+	Data RollingData
+	Form RollingFormData
+}
+
+func newRollingPage(ctx context.Context, db Storage, cfg *pageConfig, values url.Values) (*RollingPage, error) {
+	form, err := newRollingFormData(ctx, db, cfg, values)
+	if err != nil {
+		return nil, err
+	}
+	page := &RollingPage{
+		Form: form,
+		Data: RollingData{Measure: form.Measure},
+	}
+	return page, page.render(ctx, db, cfg.rollingStats)
+}
+
+func (p *RollingPage) render(ctx context.Context, db Storage, rolling rollingStatsFn) error {
+	ctx, span := telemetry.NewSpan(ctx, "rolling.render")
+	defer span.End()
+
+	sports, workouts, checkedYears, filters, err := p.Form.filters()
+	if err != nil {
+		return err
+	}
+	m, _, err := stats.RollingMeasure(p.Form.Measure)
+	if err != nil {
+		return telemetry.Error(span, err)
+	}
+	years, values, err := rolling(
+		ctx, db, p.Form.Measure, p.Form.Window, p.Form.Average, sports, workouts, checkedYears, filters...,
+	)
+	if err != nil {
+		return telemetry.Error(span, err)
+	}
+	system := units.FromContext(ctx).System
+	days := 0
+	for _, year := range years {
+		days = max(days, len(values[year]))
+	}
+	if len(years) == 0 {
+		slog.Error("No years found in rolling.render()")
+		return nil
+	}
+	refTime, err := time.Parse(time.DateOnly, fmt.Sprintf("%d-01-01", slices.Max(years)))
+	if err != nil {
+		return err
+	}
+	scriptRows := [][]interface{}{}
+	for day := range days {
+		scriptRows = append(scriptRows, make([]interface{}, len(years)+1))
+		index0 := refTime.Add(24 * time.Duration(day) * time.Hour)
+		// Month in JavaScript's Date is 0-indexed
+		newDate := fmt.Sprintf("new Date(%d, %d, %d)", index0.Year(), index0.Month()-1, index0.Day())
+		scriptRows[day][0] = template.JS(newDate) // #nosec G203
+		for idx, year := range years {
+			if day < len(values[year]) {
+				scriptRows[day][idx+1] = present.Convert(m, system, values[year][day])
+			}
+		}
+	}
+	byteRows, _ := json.Marshal(scriptRows)
+	p.Data.ScriptColumns = years
+	p.Data.ScriptRows = template.JS(strings.ReplaceAll(string(byteRows), `"`, ``)) // #nosec G203
+	return nil
+}
+
+func rollingGet(renderer *Template, cfg *pageConfig, db Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, span := telemetry.NewSpan(r.Context(), "rollingGET")
+		defer span.End()
+
+		page, err := newRollingPage(ctx, db, cfg, r.URL.Query())
+		if err != nil {
+			status := errorStatus(err)
+			http.Error(w, http.StatusText(status), status)
+			_ = telemetry.Error(span, err)
+			return
+		}
+		if err := render(w, r, renderer, &Page{Tab: "rolling", Rolling: page}, "rolling-data", page.Data); err != nil {
+			http.Error(w, "Template rendering failed", http.StatusInternalServerError)
+			_ = telemetry.Error(span, err)
+		}
+	}
+}
@@ -7,19 +7,27 @@ import (
 	"fmt"
 	"html/template"
 	"io"
+	"io/fs"
 	"log/slog"
-	"maps"
+	"net"
 	"net/http"
 	"net/url"
+	"os"
+	"os/signal"
 	"slices"
 	"strconv"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/labstack/echo/v4"
 
+	"github.com/jylitalo/mystats/pkg/season"
+	"github.com/jylitalo/mystats/pkg/sport"
 	"github.com/jylitalo/mystats/pkg/stats"
 	"github.com/jylitalo/mystats/pkg/telemetry"
+	"github.com/jylitalo/mystats/pkg/units"
 	"github.com/jylitalo/mystats/storage"
 )
 
@@ -27,8 +35,8 @@ type Storage interface {
 	QueryBestEffortDistances(ctx context.Context) ([]string, error)
 	QuerySports(ctx context.Context) ([]string, error)
 	QueryWorkouts(ctx context.Context) ([]string, error)
-	QueryYears(ctx context.Context, opts ...storage.QueryOption) ([]int, error)
-	Query(ctx context.Context, fields []string, opts ...storage.QueryOption) (*sql.Rows, error)
+	QueryYears(ctx context.Context, t storage.Table, opts ...storage.QueryOption) ([]int, error)
+	Query(ctx context.Context, q *storage.Query) (*sql.Rows, error)
 }
 
 type TableData struct {
@@ -43,87 +51,93 @@ func newTableData() TableData {
 	}
 }
 
+// Page is built for every request, so that concurrent requests never share state.
+// Only active tab has content.
 type Page struct {
+	Tab       string
+	Fragment  string // template and id of element that htmx requests replace
+	User      string // logged in user, empty without authentication
+	Activity  *ActivityPage
 	Best      *BestPage
+	Calendar  *CalendarPage
 	HeartRate *HeartRatePage
 	List      *ListPage
 	Plot      *PlotPage
+	Rolling   *RollingPage
 	Steps     *StepsPage
 	Top       *TopPage
 }
 
+// Tab is link in tab bar
+type Tab struct {
+	Name  string // path of page without slash
+	Title string
+}
+
+// Tabs lists pages in tab bar. Plot is the default page.
+func (p *Page) Tabs() []Tab {
+	return []Tab{
+		{Name: "plot", Title: "Plot"},
+		{Name: "rolling", Title: "Rolling"},
+		{Name: "best", Title: "Strava's Running PBs"},
+		{Name: "list", Title: "List"},
+		{Name: "calendar", Title: "Calendar"},
+		{Name: "top", Title: "Top"},
+		{Name: "steps", Title: "Steps"},
+		{Name: "heartrate", Title: "Resting HR"},
+	}
+}
+
+// pageConfig is shared by all requests and never modified after Start
 type pageConfig struct {
-	bestStats  bestStatsFn
-	listStats  listStatsFn
-	plotStats  plotStatsFn
-	stepsStats stepStatsFn
-	topStats   topStatsFn
-	sports     []string
+	activityStats    activityStatsFn
+	adjacentStats    adjacentStatsFn
+	bestStats        bestStatsFn
+	bestProgressions progressionStatsFn
+	calendarStats    calendarStatsFn
+	detailStats      detailStatsFn
+	lapStats         lapStatsFn
+	listStats        listStatsFn
+	plotStats        plotStatsFn
+	profileStats     profileStatsFn
+	rollingStats     rollingStatsFn
+	splitStats       splitStatsFn
+	stepsStats       stepStatsFn
+	topStats         topStatsFn
+	groups           []sport.Group
+	seasons          []season.Season
+	sports           []string
 }
 type pageOptions func(po *pageConfig)
 
-func newPage(ctx context.Context, db Storage, opts ...pageOptions) (*Page, error) {
-	ctx, span := telemetry.NewSpan(ctx, "server.newPage")
-	defer span.End()
-	allSports, err := db.QuerySports(ctx)
-	if err != nil {
-		return nil, telemetry.Error(span, err)
-	}
-	sports := map[string]bool{}
-	for _, s := range allSports {
-		sports[s] = false
-	}
-	cfg := pageConfig{
-		bestStats:  stats.Best,
-		listStats:  stats.List,
-		plotStats:  stats.Stats,
-		stepsStats: stepsStats,
-		topStats:   stats.Top,
-		sports:     allSports,
+func newPageConfig(opts ...pageOptions) *pageConfig {
+	cfg := &pageConfig{
+		activityStats:    stats.Activities,
+		adjacentStats:    stats.Adjacent,
+		bestStats:        stats.Best,
+		bestProgressions: stats.BestProgression,
+		calendarStats:    stats.Calendar,
+		detailStats:      stats.Details,
+		lapStats:         stats.Laps,
+		listStats:        stats.List,
+		plotStats:        stats.Stats,
+		profileStats:     stats.ElevationProfile,
+		rollingStats:     stats.Rolling,
+		splitStats:       stats.Split,
+		stepsStats:       stepsStats,
+		topStats:         stats.Top,
 	}
 	for _, o := range opts {
-		o(&cfg)
-	}
-	for _, t := range cfg.sports {
-		if _, ok := sports[t]; ok {
-			sports[t] = true
-		}
-	}
-	workoutTypes, errW := db.QueryWorkouts(ctx)
-	selectedWT := map[string]bool{}
-	for _, wt := range workoutTypes {
-		selectedWT[wt] = true
+		o(cfg)
 	}
-	dailyStepsYears, errDS := db.QueryYears(ctx, storage.WithTable(storage.DailyStepsTable))
-	heartRateYears, errHR := db.QueryYears(ctx, storage.WithTable(storage.HeartRateTable))
-	if err := errors.Join(errDS, errHR); err != nil {
-		return nil, err
-	}
-	stravaYears, errStr := db.QueryYears(ctx)
-	be, errBE := newBestPage(ctx, db, cfg.bestStats)
-	hr, errHR := newHeartRatePage(ctx, db, heartRateYears)
-	steps, errSte := newStepsPage(ctx, db, dailyStepsYears, cfg.stepsStats)
-	list, errL := newListPage(ctx, db, stravaYears, maps.Clone(sports), maps.Clone(selectedWT), cfg.listStats)
-	plot, errP := newPlotPage(ctx, db, stravaYears, maps.Clone(sports), maps.Clone(selectedWT), cfg.plotStats)
-	top, errTop := newTopPage(ctx, db, stravaYears, maps.Clone(sports), maps.Clone(selectedWT), cfg.topStats)
-	if err := errors.Join(errW, errStr, errBE, errHR, errSte, errL, errP, errTop); err != nil {
-		return nil, err
-	}
-	return &Page{
-		Best:      be,
-		HeartRate: hr,
-		List:      list,
-		Plot:      plot,
-		Steps:     steps,
-		Top:       top,
-	}, nil
+	return cfg
 }
 
 type Template struct {
 	tmpl *template.Template
 }
 
-func newTemplate(path string) *Template {
+func newTemplate(fsys fs.FS) *Template {
 	funcMap := template.FuncMap{
 		"N": func(start, end int) (stream chan int) {
 			stream = make(chan int)
@@ -135,12 +149,12 @@ func newTemplate(path string) *Template {
 			}()
 			return
 		},
+		"chart": func(name, query string) string {
+			return "/chart/" + name + ".svg?" + query
+		},
 		"dec": func(i int) int {
 			return i - 1
 		},
-		"esc": func(s string) string {
-			return strings.ReplaceAll(strings.ReplaceAll(s, " ", "_"), "/", "X")
-		},
 		"inc": func(i int) int {
 			return i + 1
 		},
@@ -155,7 +169,7 @@ func newTemplate(path string) *Template {
 		},
 	}
 	return &Template{
-		tmpl: template.Must(template.New("index").Funcs(funcMap).ParseGlob(path)),
+		tmpl: template.Must(template.New("index").Funcs(funcMap).ParseFS(fsys, "views/*.html")),
 	}
 }
 
@@ -163,24 +177,156 @@ func (t *Template) Render(w io.Writer, name string, data interface{}, c echo.Con
 	return t.tmpl.ExecuteTemplate(w, name, data)
 }
 
-func selectedSports(sports map[string]bool) []string {
+// errInvalidForm is wrapped by errors from parsing query string
+var errInvalidForm = errors.New("invalid form value")
+
+// errorStatus tells whether client (bad query string, unknown activity) or server caused error
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, errInvalidForm):
+		return http.StatusBadRequest
+	case errors.Is(err, stats.ErrNotFound):
+		return http.StatusNotFound
+	}
+	return http.StatusInternalServerError
+}
+
+// FilterFormData has filters that Strava pages share. Sports and workouts have every option with its state.
+type FilterFormData struct {
+	From     string
+	To       string
+	Sports   map[string]bool
+	Groups   []SportGroup
+	Workouts map[string]bool
+	Years    map[int]bool
+}
+
+// newFilterFormData reads filters from query string. Bare URL (e.g. /plot) gets configured sports,
+// all workouts and default years. Otherwise unchecked options are simply missing from query string.
+func newFilterFormData(
+	ctx context.Context, db Storage, cfg *pageConfig, values url.Values, defaultYears func([]int) []int,
+) (FilterFormData, error) {
+	allSports, errS := db.QuerySports(ctx)
+	workouts, errW := db.QueryWorkouts(ctx)
+	years, errY := yearCheckboxes(ctx, db, storage.SummaryTable, values, defaultYears)
+	if err := errors.Join(errS, errW, errY); err != nil {
+		return FilterFormData{}, err
+	}
+	form := FilterFormData{
+		From:     values.Get("from"),
+		To:       values.Get("to"),
+		Sports:   checkboxes(allSports, cfg.sports),
+		Groups:   newSportGroups(cfg.groups, cfg.sports),
+		Workouts: checkboxes(workouts, workouts),
+		Years:    years,
+	}
+	if len(values) == 0 {
+		return form, nil
+	}
+	form.Sports = checkboxes(allSports, values["sport"])
+	form.Groups = newSportGroups(cfg.groups, values["group"])
+	form.Workouts = checkboxes(workouts, values["workout"])
+	return form, nil
+}
+
+// filters returns selected sports, workouts and years with date range and sport group filters
+func (f FilterFormData) filters() ([]string, []string, []int, []storage.QueryOption, error) {
+	filters, err := dateRangeValues(f.From, f.To)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	_, groupFilters := selectedGroups(f.Groups)
+	return selected(f.Sports), selected(f.Workouts), selectedYears(f.Years), append(filters, groupFilters...), nil
+}
+
+// yearCheckboxes has years of table. Bare URL checks default years, otherwise years from query string.
+func yearCheckboxes(
+	ctx context.Context, db Storage, t storage.Table, values url.Values, defaultYears func([]int) []int,
+) (map[int]bool, error) {
+	years, err := db.QueryYears(ctx, t)
+	if err != nil {
+		return nil, err
+	}
+	if len(values) == 0 {
+		return checkboxes(years, defaultYears(years)), nil
+	}
+	checked, err := yearValues(values)
+	return checkboxes(years, checked), err
+}
+
+// monthDayValues parses end of year-to-date period. Default is today.
+func monthDayValues(values url.Values) (int, int, error) {
+	now := time.Now()
+	month, errM := intValue(values, "month", int(now.Month()))
+	day, errD := intValue(values, "day", now.Day())
+	if err := errors.Join(errM, errD); err != nil {
+		return 0, 0, err
+	}
+	if month < 1 || month > 12 || day < 1 || day > 31 {
+		return 0, 0, fmt.Errorf("%w: month %d and day %d", errInvalidForm, month, day)
+	}
+	return month, day, nil
+}
+
+// allYears checks every year by default
+func allYears(years []int) []int {
+	return years
+}
+
+// currentYear checks only current year by default
+func currentYear(years []int) []int {
+	return []int{time.Now().Year()}
+}
+
+// checkboxes has every option, and those that are in checked are true
+func checkboxes[T comparable](options, checked []T) map[T]bool {
+	result := map[T]bool{}
+	for _, o := range options {
+		result[o] = slices.Contains(checked, o)
+	}
+	return result
+}
+
+// selected lists checked options in alphabetical order
+func selected(options map[string]bool) []string {
 	checked := []string{}
-	for k, v := range sports {
+	for k, v := range options {
 		if v {
 			checked = append(checked, k)
 		}
 	}
+	slices.Sort(checked)
 	return checked
 }
 
-func selectedWorkouts(workouts map[string]bool) []string {
-	checked := []string{}
-	for k, v := range workouts {
-		if v {
-			checked = append(checked, k)
+// SportGroup is checkbox for user-defined sport group in forms
+type SportGroup struct {
+	sport.Group
+	Checked bool
+}
+
+func newSportGroups(groups []sport.Group, selected []string) []SportGroup {
+	options := make([]SportGroup, len(groups))
+	for idx, g := range groups {
+		options[idx] = SportGroup{Group: g, Checked: slices.Contains(selected, g.Name)}
+	}
+	return options
+}
+
+// selectedGroups returns names of checked groups and query option that filters with them
+func selectedGroups(groups []SportGroup) ([]string, []storage.QueryOption) {
+	names := []string{}
+	checked := []sport.Group{}
+	for _, g := range groups {
+		if g.Checked {
+			names = append(names, g.Name)
+			checked = append(checked, g.Group)
 		}
 	}
-	return checked
+	if len(checked) == 0 {
+		return names, nil
+	}
+	return names, []storage.QueryOption{storage.WithSportGroups(checked...)}
 }
 
 func selectedYears(years map[int]bool) []int {
@@ -194,110 +340,221 @@ func selectedYears(years map[int]bool) []int {
 	return checked
 }
 
-func sportsValues(values url.Values) (map[string]bool, error) {
-	if values == nil {
-		return nil, errors.New("no type values given")
+// dateRangeValues parses from and to date pickers
+func dateRangeValues(from, to string) ([]storage.QueryOption, error) {
+	filters, err := storage.ParseDateRange(from, to)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errInvalidForm, err)
 	}
-	sports := map[string]bool{}
-	for k, v := range values {
-		if strings.HasPrefix(k, "sport_") {
-			tv := strings.ReplaceAll(k[6:], "_", " ")
-			sports[tv] = (len(tv) > 0 && v[0] == "on")
+	return filters, nil
+}
+
+// yearValues parses years (e.g. year=2023&year=2024) from query string
+func yearValues(values url.Values) ([]int, error) {
+	years := []int{}
+	for _, v := range values["year"] {
+		y, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("%w: year %s", errInvalidForm, v)
 		}
+		years = append(years, y)
 	}
-	return sports, nil
+	return years, nil
 }
 
-func workoutsValues(values url.Values) (map[string]bool, error) {
-	if values == nil {
-		return nil, errors.New("no workoutType values given")
+// intValue parses number from query string. Missing value returns def.
+func intValue(values url.Values, key string, def int) (int, error) {
+	v := values.Get(key)
+	if v == "" {
+		return def, nil
 	}
-	sports := map[string]bool{}
-	for k, v := range values {
-		if strings.HasPrefix(k, "wt_") {
-			tv := strings.ReplaceAll(k[3:], "_", " ")
-			sports[tv] = (len(tv) > 0 && v[0] == "on")
-		}
+	i, err := strconv.Atoi(v)
+	if err != nil {
+		return def, fmt.Errorf("%w: %s %s", errInvalidForm, key, v)
 	}
-	return sports, nil
+	return i, nil
 }
 
-func yearValues(values url.Values) (map[int]bool, error) {
-	if values == nil {
-		return nil, errors.New("no year values given")
+// optionValue checks that value is one of options. Missing value returns def.
+func optionValue[T comparable](value, def T, options []T) (T, error) {
+	var zero T
+	if value == zero {
+		return def, nil
 	}
-	years := map[int]bool{}
-	for k, v := range values {
-		if strings.HasPrefix(k, "year_") {
-			y, err := strconv.Atoi(k[5:])
-			if err != nil {
-				return nil, err
-			}
-			years[y] = (len(v) > 0 && v[0] == "on")
-		}
+	if !slices.Contains(options, value) {
+		return def, fmt.Errorf("%w: %v (valid values are %v)", errInvalidForm, value, options)
 	}
-	// slog.Info("server.yearValues", "years", years)
-	return years, nil
+	return value, nil
+}
+
+// startOptions are optional features of server
+type startOptions struct {
+	address      string
+	certFile     string
+	keyFile      string
+	users        Users
+	oidc         *OIDCConfig
+	sync         SyncFn
+	syncInterval time.Duration
 }
 
-func yearToDateQuery(
-	ctx context.Context, db Storage, day, month int, years []int, table, column string,
-) ([]int, *sql.Rows, error) {
-	o := []string{"year", "month", "day"}
-	opts := []storage.QueryOption{
-		storage.WithDayOfYear(day, month),
-		storage.WithTable(table),
-		storage.WithOrder(storage.OrderConfig{GroupBy: o, OrderBy: o}),
+// StartOption enables optional feature of server
+type StartOption func(o *startOptions)
+
+// WithAddress listens on address (e.g. 0.0.0.0) instead of 127.0.0.1
+func WithAddress(address string) StartOption {
+	return func(o *startOptions) {
+		o.address = address
 	}
-	opts = append(opts, storage.WithYears(years...))
-	rows, err := db.Query(ctx, append(o, column), opts...)
-	if err != nil {
-		return nil, nil, fmt.Errorf("select caused: %w", err)
+}
+
+// WithTLS serves https with certificate and its private key from PEM files
+func WithTLS(certFile, keyFile string) StartOption {
+	return func(o *startOptions) {
+		o.certFile = certFile
+		o.keyFile = keyFile
 	}
-	foundYears, err := db.QueryYears(ctx, opts...)
-	return foundYears, rows, err
 }
 
-func Start(ctx context.Context, db Storage, sports []string, port int) error {
+// shutdownTimeout is time that requests in progress get to finish after SIGINT or SIGTERM
+const shutdownTimeout = 10 * time.Second
+
+// Start serves until SIGINT or SIGTERM. Then it waits for requests in progress and background sync,
+// so that caller can close database.
+func Start(
+	ctx context.Context, db Storage, sports []string, groups []sport.Group, seasons []season.Season, port int,
+	assetsDir string, opts ...StartOption,
+) error {
 	ctx, span := telemetry.NewSpan(ctx, "server.start")
 	defer span.End()
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	renderer := newTemplate("server/views/*.html")
-	page, err := newPage(ctx, db, func(pc *pageConfig) { pc.sports = sports })
+	static, err := assets(assetsDir)
 	if err != nil {
-		return err
+		return telemetry.Error(span, err)
+	}
+	renderer := newTemplate(static)
+	cfg := newPageConfig(func(pc *pageConfig) {
+		pc.groups = groups
+		pc.seasons = seasons
+		pc.sports = sports
+	})
+	options := &startOptions{address: "127.0.0.1"}
+	for _, o := range opts {
+		o(options)
+	}
+	a, err := newAuth(ctx, options.users, options.oidc)
+	if err != nil {
+		return telemetry.Error(span, err)
+	}
+	var sched *syncer
+	var background sync.WaitGroup
+	defer background.Wait()
+	if options.sync != nil {
+		sched = newSyncer(options.sync, options.syncInterval)
+		background.Go(func() { sched.run(ctx) })
+	}
+	handler, err := newMux(ctx, static, renderer, cfg, db, sched, a)
+	if err != nil {
+		stop()
+		return telemetry.Error(span, err)
 	}
-	mux := http.NewServeMux()
-	mux.Handle("/css/", http.StripPrefix("/css/", http.FileServer(http.Dir("server/css"))))
-
-	mux.HandleFunc("/", indexGet(ctx, renderer, page))
-	mux.HandleFunc("/best", bestPost(ctx, renderer, page.Best, db))
-	mux.HandleFunc("/event", listEvent(ctx, renderer, page.List, db))
-	mux.HandleFunc("/heartrate", heartratePost(ctx, renderer, page.HeartRate, db))
-	mux.HandleFunc("/list", listPost(ctx, renderer, page.List, db))
-	mux.HandleFunc("/plot", plotPost(ctx, renderer, page.Plot, db))
-	mux.HandleFunc("/top", topPost(ctx, renderer, page.Top, db))
-	mux.HandleFunc("/steps", stepsPost(ctx, renderer, page.Steps, db))
 	srv := &http.Server{
-		Addr:         "127.0.0.1:" + strconv.Itoa(port),
-		Handler:      mux,              // replace with your mux/router
+		Addr:         net.JoinHostPort(options.address, strconv.Itoa(port)),
+		Handler:      handler,
 		ReadTimeout:  10 * time.Second, // max time to read request headers/body
 		WriteTimeout: 10 * time.Second, // max time to write response
 		IdleTimeout:  60 * time.Second, // keep-alive idle connections
 	}
-	slog.Info("server.Start", "Listening on", port)
-	return srv.ListenAndServe()
+	slog.Info("server.Start", "Listening on", srv.Addr, "tls", options.certFile != "", "auth", a != nil)
+	served := make(chan error, 1)
+	go func() {
+		if options.certFile != "" {
+			served <- srv.ListenAndServeTLS(options.certFile, options.keyFile)
+		} else {
+			served <- srv.ListenAndServe()
+		}
+	}()
+	select {
+	case err = <-served: // e.g. port is already in use
+		stop()
+		return telemetry.Error(span, err)
+	case <-ctx.Done():
+	}
+	slog.Info("server.Start", "shutting down", context.Cause(ctx))
+	stop() // second signal stops immediately
+	shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), shutdownTimeout)
+	defer cancel()
+	err = srv.Shutdown(shutdownCtx)
+	if errServe := <-served; !errors.Is(errServe, http.ErrServerClosed) {
+		err = errors.Join(err, errServe)
+	}
+	return telemetry.Error(span, err)
 }
 
-func indexGet(ctx context.Context, renderer *Template, page *Page) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		_, span := telemetry.NewSpan(ctx, "indexGET")
-		defer span.End()
+// newMux routes every page to GET handler. Form state is in query string, so that every view can be bookmarked.
+// With auth, every page requires login. Cross-origin requests, that would change state, are always rejected.
+// Handlers get tracer and unit preferences from ctx through context of request.
+func newMux(
+	ctx context.Context, static fs.FS, renderer *Template, cfg *pageConfig, db Storage, sched *syncer, a *auth,
+) (http.Handler, error) {
+	mux := http.NewServeMux()
+	mux.Handle("GET /css/", staticHandler(static))
+	mux.Handle("GET /js/", staticHandler(static))
 
-		err := renderer.tmpl.ExecuteTemplate(w, "index", page)
-		if err != nil {
-			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
-			_ = telemetry.Error(span, err)
-		}
+	mux.Handle("GET /{$}", http.RedirectHandler("/plot", http.StatusFound))
+	mux.HandleFunc("GET /activity", activityGet(renderer, cfg, db))
+	mux.HandleFunc("GET /calendar", calendarGet(renderer, cfg, db))
+	mux.HandleFunc("GET /chart/calendar.svg", chartGet("chart.calendar", calendarSVG(cfg, db)))
+	mux.HandleFunc("GET /chart/heartrate.svg", chartGet("chart.heartrate", heartrateSVG(db)))
+	mux.HandleFunc("GET /chart/plot.svg", chartGet("chart.plot", plotSVG(cfg, db)))
+	mux.HandleFunc("GET /chart/profile.svg", chartGet("chart.profile", profileSVG(cfg, db)))
+	mux.HandleFunc("GET /chart/route.svg", chartGet("chart.route", routeSVG(cfg, db)))
+	mux.HandleFunc("GET /chart/splits.svg", chartGet("chart.splits", splitsSVG(cfg, db)))
+	mux.HandleFunc("GET /chart/stats.svg", chartGet("chart.stats", statsSVG(cfg, db)))
+	mux.HandleFunc("GET /chart/steps.svg", chartGet("chart.steps", stepsSVG(cfg, db)))
+	mux.HandleFunc("GET /chart/steps-calendar.svg", chartGet("chart.stepsCalendar", stepsCalendarSVG(cfg, db)))
+	mux.HandleFunc("GET /best", bestGet(renderer, cfg, db))
+	mux.HandleFunc("GET /events", eventsGet(sched))
+	mux.HandleFunc("GET /event", func(w http.ResponseWriter, r *http.Request) {
+		// old links from list page
+		http.Redirect(w, r, "/activity?"+r.URL.RawQuery, http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("GET /heartrate", heartrateGet(renderer, db))
+	mux.HandleFunc("GET /list", listGet(renderer, cfg, db))
+	mux.HandleFunc("GET /plot", plotGet(renderer, cfg, db))
+	mux.HandleFunc("GET /rolling", rollingGet(renderer, cfg, db))
+	mux.HandleFunc("GET /top", topGet(renderer, cfg, db))
+	mux.HandleFunc("GET /steps", stepsGet(renderer, cfg, db))
+	mux.HandleFunc("GET /sync", syncGet(renderer, sched))
+	api, err := newAPIServer(cfg, db)
+	if err != nil {
+		return nil, err
+	}
+	mux.Handle("/api/v1/", api)
+	var handler http.Handler = mux
+	if a != nil {
+		handler = a.routes(staticHandler(static), renderer, mux)
+	}
+	handler = withPreferences(units.FromContext(ctx), http.NewCrossOriginProtection().Handler(handler))
+	return telemetry.Handler(ctx, handler), nil
+}
+
+// withPreferences puts unit preferences of server into context of every request
+func withPreferences(prefs units.Preferences, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.ServeHTTP(w, r.WithContext(units.WithContext(r.Context(), prefs)))
+	})
+}
+
+// render writes only data of page for htmx requests, since form is already on screen.
+// Other requests (e.g. bookmarks) get the whole page.
+func render(w http.ResponseWriter, r *http.Request, renderer *Template, page *Page, name string, data any) error {
+	if r.Header.Get("HX-Request") == "true" {
+		return renderer.tmpl.ExecuteTemplate(w, name, data)
 	}
+	page.Fragment = name
+	page.User = currentUser(r.Context())
+	return renderer.tmpl.ExecuteTemplate(w, "index", page)
 }
@@ -2,13 +2,29 @@ package server //nolint:testpackage
 
 import (
 	"bufio"
-	"bytes"
 	"context"
+	"crypto/sha256"
 	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/jylitalo/mystats/api/mystats"
+	"github.com/jylitalo/mystats/pkg/sport"
 	"github.com/jylitalo/mystats/pkg/stats"
 	"github.com/jylitalo/mystats/pkg/telemetry"
+	"github.com/jylitalo/mystats/pkg/units"
 	"github.com/jylitalo/mystats/storage"
 )
 
@@ -18,7 +34,7 @@ func (t *testDB) QueryBestEffortDistances(ctx context.Context) ([]string, error)
 	return nil, nil
 }
 
-func (t *testDB) Query(ctx context.Context, fields []string, opts ...storage.QueryOption) (*sql.Rows, error) {
+func (t *testDB) Query(ctx context.Context, q *storage.Query) (*sql.Rows, error) {
 	return nil, nil
 }
 
@@ -30,65 +46,550 @@ func (t *testDB) QueryWorkouts(ctx context.Context) ([]string, error) {
 	return nil, nil
 }
 
-func (t *testDB) QueryYears(ctx context.Context, opts ...storage.QueryOption) ([]int, error) {
+func (t *testDB) QueryYears(ctx context.Context, table storage.Table, opts ...storage.QueryOption) ([]int, error) {
 	return nil, nil
 }
 
-func TestTemplateRender(t *testing.T) {
-	ctx, _, _ := telemetry.Setup(context.TODO(), "test")
-	db := &testDB{}
-	p, err := newPage(
-		ctx, db,
+// testPageConfig has stats functions that don't need database
+func testPageConfig() *pageConfig {
+	return newPageConfig(
 		func(pc *pageConfig) {
+			pc.activityStats = func(
+				ctx context.Context, db stats.Storage, order []storage.Order, limit, offset int,
+				filters ...storage.QueryOption,
+			) ([]stats.Activity, error) {
+				activities := []stats.Activity{}
+				for id := offset; id < min(offset+limit, 3); id++ {
+					activities = append(activities, stats.Activity{StravaID: int64(id), Name: "Run"})
+				}
+				return activities, nil
+			}
+			pc.calendarStats = func(
+				ctx context.Context, db stats.Storage, measure string, year int, sports, workouts []string,
+				filters ...storage.QueryOption,
+			) (*stats.CalendarResult, error) {
+				days := make([]float64, 366)
+				days[0] = 1000
+				return &stats.CalendarResult{
+					Year: year, Days: days, Weeks: make([]float64, 53), Months: make([]float64, 12),
+				}, nil
+			}
+			pc.detailStats = func(ctx context.Context, db stats.Storage, id int64) (*stats.ActivityDetails, error) {
+				if id != 1 {
+					return nil, stats.ErrNotFound
+				}
+				return &stats.ActivityDetails{Activity: stats.Activity{StravaID: id}}, nil
+			}
+			pc.splitStats = func(ctx context.Context, db stats.Storage, id int64) ([]stats.ActivitySplit, error) {
+				return []stats.ActivitySplit{{Split: 1, Distance: 1000}}, nil
+			}
+			pc.lapStats = func(ctx context.Context, db stats.Storage, id int64) ([]stats.ActivityLap, error) {
+				return []stats.ActivityLap{{Lap: 1, Distance: 500}, {Lap: 2, Distance: 500}}, nil
+			}
+			pc.profileStats = func(ctx context.Context, db stats.Storage, id int64) ([]stats.ProfilePoint, error) {
+				return []stats.ProfilePoint{{Distance: 0, Altitude: 10}, {Distance: 1000, Altitude: 15}}, nil
+			}
+			pc.adjacentStats = func(ctx context.Context, db stats.Storage, id int64) (int64, int64, error) {
+				return 0, 2, nil
+			}
 			pc.bestStats = func(
-				ctx context.Context, db stats.Storage, distance string, limit int,
-			) ([]string, [][]string, error) {
-				return nil, nil, nil
+				ctx context.Context, db stats.Storage, distance string, limit int, filters ...storage.QueryOption,
+			) ([]stats.BestEffort, error) {
+				return nil, nil
+			}
+			pc.bestProgressions = func(
+				ctx context.Context, db stats.Storage, distance string, filters ...storage.QueryOption,
+			) ([]stats.ProgressionRecord, error) {
+				return nil, nil
 			}
 			pc.listStats = func(
 				ctx context.Context, db stats.Storage, sports, workouts []string, years []int,
-				limit int, name string,
-			) ([]string, [][]string, error) {
-				return nil, nil, nil
+				limit int, name string, filters ...storage.QueryOption,
+			) ([]stats.Activity, error) {
+				return nil, nil
 			}
 			pc.plotStats = func(
 				ctx context.Context, db stats.Storage, measurement, period string, sports, workouts []string,
-				month, day int, years []int) ([]int, [][]string, []string, error,
-			) {
-				return nil, nil, nil, nil
+				month, day int, years []int, filters ...storage.QueryOption,
+			) (*stats.StatsResult, error) {
+				return nil, nil
+			}
+			pc.rollingStats = func(
+				ctx context.Context, db stats.Storage, measure string, window int, average bool,
+				sports, workouts []string, years []int, filters ...storage.QueryOption,
+			) ([]int, map[int][]float64, error) {
+				return nil, nil, nil
 			}
 			pc.stepsStats = func(ctx context.Context, db Storage, period string, month, day int, years []int,
 			) ([]int, [][]string, []string, error) {
 				return nil, nil, nil, nil
 			}
 			pc.topStats = func(ctx context.Context, db stats.Storage, measure, period string, sports, workouts []string,
-				limit int, years []int,
-			) ([]string, [][]string, error) {
-				return nil, nil, nil
+				limit int, years []int, filters ...storage.QueryOption,
+			) (*stats.TopResult, error) {
+				return nil, nil
 			}
-			pc.sports = []string{"Race"}
+			pc.groups = []sport.Group{{Name: "running", Types: []string{"Run"}, SportTypes: []string{"TrailRun"}}}
+			pc.sports = []string{"Race", "running"}
 		},
 	)
+}
+
+func TestPages(t *testing.T) {
+	ctx, _, _ := telemetry.Setup(context.TODO(), "test")
+	mux, err := newMux(ctx, embedded, newTemplate(embedded), testPageConfig(), &testDB{}, nil, nil)
 	if err != nil {
-		t.Error(err)
+		t.Fatal(err)
+	}
+	htmxStatus := http.StatusOK
+	if _, err := fs.Stat(embedded, "js/htmx.min.js"); err != nil {
+		htmxStatus = http.StatusFound // redirected to htmxURL
+	}
+	tests := []struct {
+		url    string
+		status int
+	}{
+		{"/", http.StatusFound},
+		{"/css/index.css", http.StatusOK},
+		{"/js/htmx.min.js", htmxStatus},
+		{"/activity?id=1", http.StatusOK},
+		{"/activity?id=2", http.StatusNotFound},
+		{"/activity?id=x", http.StatusBadRequest},
+		{"/chart/profile.svg?id=1", http.StatusOK},
+		{"/chart/route.svg?id=1", http.StatusOK},
+		{"/chart/route.svg?id=2", http.StatusNotFound},
+		{"/chart/splits.svg?id=1&measure=heartrate", http.StatusOK},
+		{"/chart/splits.svg?id=1&measure=cadence", http.StatusBadRequest},
+		{"/calendar", http.StatusOK},
+		{"/calendar?measure=steps&date=" + time.Now().Format(time.DateOnly), http.StatusOK},
+		{"/calendar?date=1999-01-01", http.StatusBadRequest},
+		{"/calendar?measure=unknown", http.StatusBadRequest},
+		{"/chart/calendar.svg?measure=elevation", http.StatusOK},
+		{"/chart/heartrate.svg?average=9", http.StatusBadRequest},
+		{"/chart/plot.svg?sport=Run&measure=elevation", http.StatusOK},
+		{"/chart/stats.svg?measure=elevation&period=week", http.StatusOK},
+		{"/chart/steps.svg", http.StatusOK},
+		{"/chart/steps-calendar.svg?year=2024", http.StatusOK},
+		{"/views/index.html", http.StatusNotFound},
+		{"/best", http.StatusOK},
+		{"/best?distance=5k&limit=5&progression=on", http.StatusOK},
+		{"/heartrate?year=2024&average=2", http.StatusOK},
+		{"/list", http.StatusOK},
+		{"/list?sport=Run&search=%25Marathon%25&limit=10&from=2024-01-01", http.StatusOK},
+		{"/plot", http.StatusOK},
+		{"/plot?sport=Run&group=running&year=2024&month=6&day=12&measure=elevation&period=week", http.StatusOK},
+		{"/rolling?window=7&average=on", http.StatusOK},
+		{"/steps?year=2024&period=week", http.StatusOK},
+		{"/top?measure=moving_time&period=month&limit=20", http.StatusOK},
+		{"/event?id=1", http.StatusMovedPermanently},
+		{"/events", http.StatusNoContent},
+		{"/sync", http.StatusNoContent},
+		{"/list?limit=7", http.StatusBadRequest},
+		{"/plot?year=abc", http.StatusBadRequest},
+		{"/plot?measure=unknown", http.StatusBadRequest},
+		{"/rolling?from=yesterday", http.StatusBadRequest},
+		{"/list?from=2024-02-01&to=2024-01-01", http.StatusBadRequest},
+		{"/steps?month=13", http.StatusBadRequest},
+	}
+	for _, test := range tests {
+		for _, htmx := range []bool{false, true} {
+			req := httptest.NewRequest(http.MethodGet, test.url, nil)
+			if htmx {
+				req.Header.Set("HX-Request", "true")
+			}
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, req)
+			if rec.Code != test.status {
+				t.Errorf("%s (htmx: %t) returned %d, expected %d: %s", test.url, htmx, rec.Code, test.status, rec.Body)
+			}
+		}
 	}
-	err = p.Plot.render(ctx, &testDB{}, []string{"Run"}, nil, 6, 12, map[int]bool{2024: true}, "month")
+}
+
+func TestSyncer(t *testing.T) {
+	ctx, _, _ := telemetry.Setup(context.TODO(), "test")
+	tests := []struct {
+		name        string
+		err         error
+		rateLimited bool
+		failed      bool
+		badge       string
+	}{
+		{"ok", nil, false, false, "Synced"},
+		{"rate limited", fmt.Errorf("%w: strava", ErrRateLimited), true, false, "Strava rate limit"},
+		{"failed", errors.New("no network"), false, true, "sync failed"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := newSyncer(func(ctx context.Context) error { return test.err }, time.Hour)
+			updates, unsubscribe := s.subscribe()
+			defer unsubscribe()
+			s.once(ctx)
+			status := <-updates
+			if status.Last.IsZero() || status.RateLimited != test.rateLimited || (status.Error != "") != test.failed {
+				t.Errorf("unexpected status %#v", status)
+			}
+			mux, err := newMux(ctx, embedded, newTemplate(embedded), testPageConfig(), &testDB{}, s, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/sync", nil))
+			if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), test.badge) {
+				t.Errorf("/sync returned %d: %s", rec.Code, rec.Body)
+			}
+		})
+	}
+}
+
+// newTestProvider is local stand-in for OpenID Connect provider. It logs in everyone as alice@example.com.
+// newTestProvider is OpenID Connect provider that logs in user of info without asking anything
+func newTestProvider(t *testing.T, info map[string]any) *httptest.Server {
+	t.Helper()
+	var challenge string
+	mux := http.NewServeMux()
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	mux.HandleFunc("GET /.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 srv.URL,
+			"authorization_endpoint": srv.URL + "/authorize",
+			"token_endpoint":         srv.URL + "/token",
+			"userinfo_endpoint":      srv.URL + "/userinfo",
+		})
+	})
+	mux.HandleFunc("GET /authorize", func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if query.Get("client_id") != "mystats" || query.Get("code_challenge_method") != "S256" {
+			http.Error(w, "invalid_request", http.StatusBadRequest)
+			return
+		}
+		challenge = query.Get("code_challenge")
+		callback := url.Values{"code": {"code1"}, "state": {query.Get("state")}}
+		http.Redirect(w, r, query.Get("redirect_uri")+"?"+callback.Encode(), http.StatusFound)
+	})
+	mux.HandleFunc("POST /token", func(w http.ResponseWriter, r *http.Request) {
+		id, secret, _ := r.BasicAuth()
+		sum := sha256.Sum256([]byte(r.PostFormValue("code_verifier")))
+		if id != "mystats" || secret != "secret" || r.PostFormValue("code") != "code1" ||
+			base64.RawURLEncoding.EncodeToString(sum[:]) != challenge {
+			http.Error(w, "invalid_grant", http.StatusBadRequest)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]string{"access_token": "token1", "token_type": "Bearer"})
+	})
+	mux.HandleFunc("GET /userinfo", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token1" {
+			http.Error(w, "invalid_token", http.StatusUnauthorized)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(info)
+	})
+	return srv
+}
+
+func TestAuth(t *testing.T) {
+	ctx, _, _ := telemetry.Setup(context.TODO(), "test")
+	line, err := HashPassword("bob", "secret")
 	if err != nil {
-		t.Error(err)
+		t.Fatal(err)
 	}
-	var b bytes.Buffer
-	w := bufio.NewWriter(&b)
-	tmpl := newTemplate("views/*.html")
-	err = tmpl.Render(w, "index", p, nil)
+	users, err := ReadUsers(strings.NewReader("# team\n" + line + "\n"))
 	if err != nil {
-		t.Error(err)
+		t.Fatal(err)
 	}
-	err = tmpl.Render(w, "plot-data", p.Plot.Data, nil)
+	provider := newTestProvider(t, map[string]any{"sub": "123", "email": "alice@example.com", "email_verified": true})
+	a, err := newAuth(ctx, users, &OIDCConfig{
+		Issuer: provider.URL, ClientID: "mystats", ClientSecret: "secret", Allow: []string{"alice@example.com"},
+	})
 	if err != nil {
-		t.Error(err)
+		t.Fatal(err)
 	}
-	err = tmpl.Render(w, "plot-form", p.Plot.Form, nil)
+	mux, err := newMux(ctx, embedded, newTemplate(embedded), testPageConfig(), &testDB{}, nil, a)
 	if err != nil {
-		t.Error(err)
+		t.Fatal(err)
+	}
+	// session cookie is secure, so it is sent only over https
+	srv := httptest.NewTLSServer(mux)
+	defer srv.Close()
+	newClient := func() *http.Client {
+		client := srv.Client()
+		client.Jar, _ = cookiejar.New(nil)
+		return client
+	}
+	local, oidc, anonymous := newClient(), newClient(), srv.Client()
+	tests := []struct {
+		name   string
+		client *http.Client
+		req    *http.Request
+		status int
+		final  string // path of the last redirect
+		body   string
+	}{
+		{"page needs login", local, get(srv.URL + "/plot"), http.StatusOK, "/login", "Log in"},
+		{"htmx needs login", local, htmx(get(srv.URL + "/plot")), http.StatusUnauthorized, "/plot", ""},
+		{"api needs login", anonymous, get(srv.URL + "/api/v1/best"), http.StatusUnauthorized, "/api/v1/best", "error"},
+		{"api with password", anonymous, basic(get(srv.URL+"/api/v1/best"), "bob", "secret"), http.StatusOK, "", "[]"},
+		{
+			"api with wrong password", anonymous, basic(get(srv.URL+"/api/v1/best"), "bob", "x"),
+			http.StatusUnauthorized, "", "",
+		},
+		{"css is public", anonymous, get(srv.URL + "/css/index.css"), http.StatusOK, "/css/index.css", ""},
+		{"wrong password", local, form(srv.URL+"/login", "bob", "wrong", "/list"), http.StatusUnauthorized, "", "Invalid"},
+		{"unknown user", local, form(srv.URL+"/login", "eve", "secret", "/list"), http.StatusUnauthorized, "", "Invalid"},
+		{"login", local, form(srv.URL+"/login", "bob", "secret", "/list"), http.StatusOK, "/list", "Log out"},
+		{"logged in", local, get(srv.URL + "/plot"), http.StatusOK, "/plot", "bob"},
+		{"no redirect outside", local, form(srv.URL+"/login", "bob", "secret", "//evil.com"), http.StatusOK, "/plot", ""},
+		{"cross-origin logout", local, crossSite(form(srv.URL+"/logout", "", "", "")), http.StatusForbidden, "", ""},
+		{"logout", local, form(srv.URL+"/logout", "", "", ""), http.StatusOK, "/login", "Log in"},
+		{"logged out", local, get(srv.URL + "/plot"), http.StatusOK, "/login", "Log in"},
+		{"oidc login", oidc, get(srv.URL + "/login/oidc?next=%2Ftop"), http.StatusOK, "/top", "alice@example.com"},
+		{"oidc without state", oidc, get(srv.URL + "/login/callback?code=code1"), http.StatusUnauthorized, "", "failed"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			resp, err := test.client.Do(test.req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer func() { _ = resp.Body.Close() }()
+			body, _ := io.ReadAll(resp.Body)
+			if resp.StatusCode != test.status {
+				t.Errorf("returned %d, expected %d: %s", resp.StatusCode, test.status, body)
+			}
+			if test.final != "" && resp.Request.URL.Path != test.final {
+				t.Errorf("ended at %s, expected %s", resp.Request.URL, test.final)
+			}
+			if !strings.Contains(string(body), test.body) {
+				t.Errorf("body doesn't contain %s: %s", test.body, body)
+			}
+		})
+	}
+}
+
+func TestOIDCAllow(t *testing.T) {
+	ctx, _, _ := telemetry.Setup(context.TODO(), "test")
+	alice := map[string]any{"sub": "123", "email": "alice@example.com", "email_verified": true}
+	unverified := map[string]any{"sub": "123", "email": "alice@example.com", "email_verified": false}
+	// anyone can pick preferred_username at some providers
+	eve := map[string]any{
+		"sub": "666", "preferred_username": "alice@example.com", "email": "eve@example.net", "email_verified": true,
+	}
+	tests := []struct {
+		name   string
+		info   map[string]any
+		allow  []string
+		status int
+		body   string
+	}{
+		{"email", alice, []string{"ALICE@example.com"}, http.StatusOK, "alice@example.com"},
+		{"domain", alice, []string{"@example.org", "@example.com"}, http.StatusOK, "alice@example.com"},
+		{"subject", map[string]any{"sub": "123"}, []string{"123"}, http.StatusOK, "123"},
+		{"unverified email", unverified, []string{"alice@example.com"}, http.StatusUnauthorized, "failed"},
+		{"not allowed", alice, []string{"bob@example.com", "@example.org"}, http.StatusUnauthorized, "failed"},
+		{"preferred username", eve, []string{"alice@example.com"}, http.StatusUnauthorized, "failed"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			provider := newTestProvider(t, test.info)
+			a, err := newAuth(ctx, nil, &OIDCConfig{
+				Issuer: provider.URL, ClientID: "mystats", ClientSecret: "secret", Allow: test.allow,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			mux, err := newMux(ctx, embedded, newTemplate(embedded), testPageConfig(), &testDB{}, nil, a)
+			if err != nil {
+				t.Fatal(err)
+			}
+			srv := httptest.NewTLSServer(mux)
+			defer srv.Close()
+			client := srv.Client()
+			client.Jar, _ = cookiejar.New(nil)
+			resp, err := client.Do(get(srv.URL + "/login/oidc?next=%2Ftop"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer func() { _ = resp.Body.Close() }()
+			body, _ := io.ReadAll(resp.Body)
+			if resp.StatusCode != test.status || !strings.Contains(string(body), test.body) {
+				t.Errorf("returned %d, expected %d with %s: %s", resp.StatusCode, test.status, test.body, body)
+			}
+		})
+	}
+	if _, err := newAuth(ctx, nil, &OIDCConfig{Issuer: "https://example.com"}); err == nil {
+		t.Error("OIDC without allowed users should fail")
+	}
+}
+
+func get(url string) *http.Request {
+	req, _ := http.NewRequestWithContext(context.TODO(), http.MethodGet, url, nil)
+	return req
+}
+
+func form(endpoint, user, password, next string) *http.Request {
+	values := url.Values{"user": {user}, "password": {password}, "next": {next}}
+	req, _ := http.NewRequestWithContext(context.TODO(), http.MethodPost, endpoint, strings.NewReader(values.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return req
+}
+
+func htmx(req *http.Request) *http.Request {
+	req.Header.Set("HX-Request", "true")
+	return req
+}
+
+func basic(req *http.Request, user, password string) *http.Request {
+	req.SetBasicAuth(user, password)
+	return req
+}
+
+func crossSite(req *http.Request) *http.Request {
+	req.Header.Set("Sec-Fetch-Site", "cross-site")
+	return req
+}
+
+func TestEvents(t *testing.T) {
+	ctx, _, _ := telemetry.Setup(context.TODO(), "test")
+	s := newSyncer(func(ctx context.Context) error { return nil }, 10*time.Millisecond)
+	mux, err := newMux(ctx, embedded, newTemplate(embedded), testPageConfig(), &testDB{}, s, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+	resp, err := srv.Client().Do(get(srv.URL + "/events"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if ct := resp.Header.Get("Content-Type"); resp.StatusCode != http.StatusOK || ct != "text/event-stream" {
+		t.Fatalf("/events returned %d with content type %s", resp.StatusCode, ct)
+	}
+	runCtx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		s.run(runCtx)
+		close(done)
+	}()
+	line, err := bufio.NewReader(resp.Body).ReadString('\n')
+	if err != nil || line != "event: sync\n" {
+		t.Errorf("expected sync event, got %q (%v)", line, err)
+	}
+	// stream ends, when sync stops at shutdown
+	cancel()
+	<-done
+	if _, err = io.ReadAll(resp.Body); err != nil {
+		t.Errorf("stream didn't end cleanly: %v", err)
+	}
+}
+
+func TestPreferences(t *testing.T) {
+	ctx, _, _ := telemetry.Setup(context.TODO(), "test")
+	prefs, err := units.Parse("imperial", "en-US")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mux, err := newMux(
+		units.WithContext(ctx, prefs), embedded, newTemplate(embedded), testPageConfig(), &testDB{}, nil, nil,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/list", nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "Distance (mi)") {
+		t.Errorf("/list returned %d without imperial units: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestAPI(t *testing.T) {
+	ctx, _, _ := telemetry.Setup(context.TODO(), "test")
+	mux, err := newMux(ctx, embedded, newTemplate(embedded), testPageConfig(), &testDB{}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		url    string
+		status int
+		body   string
+	}{
+		{"/api/v1/activities?limit=2", http.StatusOK, `"next_offset":2`},
+		{"/api/v1/activities?limit=2&offset=2", http.StatusOK, `{"offset":2,"limit":2,"activities":[{"id":2,`},
+		{"/api/v1/activities?group=running&sport=Run&year=2024&from=2024-01-01", http.StatusOK, `"activities":[`},
+		{"/api/v1/activities?group=unknown", http.StatusBadRequest, `{"error":"invalid form value: group unknown"}`},
+		{"/api/v1/activities?limit=0", http.StatusBadRequest, `"error"`},
+		{"/api/v1/activities?from=yesterday", http.StatusBadRequest, `"error"`},
+		{"/api/v1/activities/1", http.StatusOK, `"splits":[{"split":1,"distance":1000,`},
+		{"/api/v1/activities/2", http.StatusNotFound, `"error"`},
+		{"/api/v1/activities/abc", http.StatusBadRequest, `"error"`},
+		{"/api/v1/best", http.StatusOK, `[]`},
+		{"/api/v1/best?distance=5k", http.StatusBadRequest, `"error"`},
+		{"/api/v1/heartrate?year=2024", http.StatusOK, `{"years":[],"values":[]}`},
+		{"/api/v1/stats?measure=pace&period=week", http.StatusOK, `"measure":"pace","dimension":"","period":"week"`},
+		{"/api/v1/stats?period=day", http.StatusBadRequest, `"error"`},
+		{"/api/v1/stats?measure=unknown", http.StatusBadRequest, `"error"`},
+		{"/api/v1/steps?month=2&day=30", http.StatusOK, `"years":[]`},
+		{"/api/v1/steps?month=13", http.StatusBadRequest, `"error"`},
+		{"/api/v1/top?limit=5", http.StatusOK, `"periods":[]`},
+	}
+	for _, test := range tests {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, test.url, nil))
+		if rec.Code != test.status {
+			t.Errorf("%s returned %d, expected %d: %s", test.url, rec.Code, test.status, rec.Body)
+		}
+		if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "application/json") {
+			t.Errorf("%s has content type %s", test.url, ct)
+		}
+		if !strings.Contains(rec.Body.String(), test.body) {
+			t.Errorf("%s returned %s, expected it to contain %s", test.url, rec.Body, test.body)
+		}
+	}
+}
+
+func TestAPIClient(t *testing.T) {
+	ctx, _, _ := telemetry.Setup(context.TODO(), "test")
+	mux, err := newMux(ctx, embedded, newTemplate(embedded), testPageConfig(), &testDB{}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+	client, err := mystats.NewClient(srv.URL + "/api/v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	page, err := client.ListActivities(ctx, mystats.ListActivitiesParams{Limit: mystats.NewOptInt(2)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Activities) != 2 || page.NextOffset.Or(0) != 2 {
+		t.Errorf("unexpected first page: %#v", page)
+	}
+	page, err = client.ListActivities(ctx, mystats.ListActivitiesParams{
+		Limit: mystats.NewOptInt(2), Offset: page.NextOffset,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Activities) != 1 || page.NextOffset.Set {
+		t.Errorf("unexpected last page: %#v", page)
+	}
+	_, err = client.GetActivity(ctx, mystats.GetActivityParams{ID: 2})
+	var apiErr *mystats.ErrorStatusCode
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound {
+		t.Errorf("expected not found, got %v", err)
+	}
+}
+
+// TestMeasures ensures that spec has the same measures as pkg/stats
+func TestMeasures(t *testing.T) {
+	measures := []string{}
+	for _, m := range mystats.MeasureCount.AllValues() {
+		measures = append(measures, string(m))
+	}
+	if !slices.Equal(measures, stats.Measures()) {
+		t.Errorf("measures in openapi.yaml %v don't match %v", measures, stats.Measures())
 	}
 }
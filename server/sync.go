@@ -0,0 +1,176 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/jylitalo/mystats/pkg/telemetry"
+)
+
+// ErrRateLimited is wrapped by errors of SyncFn, when Strava's rate limit stopped fetching.
+// Database still gets what was fetched before the limit.
+var ErrRateLimited = errors.New("rate limit exceeded")
+
+// SyncFn fetches new activities and adds them into database
+type SyncFn func(ctx context.Context) error
+
+// WithSync runs fn on every interval and refreshes open pages after it
+func WithSync(fn SyncFn, interval time.Duration) StartOption {
+	return func(o *startOptions) {
+		o.sync = fn
+		o.syncInterval = interval
+	}
+}
+
+// keepAlive is interval of comments in event stream, so that proxies don't close idle connections
+const keepAlive = 30 * time.Second
+
+// SyncStatus is shown in status badge of every page
+type SyncStatus struct {
+	Last        time.Time // zero until the first sync has finished
+	Next        time.Time
+	RateLimited bool
+	Error       string
+}
+
+// syncer runs SyncFn on interval and tells subscribers (open pages) after every sync
+type syncer struct {
+	fn          SyncFn
+	interval    time.Duration
+	mu          sync.Mutex
+	status      SyncStatus
+	subscribers map[chan SyncStatus]bool
+	stopped     chan struct{} // closed when run returns, so that event streams end at shutdown
+}
+
+func newSyncer(fn SyncFn, interval time.Duration) *syncer {
+	return &syncer{
+		fn:          fn,
+		interval:    interval,
+		status:      SyncStatus{Next: time.Now().Add(interval)},
+		subscribers: map[chan SyncStatus]bool{},
+		stopped:     make(chan struct{}),
+	}
+}
+
+// run syncs on every interval until ctx is done
+func (s *syncer) run(ctx context.Context) {
+	defer close(s.stopped)
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.once(ctx)
+		}
+	}
+}
+
+// once syncs and sends new status to every subscriber
+func (s *syncer) once(ctx context.Context) {
+	ctx, span := telemetry.NewSpan(ctx, "server.sync")
+	defer span.End()
+
+	err := s.fn(ctx)
+	if ctx.Err() != nil {
+		return // server is shutting down
+	}
+	now := time.Now()
+	status := SyncStatus{Last: now, Next: now.Add(s.interval), RateLimited: errors.Is(err, ErrRateLimited)}
+	switch {
+	case status.RateLimited:
+		slog.Warn("sync stopped by rate limit", "err", err)
+	case err != nil:
+		status.Error = err.Error()
+		slog.Error("sync failed", "err", telemetry.Error(span, err))
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status = status
+	for ch := range s.subscribers {
+		select {
+		case ch <- status:
+		default: // page hasn't read the previous status yet, so it will refresh anyway
+		}
+	}
+}
+
+func (s *syncer) Status() SyncStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.status
+}
+
+// subscribe returns channel that gets status after every sync and function that closes it
+func (s *syncer) subscribe() (<-chan SyncStatus, func()) {
+	ch := make(chan SyncStatus, 1)
+	s.mu.Lock()
+	s.subscribers[ch] = true
+	s.mu.Unlock()
+	return ch, func() {
+		s.mu.Lock()
+		delete(s.subscribers, ch)
+		s.mu.Unlock()
+	}
+}
+
+// syncGet renders status badge. Without sync there is no content, so htmx leaves page as it is.
+func syncGet(renderer *Template, s *syncer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s == nil {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		if err := renderer.tmpl.ExecuteTemplate(w, "sync-status", s.Status()); err != nil {
+			http.Error(w, "Template rendering failed", http.StatusInternalServerError)
+		}
+	}
+}
+
+// eventsGet sends server-sent event after every sync, so that open pages can refresh.
+// Without sync there is no content, which tells EventSource not to reconnect. Stream ends, when sync stops.
+func eventsGet(s *syncer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s == nil {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		rc := http.NewResponseController(w)
+		// stream stays open much longer than server's write timeout
+		if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+			http.Error(w, "Streaming not supported", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.WriteHeader(http.StatusOK)
+		_ = rc.Flush()
+		updates, unsubscribe := s.subscribe()
+		defer unsubscribe()
+		ticker := time.NewTicker(keepAlive)
+		defer ticker.Stop()
+		for {
+			var err error
+			select {
+			case <-s.stopped:
+				return
+			case <-r.Context().Done():
+				return
+			case <-ticker.C:
+				_, err = fmt.Fprint(w, ": keep-alive\n\n")
+			case status := <-updates:
+				_, err = fmt.Fprintf(w, "event: sync\ndata: %s\n\n", status.Last.Format(time.RFC3339))
+			}
+			if err != nil || rc.Flush() != nil {
+				return
+			}
+		}
+	}
+}
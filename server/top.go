@@ -3,12 +3,14 @@ package server
 import (
 	"context"
 	"errors"
-	"log/slog"
 	"net/http"
-	"strconv"
+	"net/url"
 
+	"github.com/jylitalo/mystats/pkg/present"
 	"github.com/jylitalo/mystats/pkg/stats"
 	"github.com/jylitalo/mystats/pkg/telemetry"
+	"github.com/jylitalo/mystats/pkg/units"
+	"github.com/jylitalo/mystats/storage"
 )
 
 type TopFormData struct {
@@ -17,111 +19,87 @@ type TopFormData struct {
 	MeasureOptions []string
 	Period         string
 	PeriodOptions  []string
-	Sports         map[string]bool
-	Workouts       map[string]bool
-	Years          map[int]bool
 	Limit          int
+	FilterFormData
 }
 
-func newTopFormData(years []int, sports, workouts map[string]bool) TopFormData {
-	yearSelection := map[int]bool{}
-	for _, y := range years {
-		yearSelection[y] = true
+// newTopFormData reads form from query string. Bare URL has top 10 weeks by distance from all years.
+func newTopFormData(ctx context.Context, db Storage, cfg *pageConfig, values url.Values) (TopFormData, error) {
+	filters, err := newFilterFormData(ctx, db, cfg, values, allYears)
+	if err != nil {
+		return TopFormData{}, err
 	}
-	return TopFormData{
+	form := TopFormData{
 		Name:           "top",
-		Measure:        "distance",
-		MeasureOptions: []string{"distance", "elevation", "time"},
-		Period:         "week",
+		MeasureOptions: stats.Measures(),
 		PeriodOptions:  []string{"week", "month"},
-		Sports:         sports,
-		Workouts:       workouts,
-		Years:          yearSelection,
-		Limit:          10,
+		FilterFormData: filters,
 	}
+	limit, errL := intValue(values, "limit", 10)
+	form.Limit, err = optionValue(limit, 10, []int{10, 20, 100})
+	var errM, errP error
+	form.Measure, errM = optionValue(values.Get("measure"), "distance", form.MeasureOptions)
+	form.Period, errP = optionValue(values.Get("period"), "week", form.PeriodOptions)
+	return form, errors.Join(errL, err, errM, errP)
 }
 
 type topStatsFn func(
 	ctx context.Context, db stats.Storage, measure, period string, sports, workouts []string,
-	limit int, years []int,
-) ([]string, [][]string, error)
+	limit int, years []int, filters ...storage.QueryOption,
+) (*stats.TopResult, error)
 
 type TopData struct {
 	Measure string
 	Period  string
-	stats   topStatsFn
 	TableData
 }
 
-func newTopData(
-	ctx context.Context, db Storage, measure, period string,
-	sports, workouts []string, limit int, years []int, stats topStatsFn,
-) (*TopData, error) {
-	var err error
-	data := &TopData{
-		Measure: measure,
-		Period:  period,
-		stats:   stats,
+func newTopData(ctx context.Context, db Storage, cfg *pageConfig, form TopFormData) (TopData, error) {
+	data := TopData{Measure: form.Measure, Period: form.Period}
+	sports, workouts, years, filters, err := form.filters()
+	if err != nil {
+		return data, err
 	}
-	data.Headers, data.Rows, err = data.stats(ctx, db, measure, period, sports, workouts, limit, years)
-	return data, err
+	result, err := cfg.topStats(ctx, db, form.Measure, form.Period, sports, workouts, form.Limit, years, filters...)
+	if err != nil || result == nil {
+		return data, err
+	}
+	data.Headers, data.Rows = present.Top(result, units.FromContext(ctx))
+	return data, nil
 }
 
 type TopPage struct {
 	Form TopFormData
-	Data *TopData
+	Data TopData
 }
 
-func newTopPage(
-	ctx context.Context, db Storage, years []int,
-	sports, workouts map[string]bool, stats topStatsFn,
-) (*TopPage, error) {
-	form := newTopFormData(years, sports, workouts)
-	data, err := newTopData(
-		ctx, db, form.Measure, form.Period, selectedSports(sports),
-		selectedWorkouts(workouts), form.Limit, years, stats,
-	)
-	return &TopPage{Form: form, Data: data}, err
+func newTopPage(ctx context.Context, db Storage, cfg *pageConfig, values url.Values) (*TopPage, error) {
+	form, err := newTopFormData(ctx, db, cfg, values)
+	if err != nil {
+		return nil, err
+	}
+	data, err := newTopData(ctx, db, cfg, form)
+	if err != nil {
+		return nil, err
+	}
+	return &TopPage{Form: form, Data: data}, nil
 }
 
-func topPost(ctx context.Context, renderer *Template, page *TopPage, db Storage) http.HandlerFunc {
+func topGet(renderer *Template, cfg *pageConfig, db Storage) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		var err, errL, errS, errW, errY error
-
-		ctx, span := telemetry.NewSpan(ctx, "topPOST")
+		ctx, span := telemetry.NewSpan(r.Context(), "topGET")
 		defer span.End()
-		if err := r.ParseForm(); err != nil {
-			http.Error(w, "Invalid form data", http.StatusBadRequest)
-			_ = telemetry.Error(span, err)
-			return
-		}
-		values := r.Form
-		slog.Info("POST /top", "values", values)
-		page.Form.Sports, errS = sportsValues(values)
-		page.Form.Workouts, errW = workoutsValues(values)
-		page.Form.Years, errY = yearValues(values)
-		page.Form.Limit, errL = strconv.Atoi(r.FormValue("limit"))
-		if err = errors.Join(err, errS, errW, errY, errL); err != nil {
-			_ = telemetry.Error(span, err)
-			return
-		}
-		page.Form.Measure = r.FormValue("Measure")
-		page.Form.Period = r.FormValue("Period")
-		page.Data, err = newTopData(
-			ctx, db, page.Form.Measure, page.Form.Period,
-			selectedSports(page.Form.Sports),
-			selectedWorkouts(page.Form.Workouts), page.Form.Limit,
-			selectedYears(page.Form.Years),
-			page.Data.stats,
-		)
+
+		page, err := newTopPage(ctx, db, cfg, r.URL.Query())
 		if err != nil {
+			status := errorStatus(err)
+			http.Error(w, http.StatusText(status), status)
 			_ = telemetry.Error(span, err)
 			return
 		}
-		if err := renderer.tmpl.ExecuteTemplate(w, "top-data", page.Data); err != nil {
-			_ = telemetry.Error(span, err)
+		if err := render(w, r, renderer, &Page{Tab: "top", Top: page}, "top-data", page.Data); err != nil {
 			http.Error(w, "Template rendering failed", http.StatusInternalServerError)
-			return
+			_ = telemetry.Error(span, err)
 		}
 	}
 }
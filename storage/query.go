@@ -0,0 +1,436 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Table is database table
+type Table string
+
+// BestEffortTable is where Strava's running Best Effort estimates are stored
+const BestEffortTable Table = "BestEffort"
+
+// DailyStepsTable is where Garmin's daily steps count is stored
+const DailyStepsTable Table = "DailySteps"
+
+// ElevationProfileTable is where elevation along the route of Strava activities is stored
+const ElevationProfileTable Table = "ElevationProfile"
+
+// HeartRateTable is where Garmin's daily resting heartrate is stored
+const HeartRateTable Table = "HeartRate"
+
+// LapTable is where laps of Strava activities are stored
+const LapTable Table = "Lap"
+
+// SplitTable is where Strava activities Split times are stored
+const SplitTable Table = "Split"
+
+// SummaryTable is where Strava's summary about activity are stored
+const SummaryTable Table = "Summary"
+
+// Expr is SQL expression. Expressions are only built from columns and functions of this package,
+// so that user input ends up in query parameters and never in SQL.
+type Expr interface {
+	sql() string
+	columns() []Column
+	aggregated() bool
+	validate() error
+}
+
+// Column is column in table. Columns are listed in Summary, BestEffort, Split, DailySteps and HeartRate.
+type Column struct {
+	table Table
+	name  string
+}
+
+func (c Column) sql() string       { return string(c.table) + "." + c.name }
+func (c Column) String() string    { return c.sql() }
+func (c Column) columns() []Column { return []Column{c} }
+func (c Column) aggregated() bool  { return false }
+func (c Column) validate() error   { return nil }
+
+// DateKeys are date columns that Summary and Garmin's daily tables share
+type DateKeys struct {
+	Year, Month, Day, Week Column
+}
+
+// SeasonYear is year when season started, when season starts from month and day (e.g. 1st of November)
+func (k DateKeys) SeasonYear(month, day int) Expr {
+	return function{
+		format: "(case when %s*100+%s>=" + strconv.Itoa(month*100+day) + " then %s else %s-1 end)",
+		args:   []Expr{k.Month, k.Day, k.Year, k.Year},
+	}
+}
+
+// YearKey is season year, when options have WithSeason, and calendar year otherwise
+func (k DateKeys) YearKey(opts ...QueryOption) Expr {
+	if cfg := NewQueryConfig(opts...); cfg.HasSeason() {
+		return k.SeasonYear(cfg.SeasonMonth, cfg.SeasonDay)
+	}
+	return k.Year
+}
+
+// seasonKey grows from 0 at start of season until end of season
+func (k DateKeys) seasonKey(month, day int) Expr {
+	return function{
+		format: "((%s*100+%s-" + strconv.Itoa(month*100+day) + "+1200)%%1200)",
+		args:   []Expr{k.Month, k.Day},
+	}
+}
+
+func dateKeys(t Table) DateKeys {
+	return DateKeys{
+		Year: Column{t, "Year"}, Month: Column{t, "Month"}, Day: Column{t, "Day"}, Week: Column{t, "Week"},
+	}
+}
+
+// Summary has Strava's summary of each activity
+var Summary = struct {
+	Table
+	DateKeys
+	StartDate, WeekYear, StravaID, Name, Type, SportType, WorkoutType, Distance, Elevation,
+	ElapsedTime, MovingTime, AverageHeartrate, Kilojoules, SummaryPolyline Column
+}{
+	Table: SummaryTable, DateKeys: dateKeys(SummaryTable),
+	StartDate: Column{SummaryTable, "StartDate"}, WeekYear: Column{SummaryTable, "WeekYear"},
+	StravaID: Column{SummaryTable, "StravaID"}, Name: Column{SummaryTable, "Name"},
+	Type: Column{SummaryTable, "Type"}, SportType: Column{SummaryTable, "SportType"},
+	WorkoutType: Column{SummaryTable, "WorkoutType"}, Distance: Column{SummaryTable, "Distance"},
+	Elevation: Column{SummaryTable, "Elevation"}, ElapsedTime: Column{SummaryTable, "ElapsedTime"},
+	MovingTime: Column{SummaryTable, "MovingTime"}, AverageHeartrate: Column{SummaryTable, "AverageHeartrate"},
+	Kilojoules: Column{SummaryTable, "Kilojoules"}, SummaryPolyline: Column{SummaryTable, "SummaryPolyline"},
+}
+
+// BestEffort has Strava's best effort estimates of running activities
+var BestEffort = struct {
+	Table
+	StravaID, Name, ElapsedTime, MovingTime, Distance Column
+}{
+	Table: BestEffortTable, StravaID: Column{BestEffortTable, "StravaID"}, Name: Column{BestEffortTable, "Name"},
+	ElapsedTime: Column{BestEffortTable, "ElapsedTime"}, MovingTime: Column{BestEffortTable, "MovingTime"},
+	Distance: Column{BestEffortTable, "Distance"},
+}
+
+// Split has kilometer and mile splits of activities
+var Split = struct {
+	Table
+	StravaID, Split, ElapsedTime, MovingTime, Distance, ElevationDiff, Units, AverageHeartrate Column
+}{
+	Table: SplitTable, StravaID: Column{SplitTable, "StravaID"}, Split: Column{SplitTable, "Split"},
+	ElapsedTime: Column{SplitTable, "ElapsedTime"}, MovingTime: Column{SplitTable, "MovingTime"},
+	Distance: Column{SplitTable, "Distance"}, ElevationDiff: Column{SplitTable, "ElevationDiff"},
+	Units: Column{SplitTable, "Units"}, AverageHeartrate: Column{SplitTable, "AverageHeartrate"},
+}
+
+// Lap has laps of activities, e.g. intervals or auto laps of device
+var Lap = struct {
+	Table
+	StravaID, Lap, Name, ElapsedTime, MovingTime, Distance, Elevation, AverageHeartrate Column
+}{
+	Table: LapTable, StravaID: Column{LapTable, "StravaID"}, Lap: Column{LapTable, "Lap"},
+	Name: Column{LapTable, "Name"}, ElapsedTime: Column{LapTable, "ElapsedTime"},
+	MovingTime: Column{LapTable, "MovingTime"}, Distance: Column{LapTable, "Distance"},
+	Elevation: Column{LapTable, "Elevation"}, AverageHeartrate: Column{LapTable, "AverageHeartrate"},
+}
+
+// ElevationProfile has altitude at points along the route of activities. Points come from streams.
+var ElevationProfile = struct {
+	Table
+	StravaID, Distance, Altitude Column
+}{
+	Table: ElevationProfileTable, StravaID: Column{ElevationProfileTable, "StravaID"},
+	Distance: Column{ElevationProfileTable, "Distance"}, Altitude: Column{ElevationProfileTable, "Altitude"},
+}
+
+// DailySteps has Garmin's step count of each day
+var DailySteps = struct {
+	Table
+	DateKeys
+	Date, TotalSteps, StepGoal Column
+}{
+	Table: DailyStepsTable, DateKeys: dateKeys(DailyStepsTable), Date: Column{DailyStepsTable, "Date"},
+	TotalSteps: Column{DailyStepsTable, "TotalSteps"}, StepGoal: Column{DailyStepsTable, "StepGoal"},
+}
+
+// HeartRate has Garmin's heart rate of each day
+var HeartRate = struct {
+	Table
+	DateKeys
+	Date, RestingHR, WellnessMinAvgHR, WellnessMaxAvgHR Column
+}{
+	Table: HeartRateTable, DateKeys: dateKeys(HeartRateTable), Date: Column{HeartRateTable, "Date"},
+	RestingHR: Column{HeartRateTable, "RestingHR"}, WellnessMinAvgHR: Column{HeartRateTable, "WellnessMinAvgHR"},
+	WellnessMaxAvgHR: Column{HeartRateTable, "WellnessMaxAvgHR"},
+}
+
+// dates are columns of date range filters. Summary has start time of activity, Garmin's tables date of day.
+var dates = map[Table]Column{
+	SummaryTable: Summary.StartDate, DailyStepsTable: DailySteps.Date, HeartRateTable: HeartRate.Date,
+}
+
+// stravaIDs are columns of WithStravaID filter
+var stravaIDs = map[Table]Column{
+	SummaryTable: Summary.StravaID, BestEffortTable: BestEffort.StravaID, SplitTable: Split.StravaID,
+	LapTable: Lap.StravaID, ElevationProfileTable: ElevationProfile.StravaID,
+}
+
+// DateKeys returns year, month, day and week columns of table
+func (t Table) DateKeys() (DateKeys, error) {
+	if _, ok := dates[t]; !ok {
+		return DateKeys{}, fmt.Errorf("table %s doesn't have dates", t)
+	}
+	return dateKeys(t), nil
+}
+
+// function is SQL function or operator. Format has %s for each argument and never comes from user.
+type function struct {
+	format    string
+	args      []Expr
+	aggregate bool
+}
+
+func (f function) sql() string {
+	args := make([]any, len(f.args))
+	for idx, arg := range f.args {
+		args[idx] = arg.sql()
+	}
+	return fmt.Sprintf(f.format, args...)
+}
+
+func (f function) String() string { return f.sql() }
+
+func (f function) columns() []Column {
+	columns := []Column{}
+	for _, arg := range f.args {
+		columns = append(columns, arg.columns()...)
+	}
+	return columns
+}
+
+func (f function) aggregated() bool {
+	return f.aggregate || slices.ContainsFunc(f.args, Expr.aggregated)
+}
+
+func (f function) validate() error {
+	for _, arg := range f.args {
+		if f.aggregate && arg.aggregated() {
+			return fmt.Errorf("aggregate inside aggregate in %s", f.sql())
+		}
+		if err := arg.validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Count counts rows
+func Count() Expr { return function{format: "count(*)", aggregate: true} }
+
+// Sum adds values together
+func Sum(e Expr) Expr { return function{format: "sum(%s)", args: []Expr{e}, aggregate: true} }
+
+// Avg is average of values
+func Avg(e Expr) Expr { return function{format: "avg(%s)", args: []Expr{e}, aggregate: true} }
+
+// Min is smallest value
+func Min(e Expr) Expr { return function{format: "min(%s)", args: []Expr{e}, aggregate: true} }
+
+// Max is largest value
+func Max(e Expr) Expr { return function{format: "max(%s)", args: []Expr{e}, aggregate: true} }
+
+// NullIfZero turns zeros into NULLs (e.g. activities without heart rate), so that aggregates skip them
+func NullIfZero(e Expr) Expr { return function{format: "nullif(%s, 0)", args: []Expr{e}} }
+
+// Ratio divides a with b as floating point numbers
+func Ratio(a, b Expr) Expr { return function{format: "(%s*1.0/%s)", args: []Expr{a, b}} }
+
+// Scale multiplies value with factor (e.g. meters into kilometers with 0.001)
+func Scale(e Expr, factor float64) Expr {
+	return function{format: "(%s*" + strconv.FormatFloat(factor, 'g', -1, 64) + ")", args: []Expr{e}}
+}
+
+// Date is date part (YYYY-MM-DD) of timestamp
+func Date(c Column) Expr { return function{format: "date(%s)", args: []Expr{c}} }
+
+// Order sorts query results
+type Order struct {
+	expr      Expr
+	desc      bool
+	nullsLast bool
+}
+
+// Asc sorts from smallest to largest
+func Asc(e Expr) Order { return Order{expr: e} }
+
+// Desc sorts from largest to smallest
+func Desc(e Expr) Order { return Order{expr: e, desc: true} }
+
+// NullsLast puts rows without value last
+func (o Order) NullsLast() Order {
+	o.nullsLast = true
+	return o
+}
+
+func (o Order) sql() string {
+	text := o.expr.sql()
+	if o.desc {
+		text += " desc"
+	}
+	if o.nullsLast {
+		text = o.expr.sql() + " is null," + text
+	}
+	return text
+}
+
+type join struct {
+	table       Table
+	left, right Column
+}
+
+// Query is select statement. Build it with Select and run it with Sqlite3.Query.
+type Query struct {
+	columns []Expr
+	table   Table
+	joins   []join
+	opts    []QueryOption
+	groupBy []Expr
+	orderBy []Order
+	limit   int
+	offset  int
+}
+
+// Select starts query with columns (or expressions) in result
+func Select(columns ...Expr) *Query {
+	return &Query{columns: columns}
+}
+
+// From sets main table of query
+func (q *Query) From(t Table) *Query {
+	q.table = t
+	return q
+}
+
+// Join adds table into query. Rows are joined, when left and right columns are equal.
+func (q *Query) Join(t Table, left, right Column) *Query {
+	q.joins = append(q.joins, join{table: t, left: left, right: right})
+	return q
+}
+
+// Where adds filters into query
+func (q *Query) Where(opts ...QueryOption) *Query {
+	q.opts = append(q.opts, opts...)
+	return q
+}
+
+// GroupBy aggregates rows that have same values
+func (q *Query) GroupBy(exprs ...Expr) *Query {
+	q.groupBy = append(q.groupBy, exprs...)
+	return q
+}
+
+// OrderBy sorts results
+func (q *Query) OrderBy(orders ...Order) *Query {
+	q.orderBy = append(q.orderBy, orders...)
+	return q
+}
+
+// Limit limits number of rows. Zero means no limit.
+func (q *Query) Limit(n int) *Query {
+	q.limit = n
+	return q
+}
+
+// Offset skips first n rows, e.g. for pagination
+func (q *Query) Offset(n int) *Query {
+	q.offset = n
+	return q
+}
+
+// SQL returns query as SQL with its parameters, e.g. for debugging
+func (q *Query) SQL() (string, []any, error) {
+	return sqlQuery(q)
+}
+
+// knownTables are tables that Create makes
+var knownTables = []Table{
+	SummaryTable, BestEffortTable, SplitTable, LapTable, ElevationProfileTable, DailyStepsTable, HeartRateTable,
+}
+
+// tables lists main table and joined tables
+func (q *Query) tables() []Table {
+	tables := []Table{q.table}
+	for _, j := range q.joins {
+		tables = append(tables, j.table)
+	}
+	return tables
+}
+
+// validate checks that tables exist, columns are from tables of query and aggregates are grouped properly
+func (q *Query) validate() error {
+	if q.table == "" {
+		return errors.New("no table in query")
+	}
+	if len(q.columns) == 0 {
+		return errors.New("no columns in query")
+	}
+	tables := q.tables()
+	for idx, t := range tables {
+		if !slices.Contains(knownTables, t) {
+			return fmt.Errorf("unknown table: %s", t)
+		}
+		if slices.Contains(tables[:idx], t) {
+			return fmt.Errorf("table %s is in query twice", t)
+		}
+	}
+	exprs := slices.Concat(q.columns, q.groupBy)
+	for _, o := range q.orderBy {
+		exprs = append(exprs, o.expr)
+	}
+	for _, j := range q.joins {
+		exprs = append(exprs, j.left, j.right)
+	}
+	for _, c := range NewQueryConfig(q.opts...).Conditions {
+		if c.Column == nil {
+			return errors.New("condition without column")
+		}
+		exprs = append(exprs, c.Column)
+	}
+	for _, e := range exprs {
+		if err := e.validate(); err != nil {
+			return err
+		}
+		for _, c := range e.columns() {
+			if !slices.Contains(tables, c.table) {
+				return fmt.Errorf("column %s needs table %s in query", c.sql(), c.table)
+			}
+		}
+	}
+	grouped := []string{}
+	for _, e := range q.groupBy {
+		if e.aggregated() {
+			return fmt.Errorf("can't group by aggregate %s", e.sql())
+		}
+		grouped = append(grouped, e.sql())
+	}
+	if !slices.ContainsFunc(q.columns, Expr.aggregated) {
+		return nil
+	}
+	for _, e := range q.columns {
+		if !e.aggregated() && !slices.Contains(grouped, e.sql()) {
+			return fmt.Errorf("%s needs to be aggregated or in group by", e.sql())
+		}
+	}
+	return nil
+}
+
+func joinSQL(exprs []Expr) string {
+	texts := make([]string, len(exprs))
+	for idx, e := range exprs {
+		texts[idx] = e.sql()
+	}
+	return strings.Join(texts, ",")
+}
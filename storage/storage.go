@@ -13,12 +13,14 @@ import (
 	"time"
 
 	garmin "github.com/jylitalo/go-garmin"
+	"github.com/jylitalo/mystats/pkg/sport"
 	"github.com/jylitalo/mystats/pkg/telemetry"
 	_ "github.com/mattn/go-sqlite3"
 )
 
 // Strava
 type SummaryRecord struct {
+	StartDate   time.Time
 	Year        int
 	Month       int
 	Day         int
@@ -33,6 +35,11 @@ type SummaryRecord struct {
 	Elevation   float64
 	MovingTime  int
 	ElapsedTime int
+	// AverageHeartrate is zero, when activity doesn't have heart rate data
+	AverageHeartrate float64
+	Kilojoules       float64
+	// SummaryPolyline is route in Google's encoded polyline format. Empty for indoor activities.
+	SummaryPolyline string
 }
 
 type BestEffortRecord struct {
@@ -45,11 +52,33 @@ type BestEffortRecord struct {
 
 type SplitRecord struct {
 	StravaID      int64
+	Units         string // metric (per km) or imperial (per mile)
 	Split         int
 	ElapsedTime   int
 	MovingTime    int
 	ElevationDiff float64
 	Distance      float64
+	// AverageHeartrate is zero, when activity doesn't have heart rate data
+	AverageHeartrate float64
+}
+
+// LapRecord is lap of activity. Laps are numbered from 1.
+type LapRecord struct {
+	StravaID         int64
+	Lap              int
+	Name             string
+	ElapsedTime      int
+	MovingTime       int
+	Distance         float64
+	Elevation        float64
+	AverageHeartrate float64
+}
+
+// ElevationProfileRecord is altitude at distance (both in meters) from the start of activity
+type ElevationProfileRecord struct {
+	StravaID int64
+	Distance float64
+	Altitude float64
 }
 
 // Garmin
@@ -67,37 +96,51 @@ type HeartRateRecord struct {
 	RestingHR        int
 }
 
-type OrderConfig struct {
-	GroupBy []string
-	OrderBy []string
-	Limit   int
-}
-
 type Sqlite3 struct {
 	db *sql.DB
 }
 
 type QueryConfig struct {
-	Tables   []string
-	Name     string
-	StravaID int64
-	Day      int
-	Month    int
-	Years    []int
-	Sport    []string
-	Workout  []string
-	Order    *OrderConfig
+	From        time.Time
+	To          time.Time
+	Name        string
+	StravaID    int64
+	Day         int
+	Month       int
+	SeasonDay   int
+	SeasonMonth int
+	SplitUnits  string
+	Years       []int
+	Sport       []string
+	SportGroups []sport.Group
+	Workout     []string
+	Conditions  []Condition
+}
+
+// Condition compares column (or expression) against values that are bound as query parameters
+type Condition struct {
+	Column   Expr
+	Operator string // =, !=, <, <=, >, >=, like, in or not in
+	Values   []any
 }
 
+// operators are allowed in conditions
+var operators = []string{"=", "!=", "<", "<=", ">", ">=", "like", "in", "not in"}
+
 type QueryOption func(c *QueryConfig)
 
-func WithTable(table string) QueryOption {
-	return func(c *QueryConfig) {
-		if slices.Contains(c.Tables, table) {
-			slog.Error("WithTable already contains table", "c.Tables", c.Tables, "table", table)
-		}
-		c.Tables = append(c.Tables, table)
+// NewQueryConfig applies options into empty QueryConfig
+func NewQueryConfig(opts ...QueryOption) *QueryConfig {
+	cfg := &QueryConfig{}
+	for _, opt := range opts {
+		opt(cfg)
 	}
+	return cfg
+}
+
+// HasSeason tells if years don't start from 1st of January
+func (c *QueryConfig) HasSeason() bool {
+	return c.SeasonMonth > 0 && c.SeasonDay > 0 && (c.SeasonMonth != 1 || c.SeasonDay != 1)
 }
 
 func WithDayOfYear(day, month int) QueryOption {
@@ -107,15 +150,51 @@ func WithDayOfYear(day, month int) QueryOption {
 	}
 }
 
-func WithYears(year ...int) QueryOption {
+// WithSeason makes years start from given day (e.g. 1st of November).
+// Year filters and WithDayOfYear are then relative to season.
+func WithSeason(month, day int) QueryOption {
 	return func(c *QueryConfig) {
-		c.Years = append(c.Years, year...)
+		c.SeasonMonth = month
+		c.SeasonDay = day
+	}
+}
+
+// WithDateRange limits results between from and to (both inclusive days). Zero time leaves range open.
+func WithDateRange(from, to time.Time) QueryOption {
+	return func(c *QueryConfig) {
+		c.From = from
+		c.To = to
+	}
+}
+
+// ParseDateRange turns from and to dates (YYYY-MM-DD) into WithDateRange. Empty date leaves range open and
+// without both dates there is no option at all.
+func ParseDateRange(fromStr, toStr string) ([]QueryOption, error) {
+	var from, to time.Time
+	var err error
+
+	if fromStr == "" && toStr == "" {
+		return nil, nil
+	}
+	if fromStr != "" {
+		if from, err = time.Parse(time.DateOnly, fromStr); err != nil {
+			return nil, fmt.Errorf("invalid from date %s: %w", fromStr, err)
+		}
+	}
+	if toStr != "" {
+		if to, err = time.Parse(time.DateOnly, toStr); err != nil {
+			return nil, fmt.Errorf("invalid to date %s: %w", toStr, err)
+		}
+	}
+	if !from.IsZero() && !to.IsZero() && to.Before(from) {
+		return nil, fmt.Errorf("to date %s is before from date %s", toStr, fromStr)
 	}
+	return []QueryOption{WithDateRange(from, to)}, nil
 }
 
-func WithOrder(order OrderConfig) QueryOption {
+func WithYears(year ...int) QueryOption {
 	return func(c *QueryConfig) {
-		c.Order = &order
+		c.Years = append(c.Years, year...)
 	}
 }
 
@@ -131,35 +210,45 @@ func WithName(name string) QueryOption {
 	}
 }
 
+// WithSplitUnits picks metric or imperial splits from Split table
+func WithSplitUnits(units string) QueryOption {
+	return func(c *QueryConfig) {
+		c.SplitUnits = units
+	}
+}
+
 func WithSports(names ...string) QueryOption {
 	return func(c *QueryConfig) {
 		c.Sport = append(c.Sport, names...)
 	}
 }
 
+// WithSportGroups limits results into activities that belong to any of given groups.
+// Groups are combined with WithSports, so activity needs to match either of them.
+func WithSportGroups(groups ...sport.Group) QueryOption {
+	return func(c *QueryConfig) {
+		c.SportGroups = append(c.SportGroups, groups...)
+	}
+}
+
 func WithWorkouts(names ...string) QueryOption {
 	return func(c *QueryConfig) {
 		c.Workout = append(c.Workout, names...)
 	}
 }
 
+// WithConditions adds conditions that all need to match
+func WithConditions(conditions ...Condition) QueryOption {
+	return func(c *QueryConfig) {
+		c.Conditions = append(c.Conditions, conditions...)
+	}
+}
+
 // dbName is the filename of sqlite file
 const dbName = "mystats.sql"
 
-// BestEffortTable is where Strava's running Best Effort estimates are stored
-const BestEffortTable = "BestEffort"
-
-// DailyStepsTable is where Garmin's daily steps count is stored
-const DailyStepsTable = "DailySteps"
-
-// HeartRateTable is where Garmin's daily resting heartrate is stored
-const HeartRateTable = "HeartRate"
-
-// SplitTable is where Strava activities Split times are stored
-const SplitTable = "Split"
-
-// SummaryTable is where Strava's summary about activity are stored
-const SummaryTable = "Summary"
+// SchemaVersion needs to be increased, when tables change. Older databases are then rebuilt by make.
+const SchemaVersion = 4
 
 func (sq *Sqlite3) Remove() error {
 	if _, err := os.Stat(dbName); err != nil && errors.Is(err, os.ErrNotExist) {
@@ -178,6 +267,32 @@ func (sq *Sqlite3) LastModified() (time.Time, error) {
 	return fi.ModTime().UTC(), nil
 }
 
+// Version returns schema version of database file without keeping it open
+func (sq *Sqlite3) Version() (int, error) {
+	if _, err := os.Stat(dbName); err != nil {
+		return 0, err
+	}
+	db, err := sql.Open("sqlite3", dbName)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = db.Close() }()
+	var version int
+	err = db.QueryRow("pragma user_version").Scan(&version)
+	return version, err
+}
+
+// NewMemory returns database with empty tables, which lives only in memory (e.g. for tests)
+func NewMemory() (*Sqlite3, error) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(1) // every connection would have its own database
+	sq := &Sqlite3{db: db}
+	return sq, sq.Create()
+}
+
 func (sq *Sqlite3) Open() error {
 	var err error
 
@@ -194,30 +309,46 @@ func (sq *Sqlite3) Create() error {
 	ymdw := "Year integer, Month integer, Day integer, WeekYear, Week integer,"
 	stravaId := "StravaID integer,"
 	emd := "ElapsedTime integer, MovingTime integer, Distance integer,"
-	_, errSummary := sq.db.Exec(`create table ` + SummaryTable + ` ( ` + ymdw + stravaId + emd + `
+	_, errSummary := sq.db.Exec(`create table ` + string(SummaryTable) + ` ( StartDate text, ` + ymdw + stravaId + emd + `
 		Name        text,
 		Type        text,
 		SportType   text,
 		WorkoutType text,
-		Elevation   real
+		Elevation   real,
+		AverageHeartrate real,
+		Kilojoules       real,
+		SummaryPolyline  text
 	)`)
-	_, errBE := sq.db.Exec(`create table ` + BestEffortTable + ` ( ` + stravaId + emd + `
+	_, errBE := sq.db.Exec(`create table ` + string(BestEffortTable) + ` ( ` + stravaId + emd + `
 		Name        text
 	)`)
-	_, errSplit := sq.db.Exec(`create table ` + SplitTable + ` ( ` + stravaId + emd + `
+	_, errSplit := sq.db.Exec(`create table ` + string(SplitTable) + ` ( ` + stravaId + emd + `
 		Split         integer,
-		ElevationDiff real
+		ElevationDiff real,
+		Units         text,
+		AverageHeartrate real
+	)`)
+	_, errLap := sq.db.Exec(`create table ` + string(LapTable) + ` ( ` + stravaId + emd + `
+		Lap         integer,
+		Name        text,
+		Elevation   real,
+		AverageHeartrate real
+	)`)
+	_, errProfile := sq.db.Exec(`create table ` + string(ElevationProfileTable) + ` ( ` + stravaId + `
+		Distance    real,
+		Altitude    real
 	)`)
-	_, errSteps := sq.db.Exec(`create table ` + DailyStepsTable + ` ( ` + ymdw + `
+	_, errSteps := sq.db.Exec(`create table ` + string(DailyStepsTable) + ` ( Date text, ` + ymdw + `
 		TotalSteps  integer,
 		StepGoal    integer
 	)`)
-	_, errHeartRate := sq.db.Exec(`create table ` + HeartRateTable + ` ( ` + ymdw + `
+	_, errHeartRate := sq.db.Exec(`create table ` + string(HeartRateTable) + ` ( Date text, ` + ymdw + `
 		WellnessMinAvgHR integer,
 		WellnessMaxAvgHR integer,
 		RestingHR integer
 	)`)
-	return errors.Join(errSummary, errBE, errHeartRate, errSplit, errSteps)
+	_, errVersion := sq.db.Exec("pragma user_version = " + strconv.Itoa(SchemaVersion))
+	return errors.Join(errSummary, errBE, errHeartRate, errSplit, errLap, errProfile, errSteps, errVersion)
 }
 
 func (sq *Sqlite3) InsertSummary(ctx context.Context, records []SummaryRecord) error {
@@ -231,21 +362,25 @@ func (sq *Sqlite3) InsertSummary(ctx context.Context, records []SummaryRecord) e
 		return telemetry.Error(span, err)
 	}
 	fields := []string{
-		"Year", "Month", "Day", "WeekYear", "Week", "StravaID", "Name", "Type", "SportType", "WorkoutType",
-		"Distance", "Elevation", "ElapsedTime", "MovingTime",
+		"StartDate", "Year", "Month", "Day", "WeekYear", "Week", "StravaID", "Name", "Type", "SportType",
+		"WorkoutType", "Distance", "Elevation", "ElapsedTime", "MovingTime", "AverageHeartrate", "Kilojoules",
+		"SummaryPolyline",
 	}
 	q := strings.Repeat("?,", len(fields)-1) + "?"
 	// #nosec G202
-	stmt, err := tx.Prepare("insert into " + SummaryTable + "(" + strings.Join(fields, ",") + ") values (" + q + ")")
+	stmt, err := tx.Prepare(
+		"insert into " + string(SummaryTable) + "(" + strings.Join(fields, ",") + ") values (" + q + ")",
+	)
 	if err != nil {
 		return telemetry.Error(span, fmt.Errorf("InsertSummary caused %w", err))
 	}
 	defer func() { _ = stmt.Close() }()
 	for _, r := range records {
 		_, err = stmt.Exec(
-			r.Year, r.Month, r.Day, r.WeekYear, r.Week, r.StravaID,
+			r.StartDate.Format(time.DateTime), r.Year, r.Month, r.Day, r.WeekYear, r.Week, r.StravaID,
 			r.Name, r.Type, r.SportType, r.WorkoutType,
-			r.Distance, r.Elevation, r.ElapsedTime, r.MovingTime,
+			r.Distance, r.Elevation, r.ElapsedTime, r.MovingTime, r.AverageHeartrate, r.Kilojoules,
+			r.SummaryPolyline,
 		)
 		if err != nil {
 			return fmt.Errorf("InsertSummary statement execution caused: %w", err)
@@ -267,7 +402,9 @@ func (sq *Sqlite3) InsertBestEffort(ctx context.Context, records []BestEffortRec
 	fields := []string{"StravaID", "Name", "ElapsedTime", "MovingTime", "Distance"}
 	q := strings.Repeat("?,", len(fields)-1) + "?"
 	// #nosec G202
-	stmt, err := tx.Prepare("insert into " + BestEffortTable + "(" + strings.Join(fields, ",") + ") values (" + q + ")")
+	stmt, err := tx.Prepare(
+		"insert into " + string(BestEffortTable) + "(" + strings.Join(fields, ",") + ") values (" + q + ")",
+	)
 	if err != nil {
 		return telemetry.Error(span, fmt.Errorf("InsertBestEffort caused %w", err))
 	}
@@ -290,22 +427,88 @@ func (sq *Sqlite3) InsertSplit(ctx context.Context, records []SplitRecord) error
 	if err != nil {
 		return telemetry.Error(span, err)
 	}
-	fields := []string{"StravaID", "Split", "ElapsedTime", "MovingTime", "Distance", "ElevationDiff"}
+	fields := []string{
+		"StravaID", "Split", "ElapsedTime", "MovingTime", "Distance", "ElevationDiff", "Units", "AverageHeartrate",
+	}
 	q := strings.Repeat("?,", len(fields)-1) + "?"
 	// #nosec G202
-	stmt, err := tx.Prepare("insert into " + SplitTable + "(" + strings.Join(fields, ",") + ") values (" + q + ")")
+	stmt, err := tx.Prepare(
+		"insert into " + string(SplitTable) + "(" + strings.Join(fields, ",") + ") values (" + q + ")",
+	)
 	if err != nil {
 		return telemetry.Error(span, fmt.Errorf("InsertSplit caused %w", err))
 	}
 	defer func() { _ = stmt.Close() }()
 	for _, r := range records {
-		if _, err = stmt.Exec(r.StravaID, r.Split, r.ElapsedTime, r.MovingTime, r.Distance, r.ElevationDiff); err != nil {
+		_, err = stmt.Exec(
+			r.StravaID, r.Split, r.ElapsedTime, r.MovingTime, r.Distance, r.ElevationDiff, r.Units, r.AverageHeartrate,
+		)
+		if err != nil {
 			return telemetry.Error(span, fmt.Errorf("InsertSplit statement execution caused: %w", err))
 		}
 	}
 	return telemetry.Error(span, tx.Commit())
 }
 
+func (sq *Sqlite3) InsertLap(ctx context.Context, records []LapRecord) error {
+	_, span := telemetry.NewSpan(ctx, "InsertLap")
+	defer span.End()
+	if sq.db == nil {
+		return telemetry.Error(span, errors.New("database is nil"))
+	}
+	tx, err := sq.db.Begin()
+	if err != nil {
+		return telemetry.Error(span, err)
+	}
+	fields := []string{
+		"StravaID", "Lap", "Name", "ElapsedTime", "MovingTime", "Distance", "Elevation", "AverageHeartrate",
+	}
+	q := strings.Repeat("?,", len(fields)-1) + "?"
+	// #nosec G202
+	stmt, err := tx.Prepare(
+		"insert into " + string(LapTable) + "(" + strings.Join(fields, ",") + ") values (" + q + ")",
+	)
+	if err != nil {
+		return telemetry.Error(span, fmt.Errorf("InsertLap caused %w", err))
+	}
+	defer func() { _ = stmt.Close() }()
+	for _, r := range records {
+		_, err = stmt.Exec(
+			r.StravaID, r.Lap, r.Name, r.ElapsedTime, r.MovingTime, r.Distance, r.Elevation, r.AverageHeartrate,
+		)
+		if err != nil {
+			return telemetry.Error(span, fmt.Errorf("InsertLap statement execution caused: %w", err))
+		}
+	}
+	return telemetry.Error(span, tx.Commit())
+}
+
+func (sq *Sqlite3) InsertElevationProfile(ctx context.Context, records []ElevationProfileRecord) error {
+	_, span := telemetry.NewSpan(ctx, "InsertElevationProfile")
+	defer span.End()
+	if sq.db == nil {
+		return telemetry.Error(span, errors.New("database is nil"))
+	}
+	tx, err := sq.db.Begin()
+	if err != nil {
+		return telemetry.Error(span, err)
+	}
+	// #nosec G202
+	stmt, err := tx.Prepare(
+		"insert into " + string(ElevationProfileTable) + "(StravaID,Distance,Altitude) values (?,?,?)",
+	)
+	if err != nil {
+		return telemetry.Error(span, fmt.Errorf("InsertElevationProfile caused %w", err))
+	}
+	defer func() { _ = stmt.Close() }()
+	for _, r := range records {
+		if _, err = stmt.Exec(r.StravaID, r.Distance, r.Altitude); err != nil {
+			return telemetry.Error(span, fmt.Errorf("InsertElevationProfile statement execution caused: %w", err))
+		}
+	}
+	return telemetry.Error(span, tx.Commit())
+}
+
 func (sq *Sqlite3) InsertDailySteps(ctx context.Context, records map[string]garmin.DailyStepsStat) error {
 	_, span := telemetry.NewSpan(ctx, "InsertDailySteps")
 	defer span.End()
@@ -317,10 +520,12 @@ func (sq *Sqlite3) InsertDailySteps(ctx context.Context, records map[string]garm
 	if err != nil {
 		return telemetry.Error(span, err)
 	}
-	fields := []string{"Year", "Month", "Day", "Week", "TotalSteps", "StepGoal"}
+	fields := []string{"Date", "Year", "Month", "Day", "Week", "TotalSteps", "StepGoal"}
 	q := strings.Repeat("?,", len(fields)-1) + "?"
 	// #nosec G202
-	stmt, err := tx.Prepare("insert into " + DailyStepsTable + "(" + strings.Join(fields, ",") + ") values (" + q + ")")
+	stmt, err := tx.Prepare(
+		"insert into " + string(DailyStepsTable) + "(" + strings.Join(fields, ",") + ") values (" + q + ")",
+	)
 	if err != nil {
 		return telemetry.Error(span, fmt.Errorf("InsertDailySteps caused %w", err))
 	}
@@ -330,7 +535,8 @@ func (sq *Sqlite3) InsertDailySteps(ctx context.Context, records map[string]garm
 			return telemetry.Error(span, fmt.Errorf("InsertDailySteps time parsing (%s) caused: %w", key, err))
 		}
 		_, week := t.ISOWeek()
-		if _, err = stmt.Exec(t.Year(), t.Month(), t.Day(), week, r.TotalSteps, r.StepGoal); err != nil {
+		_, err = stmt.Exec(t.Format(time.DateTime), t.Year(), t.Month(), t.Day(), week, r.TotalSteps, r.StepGoal)
+		if err != nil {
 			return telemetry.Error(span, fmt.Errorf("InsertDailySteps statement execution caused: %w", err))
 		}
 	}
@@ -348,10 +554,12 @@ func (sq *Sqlite3) InsertHeartRate(ctx context.Context, records map[string]garmi
 	if err != nil {
 		return telemetry.Error(span, err)
 	}
-	fields := []string{"Year", "Month", "Day", "Week", "WellnessMinAvgHR", "WellnessMaxAvgHR", "RestingHR"}
+	fields := []string{"Date", "Year", "Month", "Day", "Week", "WellnessMinAvgHR", "WellnessMaxAvgHR", "RestingHR"}
 	q := strings.Repeat("?,", len(fields)-1) + "?"
 	// #nosec G202
-	stmt, err := tx.Prepare("insert into " + HeartRateTable + "(" + strings.Join(fields, ",") + ") values (" + q + ")")
+	stmt, err := tx.Prepare(
+		"insert into " + string(HeartRateTable) + "(" + strings.Join(fields, ",") + ") values (" + q + ")",
+	)
 	if err != nil {
 		return telemetry.Error(span, fmt.Errorf("InsertHeartRate caused %w", err))
 	}
@@ -362,7 +570,10 @@ func (sq *Sqlite3) InsertHeartRate(ctx context.Context, records map[string]garmi
 			return telemetry.Error(span, fmt.Errorf("InsertHeartRate time parsing (%s) caused: %w", key, err))
 		}
 		_, week := t.ISOWeek()
-		_, err = stmt.Exec(t.Year(), t.Month(), t.Day(), week, r.WellnessMinAvgHR, r.WellnessMaxAvgHR, r.RestingHR)
+		_, err = stmt.Exec(
+			t.Format(time.DateTime), t.Year(), t.Month(), t.Day(), week,
+			r.WellnessMinAvgHR, r.WellnessMaxAvgHR, r.RestingHR,
+		)
 		if err != nil {
 			return telemetry.Error(span, fmt.Errorf("InsertHeartRate statement execution caused: %w", err))
 		}
@@ -371,185 +582,253 @@ func (sq *Sqlite3) InsertHeartRate(ctx context.Context, records map[string]garmi
 	return telemetry.Error(span, tx.Commit())
 }
 
-func sqlQuery(fields []string, opts ...QueryOption) (string, []interface{}) { //nolint:cyclop
-	cfg := &QueryConfig{}
-	for _, opt := range opts {
-		opt(cfg)
+// DeleteSince removes rows that are from day onwards. Garmin's latest day is usually incomplete,
+// so sync deletes it before inserting it again.
+func (sq *Sqlite3) DeleteSince(ctx context.Context, t Table, day time.Time) error {
+	_, span := telemetry.NewSpan(ctx, "DeleteSince")
+	defer span.End()
+	if sq.db == nil {
+		return telemetry.Error(span, errors.New("database is nil"))
 	}
-	where := []string{}
-	args := []string{}
-	if len(cfg.Tables) == 0 {
-		panic("no tables specified in sqlQuery")
+	column, ok := dates[t]
+	if !ok {
+		return telemetry.Error(span, fmt.Errorf("table %s doesn't have dates", t))
+	}
+	// #nosec G202
+	_, err := sq.db.ExecContext(ctx, "delete from "+string(t)+" where "+column.sql()+" >= ?",
+		startOfDay(day).Format(time.DateTime))
+	return telemetry.Error(span, err)
+}
+
+// sqlQuery turns query into SQL and its parameters
+func sqlQuery(q *Query) (string, []any, error) { //nolint:cyclop,funlen
+	if q == nil {
+		return "", nil, errors.New("query is nil")
 	}
-	if len(cfg.Tables) > 1 {
-		for _, table := range cfg.Tables[1:] {
-			where = append(where, fmt.Sprintf("%s.StravaID=%s.StravaID", cfg.Tables[0], table))
+	if err := q.validate(); err != nil {
+		return "", nil, err
+	}
+	cfg := NewQueryConfig(q.opts...)
+	tables := q.tables()
+	needs := func(table Table, filter string) error {
+		if !slices.Contains(tables, table) {
+			return fmt.Errorf("%s filter needs table %s in query", filter, table)
 		}
+		return nil
 	}
+	where := []string{}
+	args := []any{}
 	if len(cfg.Workout) > 0 {
-		where = append(where, "(Workouttype="+strings.Repeat("? or Workouttype=", len(cfg.Workout)-1)+"?)")
-		args = append(args, cfg.Workout...)
+		if err := needs(SummaryTable, "workout"); err != nil {
+			return "", nil, err
+		}
+		column := Summary.WorkoutType.sql()
+		where = append(where, "("+column+"="+strings.Repeat("? or "+column+"=", len(cfg.Workout)-1)+"?)")
+		for _, w := range cfg.Workout {
+			args = append(args, w)
+		}
 	}
-	if len(cfg.Sport) > 0 {
-		where = append(where, "(Type="+strings.Repeat("? or Type=", len(cfg.Sport)-1)+"?)")
-		args = append(args, cfg.Sport...)
+	if len(cfg.Sport) > 0 || len(cfg.SportGroups) > 0 {
+		if err := needs(SummaryTable, "sport"); err != nil {
+			return "", nil, err
+		}
+		condition, values := sportCondition(cfg.Sport, cfg.SportGroups)
+		where = append(where, condition)
+		args = append(args, values...)
+	}
+	dateTable := Table("")
+	for _, t := range tables {
+		if _, ok := dates[t]; ok {
+			dateTable = t
+			break
+		}
+	}
+	hasDayOfYear := cfg.Month > 0 && cfg.Day > 0
+	if dateTable == "" && (len(cfg.Years) > 0 || hasDayOfYear || !cfg.From.IsZero() || !cfg.To.IsZero()) {
+		return "", nil, fmt.Errorf("date filters need table with dates in query (not %v)", tables)
+	}
+	keys := dateKeys(dateTable)
+	switch {
+	case cfg.HasSeason() && hasDayOfYear:
+		key := (cfg.Month*100 + cfg.Day - cfg.SeasonMonth*100 - cfg.SeasonDay + 1200) % 1200
+		where = append(where, keys.seasonKey(cfg.SeasonMonth, cfg.SeasonDay).sql()+"<=?")
+		args = append(args, key)
+	case hasDayOfYear:
+		where = append(where, fmt.Sprintf("(%[1]s < ? or (%[1]s=? and %[2]s<=?))", keys.Month.sql(), keys.Day.sql()))
+		args = append(args, cfg.Month, cfg.Month, cfg.Day)
 	}
-	if cfg.Month > 0 && cfg.Day > 0 {
-		where = append(where, "(Month < ? or (Month=? and Day<=?))")
-		month := strconv.Itoa(cfg.Month)
-		args = append(args, month, month, strconv.Itoa(cfg.Day))
+	if !cfg.From.IsZero() {
+		where = append(where, dates[dateTable].sql()+">=?")
+		args = append(args, startOfDay(cfg.From).Format(time.DateTime))
+	}
+	if !cfg.To.IsZero() {
+		where = append(where, dates[dateTable].sql()+"<?")
+		args = append(args, startOfDay(cfg.To).AddDate(0, 0, 1).Format(time.DateTime))
 	}
 	if len(cfg.Years) > 0 {
-		where = append(where, "(Year="+strings.Repeat("? or Year=", len(cfg.Years)-1)+"?)")
+		year := keys.YearKey(q.opts...)
+		where = append(where, "("+year.sql()+"="+strings.Repeat("? or "+year.sql()+"=", len(cfg.Years)-1)+"?)")
 		for _, y := range cfg.Years {
-			args = append(args, strconv.Itoa(y))
+			args = append(args, y)
 		}
 	}
 	if cfg.Name != "" {
-		if slices.Contains(cfg.Tables, BestEffortTable) {
-			where = append(where, BestEffortTable+".Name=?")
-			args = append(args, cfg.Name)
-		} else {
-			where = append(where, SummaryTable+".Name LIKE ?")
-			args = append(args, cfg.Name)
+		switch {
+		case slices.Contains(tables, BestEffortTable):
+			where = append(where, BestEffort.Name.sql()+"=?")
+		case slices.Contains(tables, SummaryTable):
+			where = append(where, Summary.Name.sql()+" LIKE ?")
+		default:
+			return "", nil, fmt.Errorf("name filter needs table %s or %s in query", SummaryTable, BestEffortTable)
+		}
+		args = append(args, cfg.Name)
+	}
+	if cfg.SplitUnits != "" {
+		if err := needs(SplitTable, "split units"); err != nil {
+			return "", nil, err
 		}
+		where = append(where, Split.Units.sql()+"=?")
+		args = append(args, cfg.SplitUnits)
 	}
 	if cfg.StravaID > 0 {
-		for _, t := range cfg.Tables {
-			where = append(where, t+".stravaid=?")
-			args = append(args, strconv.FormatInt(cfg.StravaID, 10))
+		found := false
+		for _, t := range tables {
+			if column, ok := stravaIDs[t]; ok {
+				where = append(where, column.sql()+"=?")
+				args = append(args, cfg.StravaID)
+				found = true
+			}
 		}
+		if !found {
+			return "", nil, fmt.Errorf("Strava ID filter needs activity table in query (not %v)", tables)
+		}
+	}
+	for _, c := range cfg.Conditions {
+		term, values, err := c.sql()
+		if err != nil {
+			return "", nil, err
+		}
+		where = append(where, term)
+		args = append(args, values...)
+	}
+	var sb strings.Builder
+	sb.WriteString("select " + joinSQL(q.columns) + " from " + string(q.table))
+	for _, j := range q.joins {
+		sb.WriteString(" join " + string(j.table) + " on " + j.left.sql() + "=" + j.right.sql())
 	}
-	condition := ""
 	if len(where) > 0 {
-		condition = " where " + strings.Join(where, " and ")
+		sb.WriteString(" where " + strings.Join(where, " and "))
 	}
-	ifArgs := make([]interface{}, len(args))
-	for i, v := range args {
-		ifArgs[i] = v
+	if len(q.groupBy) > 0 {
+		sb.WriteString(" group by " + joinSQL(q.groupBy))
 	}
-	return fmt.Sprintf(
-		"select %s from %s%s%s", strings.Join(fields, ","), strings.Join(cfg.Tables, ","),
-		condition, sortingOrder(cfg.Order),
-	), ifArgs
-}
-
-func sortingOrder(order *OrderConfig) string {
-	sorting := ""
-	if order != nil {
-		if order.GroupBy != nil {
-			sorting += " group by " + strings.Join(order.GroupBy, ",")
+	if len(q.orderBy) > 0 {
+		orders := make([]string, len(q.orderBy))
+		for idx, o := range q.orderBy {
+			orders[idx] = o.sql()
 		}
-		if order.OrderBy != nil {
-			sorting += " order by " + strings.Join(order.OrderBy, ",")
-		}
-		if order.Limit > 0 {
-			sorting += " limit " + strconv.FormatInt(int64(order.Limit), 10)
+		sb.WriteString(" order by " + strings.Join(orders, ","))
+	}
+	if q.limit > 0 {
+		sb.WriteString(" limit " + strconv.Itoa(q.limit))
+	}
+	if q.offset > 0 {
+		if q.limit <= 0 {
+			sb.WriteString(" limit -1") // sqlite accepts offset only after limit
 		}
+		sb.WriteString(" offset " + strconv.Itoa(q.offset))
 	}
-	return sorting
+	return sb.String(), args, nil
 }
 
-func (sq *Sqlite3) QueryBestEffortDistances(ctx context.Context) ([]string, error) {
-	if sq.db == nil {
-		return nil, errors.New("database is nil")
+// sql turns condition into SQL with placeholder for each value
+func (c Condition) sql() (string, []any, error) {
+	if !slices.Contains(operators, c.Operator) {
+		return "", nil, fmt.Errorf("unknown operator in condition: %s", c.Operator)
 	}
-	query, values := sqlQuery(
-		[]string{"distinct(" + BestEffortTable + ".Name)"},
-		WithTable(BestEffortTable),
-		WithOrder(OrderConfig{OrderBy: []string{"distance desc"}}),
-	)
-	rows, err := sq.db.QueryContext(ctx, query, values...)
-	if err != nil {
-		return nil, fmt.Errorf("%s failed: %w", query, err)
+	if len(c.Values) == 0 {
+		return "", nil, fmt.Errorf("condition on %s doesn't have values", c.Column.sql())
 	}
-	defer func() { _ = rows.Close() }()
-	benames := []string{}
-	for rows.Next() {
-		var value string
-		if err = rows.Scan(&value); err != nil {
-			return benames, err
-		}
-		benames = append(benames, value)
+	if c.Operator == "in" || c.Operator == "not in" {
+		return c.Column.sql() + " " + c.Operator + " (" + strings.Repeat("?,", len(c.Values)-1) + "?)", c.Values, nil
 	}
-	// slog.Info("QueryBestEffortDistances", "benames", benames)
-	return benames, nil
+	return c.Column.sql() + c.Operator + "?", c.Values[:1], nil
 }
 
-// QueryTypes creates list of distinct years from which have records
-func (sq *Sqlite3) QuerySports(ctx context.Context) ([]string, error) {
-	if sq.db == nil {
-		return nil, errors.New("database is nil")
+// sportCondition matches activity type against sports and group's types, and sport type against group's sport types
+func sportCondition(sports []string, groups []sport.Group) (string, []any) {
+	terms := []string{}
+	args := []any{}
+	for _, s := range sports {
+		terms = append(terms, Summary.Type.sql()+"=?")
+		args = append(args, s)
 	}
-	query, values := sqlQuery(
-		[]string{"distinct(" + SummaryTable + ".Type)"},
-		WithTable(SummaryTable),
-		WithOrder(OrderConfig{GroupBy: []string{"type"}, OrderBy: []string{"type"}}),
-	)
-	// slog.Info("storage.Query", "query", query)
-	rows, err := sq.db.QueryContext(ctx, query, values...)
-	sports := []string{}
-	if err != nil {
-		return sports, fmt.Errorf("select caused: %w", err)
-	}
-	defer func() { _ = rows.Close() }()
-	for rows.Next() {
-		var value string
-		if err = rows.Scan(&value); err != nil {
-			return sports, err
+	for _, g := range groups {
+		for _, t := range g.Types {
+			terms = append(terms, Summary.Type.sql()+"=?")
+			args = append(args, t)
+		}
+		for _, st := range g.SportTypes {
+			terms = append(terms, Summary.SportType.sql()+"=?")
+			args = append(args, st)
 		}
-		sports = append(sports, value)
 	}
-	return sports, nil
+	if len(terms) == 0 { // only empty groups were given
+		return "(1=0)", args
+	}
+	return "(" + strings.Join(terms, " or ") + ")", args
 }
 
-// QueryTypes creates list of distinct years from which have records
-func (sq *Sqlite3) QueryWorkouts(ctx context.Context) ([]string, error) {
-	if sq.db == nil {
-		return nil, errors.New("database is nil")
-	}
-	o := []string{SummaryTable + ".WorkoutType"}
-	query, values := sqlQuery(
-		[]string{"distinct(" + SummaryTable + ".WorkoutType)"},
-		WithTable(SummaryTable),
-		WithOrder(OrderConfig{GroupBy: o, OrderBy: o}),
-	)
-	// slog.Info("storage.Query", "query", query)
-	rows, err := sq.db.QueryContext(ctx, query, values...)
-	workouts := []string{}
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// queryStrings returns first column of each row
+func (sq *Sqlite3) queryStrings(ctx context.Context, q *Query) ([]string, error) {
+	rows, err := sq.Query(ctx, q)
 	if err != nil {
-		return workouts, fmt.Errorf("select caused: %w", err)
+		return nil, err
 	}
 	defer func() { _ = rows.Close() }()
+	values := []string{}
 	for rows.Next() {
 		var value string
 		if err = rows.Scan(&value); err != nil {
-			return workouts, err
+			return values, err
 		}
-		workouts = append(workouts, value)
+		values = append(values, value)
 	}
-	return workouts, nil
+	return values, rows.Err()
 }
 
-// QueryYears creates list of distinct years from which have records
-func (sq *Sqlite3) QueryYears(ctx context.Context, opts ...QueryOption) ([]int, error) {
-	if sq.db == nil {
-		return nil, errors.New("database is nil")
-	}
-	defaultOpts := []QueryOption{
-		WithOrder(OrderConfig{GroupBy: []string{"Year"}, OrderBy: []string{"Year desc"}}),
-	}
-	if len(opts) == 0 {
-		defaultOpts = append(defaultOpts, WithTable(SummaryTable))
+// QueryBestEffortDistances lists names of best efforts from longest to shortest
+func (sq *Sqlite3) QueryBestEffortDistances(ctx context.Context) ([]string, error) {
+	return sq.queryStrings(ctx, Select(BestEffort.Name).From(BestEffortTable).
+		GroupBy(BestEffort.Name).OrderBy(Desc(Max(BestEffort.Distance))))
+}
+
+// QuerySports lists activity types
+func (sq *Sqlite3) QuerySports(ctx context.Context) ([]string, error) {
+	return sq.queryStrings(ctx, Select(Summary.Type).From(SummaryTable).
+		GroupBy(Summary.Type).OrderBy(Asc(Summary.Type)))
+}
+
+// QueryWorkouts lists workout types
+func (sq *Sqlite3) QueryWorkouts(ctx context.Context) ([]string, error) {
+	return sq.queryStrings(ctx, Select(Summary.WorkoutType).From(SummaryTable).
+		GroupBy(Summary.WorkoutType).OrderBy(Asc(Summary.WorkoutType)))
+}
+
+// QueryYears creates list of distinct years from which table has records. Years are season years with WithSeason.
+func (sq *Sqlite3) QueryYears(ctx context.Context, t Table, opts ...QueryOption) ([]int, error) {
+	keys, err := t.DateKeys()
+	if err != nil {
+		return nil, err
 	}
-	opts = append(defaultOpts, opts...)
-	query, values := sqlQuery(
-		[]string{"distinct(Year)"}, opts...,
-	)
-	// slog.Info("storage.Query", "query", query)
-	rows, err := sq.db.QueryContext(ctx, query, values...)
+	year := keys.YearKey(opts...)
+	rows, err := sq.Query(ctx, Select(year).From(t).Where(opts...).GroupBy(year).OrderBy(Desc(year)))
 	if err != nil {
-		return nil, fmt.Errorf("%s failed: %w", query, err)
+		return nil, err
 	}
 	defer func() { _ = rows.Close() }()
 	years := []int{}
@@ -560,21 +839,23 @@ func (sq *Sqlite3) QueryYears(ctx context.Context, opts ...QueryOption) ([]int,
 		}
 		years = append(years, year)
 	}
-	return years, nil
+	return years, rows.Err()
 }
 
-func (sq *Sqlite3) Query(ctx context.Context, fields []string, opts ...QueryOption) (*sql.Rows, error) {
+// Query runs query. Invalid queries (e.g. without table) return error.
+func (sq *Sqlite3) Query(ctx context.Context, q *Query) (*sql.Rows, error) {
 	if sq.db == nil {
 		return nil, errors.New("database is nil")
 	}
-	if len(opts) == 0 {
-		opts = []QueryOption{
-			WithOrder(OrderConfig{GroupBy: []string{"Year"}, OrderBy: []string{"Year desc"}}),
-		}
-		opts = append(opts, WithTable(SummaryTable))
+	query, values, err := sqlQuery(q)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := sq.db.QueryContext(ctx, query, values...)
+	if err != nil {
+		return nil, fmt.Errorf("%s failed: %w", query, err)
 	}
-	query, values := sqlQuery(fields, opts...)
-	return sq.db.QueryContext(ctx, query, values...)
+	return rows, nil
 }
 
 func (sq *Sqlite3) Close() error {
@@ -4,90 +4,150 @@ import (
 	"fmt"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/jylitalo/mystats/pkg/sport"
 )
 
 func TestSqlQuery(t *testing.T) { //nolint:funlen
 	values := []struct {
-		name    string
-		fields  []string
-		options []QueryOption
-		query   string
-		values  []string
+		name   string
+		query  *Query
+		sql    string
+		values []string
 	}{
 		{
-			name:    "none",
-			fields:  []string{"field"},
-			options: []QueryOption{WithTable(SummaryTable)},
-			query:   "select field from Summary",
+			name:  "none",
+			query: Select(Summary.Name).From(SummaryTable),
+			sql:   "select Summary.Name from Summary",
 		},
 		{
-			name:    "simple",
-			fields:  []string{"field"},
-			options: []QueryOption{WithTable(SummaryTable), WithSports("Run")},
-			query:   "select field from Summary where (Type=?)",
-			values:  []string{"Run"},
+			name:   "simple",
+			query:  Select(Summary.Name).From(SummaryTable).Where(WithSports("Run")),
+			sql:    "select Summary.Name from Summary where (Summary.Type=?)",
+			values: []string{"Run"},
 		},
 		{
-			name:   "multi-field",
-			fields: []string{"f1", "f2"},
-			options: []QueryOption{
-				WithTable(SummaryTable), WithSports("r1"), WithSports("r2"),
-				WithOrder(OrderConfig{GroupBy: []string{"f3"}, OrderBy: []string{"f3 desc"}}),
-			},
-			query:  "select f1,f2 from Summary where (Type=? or Type=?) group by f3 order by f3 desc",
+			name: "multi-field",
+			query: Select(Summary.Year, Sum(Summary.Distance)).From(SummaryTable).
+				Where(WithSports("r1"), WithSports("r2")).GroupBy(Summary.Year).OrderBy(Desc(Summary.Year)),
+			sql: "select Summary.Year,sum(Summary.Distance) from Summary where (Summary.Type=? or Summary.Type=?) " +
+				"group by Summary.Year order by Summary.Year desc",
 			values: []string{"r1", "r2"},
 		},
 		{
-			name:   "order",
-			fields: []string{"k1", "k2"},
-			options: []QueryOption{
-				WithTable(SummaryTable), WithWorkouts("c3"), WithSports("c1"),
-				WithOrder(OrderConfig{GroupBy: []string{"k3", "k4"}, OrderBy: []string{"k5", "k6"}, Limit: 7}),
-			},
-			query:  "select k1,k2 from Summary where (Workouttype=?) and (Type=?) group by k3,k4 order by k5,k6 limit 7",
+			name: "sport_groups",
+			query: Select(Summary.Name).From(SummaryTable).Where(WithSports("Hike"), WithSportGroups(sport.Group{
+				Name: "running", Types: []string{"Run"}, SportTypes: []string{"TrailRun"},
+			})),
+			sql:    "select Summary.Name from Summary where (Summary.Type=? or Summary.Type=? or Summary.SportType=?)",
+			values: []string{"Hike", "Run", "TrailRun"},
+		},
+		{
+			name: "order",
+			query: Select(Summary.Year, Summary.Month, Max(Summary.Distance)).From(SummaryTable).
+				Where(WithWorkouts("c3"), WithSports("c1")).GroupBy(Summary.Year, Summary.Month).
+				OrderBy(Asc(Max(Summary.Distance)).NullsLast(), Desc(Summary.Month)).Limit(7),
+			sql: "select Summary.Year,Summary.Month,max(Summary.Distance) from Summary " +
+				"where (Summary.WorkoutType=?) and (Summary.Type=?) group by Summary.Year,Summary.Month " +
+				"order by max(Summary.Distance) is null,max(Summary.Distance),Summary.Month desc limit 7",
 			values: []string{"c3", "c1"},
 		},
 		{
-			name:    "one_year",
-			fields:  []string{"field"},
-			options: []QueryOption{WithTable(SummaryTable), WithSports("Run"), WithYears(2023)},
-			query:   "select field from Summary where (Type=?) and (Year=?)",
-			values:  []string{"Run", "2023"},
+			name:  "offset",
+			query: Select(Summary.Name).From(SummaryTable).OrderBy(Asc(Summary.StravaID)).Limit(10).Offset(20),
+			sql:   "select Summary.Name from Summary order by Summary.StravaID limit 10 offset 20",
+		},
+		{
+			name:  "offset_without_limit",
+			query: Select(Summary.Name).From(SummaryTable).Offset(5),
+			sql:   "select Summary.Name from Summary limit -1 offset 5",
+		},
+		{
+			name:   "one_year",
+			query:  Select(Summary.Name).From(SummaryTable).Where(WithSports("Run"), WithYears(2023)),
+			sql:    "select Summary.Name from Summary where (Summary.Type=?) and (Summary.Year=?)",
+			values: []string{"Run", "2023"},
 		},
 		{
 			name:   "multiple_years",
-			fields: []string{"field"},
-			options: []QueryOption{
-				WithTable(SummaryTable), WithSports("Run"), WithYears(2019), WithYears(2023),
-			},
-			query:  "select field from Summary where (Type=?) and (Year=? or Year=?)",
+			query:  Select(Summary.Name).From(SummaryTable).Where(WithSports("Run"), WithYears(2019), WithYears(2023)),
+			sql:    "select Summary.Name from Summary where (Summary.Type=?) and (Summary.Year=? or Summary.Year=?)",
 			values: []string{"Run", "2019", "2023"},
 		},
 		{
-			name:   "ids",
-			fields: []string{"StravaID"},
-			options: []QueryOption{
-				WithTable(SummaryTable), WithSports("Run"), WithOrder(OrderConfig{OrderBy: []string{"StravaID desc"}}),
-			},
-			query:  "select StravaID from Summary where (Type=?) order by StravaID desc",
+			name: "date_range",
+			query: Select(Summary.Name).From(SummaryTable).Where(WithDateRange(
+				time.Date(2023, time.November, 1, 0, 0, 0, 0, time.UTC),
+				time.Date(2024, time.March, 31, 0, 0, 0, 0, time.UTC),
+			)),
+			sql:    "select Summary.Name from Summary where Summary.StartDate>=? and Summary.StartDate<?",
+			values: []string{"2023-11-01 00:00:00", "2024-04-01 00:00:00"},
+		},
+		{
+			name: "date_from_garmin",
+			query: Select(DailySteps.TotalSteps).From(DailyStepsTable).
+				Where(WithDateRange(time.Date(2024, time.January, 15, 12, 0, 0, 0, time.UTC), time.Time{})),
+			sql:    "select DailySteps.TotalSteps from DailySteps where DailySteps.Date>=?",
+			values: []string{"2024-01-15 00:00:00"},
+		},
+		{
+			name: "season",
+			query: Select(Summary.Name).From(SummaryTable).
+				Where(WithSeason(11, 1), WithDayOfYear(15, 3), WithYears(2023)),
+			sql: "select Summary.Name from Summary where ((Summary.Month*100+Summary.Day-1101+1200)%1200)<=? and " +
+				"((case when Summary.Month*100+Summary.Day>=1101 then Summary.Year else Summary.Year-1 end)=?)",
+			values: []string{"414", "2023"},
+		},
+		{
+			name: "garmin_season",
+			query: Select(HeartRate.RestingHR).From(HeartRateTable).
+				Where(WithSeason(11, 1), WithYears(2023)),
+			sql: "select HeartRate.RestingHR from HeartRate where ((case when HeartRate.Month*100+HeartRate.Day>=1101 " +
+				"then HeartRate.Year else HeartRate.Year-1 end)=?)",
+			values: []string{"2023"},
+		},
+		{
+			name: "ids",
+			query: Select(Summary.StravaID).From(SummaryTable).Where(WithSports("Run")).
+				OrderBy(Desc(Summary.StravaID)),
+			sql:    "select Summary.StravaID from Summary where (Summary.Type=?) order by Summary.StravaID desc",
 			values: []string{"Run"},
 		},
 		{
-			name:   "besteffort",
-			fields: []string{"Summary.Name"},
-			options: []QueryOption{
-				WithTable(SummaryTable), WithTable(BestEffortTable), WithName("400m"),
-			},
-			query: "select Summary.Name from Summary,BestEffort " +
-				"where Summary.StravaID=BestEffort.StravaID and BestEffort.Name=?",
+			name: "besteffort",
+			query: Select(Summary.Name).From(SummaryTable).
+				Join(BestEffortTable, Summary.StravaID, BestEffort.StravaID).Where(WithName("400m")),
+			sql: "select Summary.Name from Summary join BestEffort on Summary.StravaID=BestEffort.StravaID " +
+				"where BestEffort.Name=?",
 			values: []string{"400m"},
 		},
+		{
+			name: "strava_id",
+			query: Select(Split.Split).From(SplitTable).Where(WithStravaID(7), WithSplitUnits("metric")).
+				OrderBy(Asc(Split.Split)),
+			sql:    "select Split.Split from Split where Split.Units=? and Split.StravaID=? order by Split.Split",
+			values: []string{"metric", "7"},
+		},
+		{
+			name: "conditions",
+			query: Select(Summary.Name).From(SummaryTable).Where(WithYears(2024), WithConditions(
+				Condition{Column: Summary.Type, Operator: "in", Values: []any{"Run", "TrailRun"}},
+				Condition{Column: Summary.Distance, Operator: ">", Values: []any{20000.0}},
+			)),
+			sql: "select Summary.Name from Summary where (Summary.Year=?) and Summary.Type in (?,?) and " +
+				"Summary.Distance>?",
+			values: []string{"2024", "Run", "TrailRun", "20000"},
+		},
 	}
 	for _, value := range values {
 		t.Run(value.name, func(t *testing.T) {
-			cmd, values := sqlQuery(value.fields, value.options...)
-			if cmd != value.query {
-				t.Errorf("query mismatch got '%s' vs. expected '%s'", cmd, value.query)
+			cmd, values, err := sqlQuery(value.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cmd != value.sql {
+				t.Errorf("query mismatch got '%s' vs. expected '%s'", cmd, value.sql)
 			}
 			if fmt.Sprintf("%v", values) != "["+strings.Join(value.values, " ")+"]" {
 				t.Errorf(
@@ -98,3 +158,102 @@ func TestSqlQuery(t *testing.T) { //nolint:funlen
 		})
 	}
 }
+
+func TestParseDateRange(t *testing.T) {
+	values := []struct {
+		name string
+		from string
+		to   string
+		sql  string
+		err  string
+	}{
+		{name: "empty", sql: "select Summary.Name from Summary"},
+		{name: "from", from: "2024-01-15", sql: "select Summary.Name from Summary where Summary.StartDate>=?"},
+		{name: "to", to: "2024-01-15", sql: "select Summary.Name from Summary where Summary.StartDate<?"},
+		{
+			name: "same_day", from: "2024-01-15", to: "2024-01-15",
+			sql: "select Summary.Name from Summary where Summary.StartDate>=? and Summary.StartDate<?",
+		},
+		{name: "invalid_from", from: "yesterday", err: "invalid from date yesterday"},
+		{name: "invalid_to", to: "2024-13-01", err: "invalid to date 2024-13-01"},
+		{name: "reversed", from: "2024-02-01", to: "2024-01-01", err: "to date 2024-01-01 is before from date 2024-02-01"},
+	}
+	for _, value := range values {
+		t.Run(value.name, func(t *testing.T) {
+			filters, err := ParseDateRange(value.from, value.to)
+			if value.err != "" {
+				if err == nil || !strings.Contains(err.Error(), value.err) {
+					t.Errorf("error mismatch: %v vs. %s", err, value.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			cmd, _, err := sqlQuery(Select(Summary.Name).From(SummaryTable).Where(filters...))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cmd != value.sql {
+				t.Errorf("query mismatch got '%s' vs. expected '%s'", cmd, value.sql)
+			}
+		})
+	}
+}
+
+func TestSqlQueryErrors(t *testing.T) {
+	values := []struct {
+		name  string
+		query *Query
+		err   string
+	}{
+		{name: "nil", err: "query is nil"},
+		{name: "no_table", query: Select(Summary.Name), err: "no table in query"},
+		{name: "no_columns", query: Select().From(SummaryTable), err: "no columns in query"},
+		{name: "unknown_table", query: Select(Summary.Name).From("Summary; drop"), err: "unknown table: Summary; drop"},
+		{
+			name:  "other_table",
+			query: Select(BestEffort.Name).From(SummaryTable),
+			err:   "column BestEffort.Name needs table BestEffort in query",
+		},
+		{
+			name:  "nested_aggregate",
+			query: Select(Max(Sum(Summary.Distance))).From(SummaryTable),
+			err:   "aggregate inside aggregate in max(sum(Summary.Distance))",
+		},
+		{
+			name:  "not_grouped",
+			query: Select(Summary.Name, Count()).From(SummaryTable).GroupBy(Summary.Year),
+			err:   "Summary.Name needs to be aggregated or in group by",
+		},
+		{
+			name:  "group_by_aggregate",
+			query: Select(Count()).From(SummaryTable).GroupBy(Count()),
+			err:   "can't group by aggregate count(*)",
+		},
+		{
+			name:  "filter_without_dates",
+			query: Select(BestEffort.Name).From(BestEffortTable).Where(WithYears(2024)),
+			err:   "date filters need table with dates in query (not [BestEffort])",
+		},
+		{
+			name:  "filter_without_table",
+			query: Select(HeartRate.RestingHR).From(HeartRateTable).Where(WithSports("Run")),
+			err:   "sport filter needs table Summary in query",
+		},
+		{
+			name: "operator",
+			query: Select(Summary.Name).From(SummaryTable).Where(WithConditions(
+				Condition{Column: Summary.Name, Operator: "; drop table Summary", Values: []any{"x"}},
+			)),
+			err: "unknown operator in condition: ; drop table Summary",
+		},
+	}
+	for _, value := range values {
+		t.Run(value.name, func(t *testing.T) {
+			if _, _, err := sqlQuery(value.query); err == nil || err.Error() != value.err {
+				t.Errorf("error mismatch got '%v' vs. expected '%s'", err, value.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package tui
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// option is checkbox in filter panel
+type option struct {
+	group   string
+	name    string
+	checked bool
+}
+
+// filter is panel for selecting sports, workout types and years
+type filter struct {
+	options []option
+	cursor  int
+}
+
+const (
+	sportGroup   = "Sports"
+	workoutGroup = "Workouts"
+	yearGroup    = "Years"
+)
+
+// newFilter has given sports checked, workouts unchecked (i.e. no filtering) and all years checked
+func newFilter(sports, workouts []string, years []int, checked []string) filter {
+	f := filter{}
+	for _, name := range sports {
+		f.options = append(f.options, option{group: sportGroup, name: name, checked: slices.Contains(checked, name)})
+	}
+	for _, name := range workouts {
+		f.options = append(f.options, option{group: workoutGroup, name: name})
+	}
+	for _, year := range years {
+		f.options = append(f.options, option{group: yearGroup, name: strconv.Itoa(year), checked: true})
+	}
+	return f
+}
+
+// selected returns checked names within group
+func (f filter) selected(group string) []string {
+	names := []string{}
+	for _, o := range f.options {
+		if o.group == group && o.checked {
+			names = append(names, o.name)
+		}
+	}
+	return names
+}
+
+func (f filter) years() []int {
+	years := []int{}
+	for _, name := range f.selected(yearGroup) {
+		if year, err := strconv.Atoi(name); err == nil {
+			years = append(years, year)
+		}
+	}
+	return years
+}
+
+// update moves cursor and toggles checkboxes. Key 'a' toggles every option in cursor's group.
+func (f filter) update(msg tea.KeyMsg) filter {
+	if len(f.options) == 0 {
+		return f
+	}
+	switch msg.String() {
+	case "up", "k":
+		f.cursor = max(0, f.cursor-1)
+	case "down", "j":
+		f.cursor = min(len(f.options)-1, f.cursor+1)
+	case " ", "x":
+		f.options[f.cursor].checked = !f.options[f.cursor].checked
+	case "a":
+		group := f.options[f.cursor].group
+		checked := !slices.ContainsFunc(f.options, func(o option) bool { return o.group == group && o.checked })
+		for idx := range f.options {
+			if f.options[idx].group == group {
+				f.options[idx].checked = checked
+			}
+		}
+	}
+	return f
+}
+
+// view shows options around cursor, so that panel fits into height
+func (f filter) view(height int) string {
+	lines := []string{}
+	cursorLine := 0
+	group := ""
+	for idx, o := range f.options {
+		if o.group != group {
+			group = o.group
+			lines = append(lines, group)
+		}
+		checkbox, pointer := "[ ]", "  "
+		if o.checked {
+			checkbox = "[x]"
+		}
+		if idx == f.cursor {
+			pointer, cursorLine = "> ", len(lines)
+		}
+		lines = append(lines, fmt.Sprintf("%s%s %s", pointer, checkbox, o.name))
+	}
+	height = max(1, height)
+	first := max(0, min(cursorLine-height/2, len(lines)-height))
+	return strings.Join(lines[first:min(len(lines), first+height)], "\n")
+}
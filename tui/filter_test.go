@@ -0,0 +1,59 @@
+package tui //nolint:testpackage
+
+import (
+	"slices"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestFilter(t *testing.T) {
+	keys := func(names ...string) []tea.KeyMsg {
+		msgs := []tea.KeyMsg{}
+		for _, name := range names {
+			switch name {
+			case "down":
+				msgs = append(msgs, tea.KeyMsg{Type: tea.KeyDown})
+			case " ":
+				msgs = append(msgs, tea.KeyMsg{Type: tea.KeySpace, Runes: []rune{' '}})
+			default:
+				msgs = append(msgs, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(name)})
+			}
+		}
+		return msgs
+	}
+	values := []struct {
+		name     string
+		keys     []tea.KeyMsg
+		sports   []string
+		workouts []string
+		years    []int
+	}{
+		{name: "default", sports: []string{"Run"}, workouts: []string{}, years: []int{2023, 2024}},
+		{
+			name: "toggle", keys: keys("down", " ", "down", " "),
+			sports: []string{"Run", "Ride"}, workouts: []string{"Race"}, years: []int{2023, 2024},
+		},
+		{
+			name: "group", keys: keys("down", "down", "down", "a"),
+			sports: []string{"Run"}, workouts: []string{}, years: []int{},
+		},
+	}
+	for _, value := range values {
+		t.Run(value.name, func(t *testing.T) {
+			f := newFilter([]string{"Run", "Ride"}, []string{"Race"}, []int{2023, 2024}, []string{"Run"})
+			for _, msg := range value.keys {
+				f = f.update(msg)
+			}
+			if sports := f.selected(sportGroup); !slices.Equal(sports, value.sports) {
+				t.Errorf("sports mismatch: %v vs. %v", sports, value.sports)
+			}
+			if workouts := f.selected(workoutGroup); !slices.Equal(workouts, value.workouts) {
+				t.Errorf("workouts mismatch: %v vs. %v", workouts, value.workouts)
+			}
+			if years := f.years(); !slices.Equal(years, value.years) {
+				t.Errorf("years mismatch: %v vs. %v", years, value.years)
+			}
+		})
+	}
+}
@@ -0,0 +1,198 @@
+package tui
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jylitalo/mystats/pkg/chart"
+	"github.com/jylitalo/mystats/pkg/output"
+	"github.com/jylitalo/mystats/pkg/present"
+	"github.com/jylitalo/mystats/pkg/season"
+	"github.com/jylitalo/mystats/pkg/sport"
+	"github.com/jylitalo/mystats/pkg/stats"
+	"github.com/jylitalo/mystats/storage"
+)
+
+// tabs mirror pages of server
+var tabs = []string{"list", "best", "top", "plot", "steps", "heartrate"}
+
+const (
+	listTab = iota
+	bestTab
+	topTab
+	plotTab
+	stepsTab
+	heartRateTab
+)
+
+// periods for top tab
+var periods = []string{"week", "month", "day"}
+
+// listLimit is number of activities in list tab
+const listLimit = 100
+
+// topLimit is number of entries in best and top tabs
+const topLimit = 20
+
+// loadedMsg has content of tab. Tables have headers, charts have text.
+type loadedMsg struct {
+	tab     int
+	headers []string
+	rows    [][]string
+	ids     []int64 // Strava IDs of list rows for drill-down
+	text    string
+	err     error
+}
+
+// detailMsg has summary and splits of activity
+type detailMsg struct {
+	text string
+	err  error
+}
+
+// sportOptions turns checked sports into sport types and group filters like sport flags in commands
+func (m Model) sportOptions() ([]string, []storage.QueryOption) {
+	types, selected := sport.Split(m.groups, m.filter.selected(sportGroup))
+	if len(selected) == 0 {
+		return types, nil
+	}
+	return types, []storage.QueryOption{storage.WithSportGroups(selected...)}
+}
+
+// fetch queries content for tab
+func (m Model) fetch(tab int) loadedMsg {
+	msg := loadedMsg{tab: tab}
+	years := m.filter.years()
+	workouts := m.filter.selected(workoutGroup)
+	types, filters := m.sportOptions()
+	switch tab {
+	case listTab:
+		activities, err := stats.List(m.ctx, m.db, types, workouts, years, listLimit, "", filters...)
+		if err != nil {
+			return loadedMsg{tab: tab, err: err}
+		}
+		msg.headers, msg.rows = present.List(activities, m.prefs)
+		for _, a := range activities {
+			msg.ids = append(msg.ids, a.StravaID)
+		}
+	case bestTab:
+		if len(m.distances) == 0 {
+			return loadedMsg{tab: tab, text: "No best efforts found"}
+		}
+		distance := m.distances[m.distance]
+		efforts, err := stats.Best(m.ctx, m.db, distance, topLimit, storage.WithYears(years...))
+		if err != nil {
+			return loadedMsg{tab: tab, err: err}
+		}
+		msg.headers, msg.rows = present.Best(distance, efforts, m.prefs)
+	case topTab:
+		measure := stats.Measures()[m.topMeasure]
+		result, err := stats.Top(
+			m.ctx, m.db, measure, periods[m.period], types, workouts, topLimit, years, filters...,
+		)
+		if err != nil {
+			return loadedMsg{tab: tab, err: err}
+		}
+		msg.headers, msg.rows = present.Top(result, m.prefs)
+	case plotTab:
+		return m.fetchPlot(types, workouts, years, filters)
+	case stepsTab, heartRateTab:
+		query, title := stats.Steps, "Steps"
+		if tab == heartRateTab {
+			query, title = stats.HeartRate, "Resting heart rate"
+		}
+		values, err := query(m.ctx, m.db, 12, 31, years)
+		if err != nil {
+			return loadedMsg{tab: tab, err: err}
+		}
+		msg.text = m.lines(title, "", season.Calendar, values)
+	}
+	return msg
+}
+
+func (m Model) fetchPlot(types, workouts []string, years []int, filters []storage.QueryOption) loadedMsg {
+	s := season.For(m.seasons, m.filter.selected(sportGroup))
+	if !s.IsCalendar() {
+		sm, sd, err := s.MonthDay()
+		if err != nil {
+			return loadedMsg{tab: plotTab, err: err}
+		}
+		filters = append(filters, storage.WithSeason(sm, sd))
+	}
+	measure, err := stats.MeasureByName(stats.CumulativeMeasures()[m.plotMeasure])
+	if err != nil {
+		return loadedMsg{tab: plotTab, err: err}
+	}
+	values, err := stats.Cumulative(m.ctx, m.db, s, measure.Name, types, workouts, 12, 31, years, filters...)
+	if err != nil {
+		return loadedMsg{tab: plotTab, err: err}
+	}
+	for _, days := range values {
+		for idx := range days {
+			days[idx] = present.Convert(measure, m.prefs.System, days[idx])
+		}
+	}
+	title := present.Header(measure, m.prefs.System)
+	return loadedMsg{tab: plotTab, text: m.lines(title, present.Unit(measure, m.prefs.System), s, values)}
+}
+
+// lines draws values of each year as line chart that fits into tab
+func (m Model) lines(title, unit string, s season.Season, values map[int][]float64) string {
+	years := []int{}
+	for year := range values {
+		years = append(years, year)
+	}
+	slices.Sort(years)
+	labels := s.Labels(years)
+	series := make([]chart.Series, len(years))
+	for idx, year := range years {
+		series[idx] = chart.Series{Name: labels[idx], Values: values[year]}
+	}
+	// season year with 29th of February, so that every day has a name
+	first := s.First(2024, time.UTC)
+	if month, _, _ := s.MonthDay(); month > 2 {
+		first = s.First(2023, time.UTC)
+	}
+	xLabel := func(day int) string {
+		return first.AddDate(0, 0, day).Format("Jan 02")
+	}
+	format := func(value float64) string {
+		if math.Abs(value) >= 10000 {
+			return fmt.Sprintf("%.0fk%s", value/1000, unit)
+		}
+		return fmt.Sprintf("%.0f%s", value, unit)
+	}
+	var sb strings.Builder
+	t := chart.Terminal{Width: m.width, Color: m.term.Color}
+	if err := chart.Lines(&sb, t, title, series, max(2, m.bodyHeight()-4), xLabel, format); err != nil {
+		return err.Error()
+	}
+	if sb.Len() == 0 {
+		return "No data found"
+	}
+	return sb.String()
+}
+
+// fetchDetails queries summary and splits of activity for drill-down
+func (m Model) fetchDetails(id int64) detailMsg {
+	d, err := stats.Details(m.ctx, m.db, id)
+	if err != nil {
+		return detailMsg{err: err}
+	}
+	splits, err := stats.Split(m.ctx, m.db, id)
+	if err != nil {
+		return detailMsg{err: err}
+	}
+	headers, rows := present.Details(d, splits, m.prefs)
+	splitHeaders, splitRows := present.Splits(splits, m.prefs)
+	var sb strings.Builder
+	err = output.WriteAll(
+		&sb, "table",
+		output.Table{Caption: d.Name, Headers: headers, Rows: rows},
+		output.Table{Caption: "Splits", Headers: splitHeaders, Rows: splitRows},
+	)
+	return detailMsg{text: sb.String(), err: err}
+}
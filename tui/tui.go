@@ -0,0 +1,327 @@
+// Package tui is keyboard driven terminal dashboard with same pages as server.
+package tui
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/charmbracelet/bubbles/table"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/jylitalo/mystats/pkg/chart"
+	"github.com/jylitalo/mystats/pkg/season"
+	"github.com/jylitalo/mystats/pkg/sport"
+	"github.com/jylitalo/mystats/pkg/stats"
+	"github.com/jylitalo/mystats/pkg/telemetry"
+	"github.com/jylitalo/mystats/pkg/units"
+	"github.com/jylitalo/mystats/storage"
+)
+
+type Storage interface {
+	QueryBestEffortDistances(ctx context.Context) ([]string, error)
+	QuerySports(ctx context.Context) ([]string, error)
+	QueryWorkouts(ctx context.Context) ([]string, error)
+	QueryYears(ctx context.Context, t storage.Table, opts ...storage.QueryOption) ([]int, error)
+	Query(ctx context.Context, q *storage.Query) (*sql.Rows, error)
+}
+
+var (
+	activeTabStyle   = lipgloss.NewStyle().Bold(true).Reverse(true).Padding(0, 1)
+	inactiveTabStyle = lipgloss.NewStyle().Faint(true).Padding(0, 1)
+	helpStyle        = lipgloss.NewStyle().Faint(true)
+	errorStyle       = lipgloss.NewStyle().Foreground(lipgloss.Color("9"))
+)
+
+// Model is state of dashboard
+type Model struct {
+	ctx     context.Context //nolint:containedctx // bubbletea commands don't get context
+	db      Storage
+	prefs   units.Preferences
+	groups  []sport.Group
+	seasons []season.Season
+	term    chart.Terminal
+
+	tab       int
+	filter    filter
+	filtering bool
+	detail    bool // drill-down from list into activity
+	loading   bool
+	err       error
+
+	distances   []string
+	distance    int
+	topMeasure  int
+	period      int
+	plotMeasure int
+
+	width, height int
+	table         table.Model
+	viewport      viewport.Model
+	isTable       bool
+	ids           []int64
+}
+
+// New queries filter options from storage. Sports are checked by default.
+func New(
+	ctx context.Context, db Storage, sports []string, groups []sport.Group, seasons []season.Season,
+) (Model, error) {
+	allSports, err := db.QuerySports(ctx)
+	if err != nil {
+		return Model{}, err
+	}
+	workouts, err := db.QueryWorkouts(ctx)
+	if err != nil {
+		return Model{}, err
+	}
+	years, err := db.QueryYears(ctx, storage.SummaryTable)
+	if err != nil {
+		return Model{}, err
+	}
+	distances, err := db.QueryBestEffortDistances(ctx)
+	if err != nil {
+		return Model{}, err
+	}
+	names := []string{}
+	for _, g := range groups {
+		names = append(names, g.Name)
+	}
+	t := chart.Detect(os.Stdout)
+	return Model{
+		ctx: ctx, db: db, prefs: units.FromContext(ctx), groups: groups, seasons: seasons, term: t,
+		filter:    newFilter(append(names, allSports...), workouts, years, sports),
+		distances: distances,
+		width:     t.Width, height: 24,
+		table:    table.New(table.WithFocused(true)),
+		viewport: viewport.New(t.Width, 20),
+	}, nil
+}
+
+// Run starts dashboard and returns, when user quits
+func Run(ctx context.Context, db Storage, sports []string, groups []sport.Group, seasons []season.Season) error {
+	ctx, span := telemetry.NewSpan(ctx, "tui.Run")
+	defer span.End()
+
+	m, err := New(ctx, db, sports, groups, seasons)
+	if err != nil {
+		return telemetry.Error(span, err)
+	}
+	_, err = tea.NewProgram(m, tea.WithAltScreen(), tea.WithContext(ctx)).Run()
+	return telemetry.Error(span, err)
+}
+
+func (m Model) Init() tea.Cmd {
+	return m.load()
+}
+
+// load fetches content of active tab in background
+func (m Model) load() tea.Cmd {
+	tab := m.tab
+	return func() tea.Msg { return m.fetch(tab) }
+}
+
+// bodyHeight is number of lines between tabs and help
+func (m Model) bodyHeight() int {
+	return max(3, m.height-3)
+}
+
+func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width, m.height = msg.Width, msg.Height
+		m.table.SetHeight(m.bodyHeight())
+		m.viewport.Width, m.viewport.Height = m.width, m.bodyHeight()
+		if m.tab >= plotTab && !m.detail { // charts are drawn to fit terminal
+			m.loading = true
+			return m, m.load()
+		}
+		return m, nil
+	case loadedMsg:
+		if msg.tab != m.tab {
+			return m, nil
+		}
+		m.loading, m.err = false, msg.err
+		m.setContent(msg)
+		return m, nil
+	case detailMsg:
+		m.loading, m.err = false, msg.err
+		m.viewport.SetContent(msg.text)
+		m.viewport.GotoTop()
+		return m, nil
+	case tea.KeyMsg:
+		return m.keyPress(msg)
+	}
+	return m, nil
+}
+
+func (m Model) keyPress(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	key := msg.String()
+	if key == "ctrl+c" {
+		return m, tea.Quit
+	}
+	if m.filtering {
+		switch key {
+		case "f", "esc", "enter":
+			m.filtering, m.loading = false, true
+			return m, m.load()
+		}
+		m.filter = m.filter.update(msg)
+		return m, nil
+	}
+	if m.detail {
+		switch key {
+		case "q":
+			return m, tea.Quit
+		case "esc", "backspace":
+			m.detail = false
+			return m, nil
+		}
+		var cmd tea.Cmd
+		m.viewport, cmd = m.viewport.Update(msg)
+		return m, cmd
+	}
+	switch key {
+	case "q":
+		return m, tea.Quit
+	case "tab", "right", "l":
+		return m.switchTab((m.tab + 1) % len(tabs))
+	case "shift+tab", "left", "h":
+		return m.switchTab((m.tab + len(tabs) - 1) % len(tabs))
+	case "1", "2", "3", "4", "5", "6":
+		return m.switchTab(int(key[0] - '1'))
+	case "f":
+		m.filtering = true
+		return m, nil
+	case "r":
+		m.loading = true
+		return m, m.load()
+	case "enter":
+		if m.tab == listTab && m.table.Cursor() < len(m.ids) && len(m.ids) > 0 {
+			id := m.ids[m.table.Cursor()]
+			m.detail, m.loading = true, true
+			m.viewport.SetContent("")
+			return m, func() tea.Msg { return m.fetchDetails(id) }
+		}
+	case "d":
+		if m.tab == bestTab && len(m.distances) > 0 {
+			m.distance = (m.distance + 1) % len(m.distances)
+			m.loading = true
+			return m, m.load()
+		}
+	case "m":
+		switch m.tab {
+		case topTab:
+			m.topMeasure = (m.topMeasure + 1) % len(stats.Measures())
+		case plotTab:
+			m.plotMeasure = (m.plotMeasure + 1) % len(stats.CumulativeMeasures())
+		default:
+			return m, nil
+		}
+		m.loading = true
+		return m, m.load()
+	case "p":
+		if m.tab == topTab {
+			m.period = (m.period + 1) % len(periods)
+			m.loading = true
+			return m, m.load()
+		}
+	}
+	var cmd tea.Cmd
+	if m.isTable {
+		m.table, cmd = m.table.Update(msg)
+	} else {
+		m.viewport, cmd = m.viewport.Update(msg)
+	}
+	return m, cmd
+}
+
+func (m Model) switchTab(tab int) (tea.Model, tea.Cmd) {
+	if tab == m.tab {
+		return m, nil
+	}
+	m.tab, m.loading, m.err = tab, true, nil
+	return m, m.load()
+}
+
+// setContent shows tables with selectable rows and charts as scrollable text
+func (m *Model) setContent(msg loadedMsg) {
+	m.ids = msg.ids
+	m.isTable = msg.headers != nil
+	if !m.isTable {
+		m.viewport.SetContent(msg.text)
+		m.viewport.GotoTop()
+		return
+	}
+	columns := make([]table.Column, len(msg.headers))
+	for idx, header := range msg.headers {
+		columns[idx] = table.Column{Title: header, Width: utf8.RuneCountInString(header)}
+	}
+	rows := make([]table.Row, len(msg.rows))
+	for idx, row := range msg.rows {
+		for cIdx, cell := range row {
+			if cIdx < len(columns) {
+				columns[cIdx].Width = max(columns[cIdx].Width, utf8.RuneCountInString(cell))
+			}
+		}
+		rows[idx] = row
+	}
+	// rows must be replaced before columns, because table renders rows with new columns
+	m.table.SetRows(nil)
+	m.table.SetColumns(columns)
+	m.table.SetRows(rows)
+	m.table.SetHeight(m.bodyHeight())
+	m.table.GotoTop()
+}
+
+func (m Model) View() string {
+	names := make([]string, len(tabs))
+	for idx, name := range tabs {
+		style := inactiveTabStyle
+		if idx == m.tab {
+			style = activeTabStyle
+		}
+		names[idx] = style.Render(string(rune('1'+idx)) + " " + name)
+	}
+	var body string
+	switch {
+	case m.filtering:
+		body = m.filter.view(m.bodyHeight())
+	case m.loading:
+		body = "Loading..."
+	case m.err != nil:
+		body = errorStyle.Render(m.err.Error())
+	case m.isTable && !m.detail:
+		body = m.table.View()
+	default:
+		body = m.viewport.View()
+	}
+	return lipgloss.JoinHorizontal(lipgloss.Top, names...) + "\n" + body + "\n" + helpStyle.Render(m.help())
+}
+
+// help lists keys that are available in current view
+func (m Model) help() string {
+	switch {
+	case m.filtering:
+		return "↑/↓ move • space toggle • a toggle group • enter apply"
+	case m.detail:
+		return "↑/↓ scroll • esc back • q quit"
+	}
+	keys := []string{"tab/1-6 switch", "f filter", "r reload"}
+	switch m.tab {
+	case listTab:
+		keys = append(keys, "enter splits")
+	case bestTab:
+		if len(m.distances) > 0 {
+			keys = append(keys, "d distance ("+m.distances[m.distance]+")")
+		}
+	case topTab:
+		keys = append(keys, "m measure ("+stats.Measures()[m.topMeasure]+")", "p period ("+periods[m.period]+")")
+	case plotTab:
+		keys = append(keys, "m measure ("+stats.CumulativeMeasures()[m.plotMeasure]+")")
+	}
+	return strings.Join(append(keys, "q quit"), " • ")
+}